@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/taheri24/xpanel/backend/pkg/config"
+	"github.com/taheri24/xpanel/backend/pkg/xfeature"
+	"go.uber.org/fx"
+)
+
+// DataSources holds the default connection and the named connections configured
+// with DB_<NAME>_DSN variables
+type DataSources struct {
+	Default *DB
+	named   map[string]*DB
+}
+
+// NewDataSources opens the named data sources for fx. Unreachable sources are
+// reported by Health instead of failing startup.
+func NewDataSources(cfg *config.Config, db *DB, lc fx.Lifecycle) (*DataSources, error) {
+	sources := &DataSources{Default: db, named: make(map[string]*DB)}
+
+	for name, dsn := range cfg.Database.DataSources {
+		driver := cfg.Database.DataSourceDriver(name)
+		slog.Info("Opening data source", "name", name, "driver", driver)
+		if err := checkDriver(driver); err != nil {
+			sources.close()
+			return nil, fmt.Errorf("data source %s: %w", name, err)
+		}
+		conn, err := sqlx.Open(driver, dsn)
+		if err != nil {
+			sources.close()
+			return nil, fmt.Errorf("data source %s: %w", name, err)
+		}
+		conn.SetMaxOpenConns(25)
+		conn.SetMaxIdleConns(5)
+		conn.SetConnMaxLifetime(5 * time.Minute)
+		sources.named[name] = &DB{conn}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := conn.PingContext(ctx); err != nil {
+			slog.Warn("Failed to ping data source", "name", name, "error", err)
+		}
+		cancel()
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			sources.close()
+			return nil
+		},
+	})
+
+	return sources, nil
+}
+
+func (s *DataSources) close() {
+	for name, db := range s.named {
+		slog.Info("Closing data source", "name", name)
+		if err := db.DB.Close(); err != nil {
+			slog.Error("Failed to close data source", "name", name, "error", err)
+		}
+	}
+}
+
+// DataSource returns the connection pool of a data source; names are case-insensitive
+func (s *DataSources) DataSource(name string) (*sqlx.DB, error) {
+	name = strings.ToLower(name)
+	if name == "" || name == xfeature.DefaultDataSource {
+		return s.Default.DB, nil
+	}
+	db, ok := s.named[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", xfeature.ErrUnknownDataSource, name)
+	}
+	return db.DB, nil
+}
+
+// Health checks every named data source and returns the failures by name
+func (s *DataSources) Health() map[string]error {
+	results := make(map[string]error, len(s.named))
+	for name, db := range s.named {
+		results[name] = db.Health()
+	}
+	return results
+}
@@ -2,29 +2,49 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
 	_ "github.com/microsoft/go-mssqldb"
 	"github.com/taheri24/xpanel/backend/pkg/config"
+	"github.com/taheri24/xpanel/backend/pkg/xfeature"
 	"go.uber.org/fx"
 )
 
+func init() {
+	// pgx registers itself as pgx; postgres selects the same driver
+	sql.Register("postgres", stdlib.GetDefaultDriver())
+}
+
 type DB struct {
 	*sqlx.DB
 }
 
 func New(cfg *config.DatabaseConfig) (*DB, error) {
 	slog.Info("Connecting to database",
+		"driver", cfg.Driver,
 		"host", cfg.Host,
 		"port", cfg.Port,
 		"database", cfg.Database,
 	)
 
+	// Queries are written for the SQL dialect of the driver, so only drivers with a dialect are accepted
+	if err := checkDriver(cfg.Driver); err != nil {
+		slog.Error("Unsupported database driver", "driver", cfg.Driver, "error", err)
+		return nil, err
+	}
+
 	dsn := cfg.ConnectionString()
 	slog.Info("ConnectionString>>>", "dsn", dsn)
-	db, err := sqlx.Connect("sqlserver", dsn)
+	db, err := sqlx.Connect(cfg.Driver, dsn)
 	if err != nil {
 		slog.Error("Failed to connect to database", "error", err)
 		return nil, err
@@ -49,6 +69,22 @@ func New(cfg *config.DatabaseConfig) (*DB, error) {
 	return &DB{db}, nil
 }
 
+// checkDriver requires a SQL dialect for the driver and the driver itself to be built into
+// the server. sqlite3 needs cgo: without it go-sqlite3 registers a stub that fails to connect.
+func checkDriver(driver string) error {
+	if _, err := xfeature.LookupDialect(driver); err != nil {
+		return err
+	}
+	if !slices.Contains(sql.Drivers(), driver) {
+		return fmt.Errorf("database driver %q is not built into this server, available drivers: %s",
+			driver, strings.Join(sql.Drivers(), ", "))
+	}
+	if driver == "sqlite3" && !sqliteAvailable {
+		return fmt.Errorf("database driver sqlite3 requires a server built with cgo (CGO_ENABLED=1 and a C compiler)")
+	}
+	return nil
+}
+
 func (db *DB) Close() error {
 	slog.Info("Closing database connection")
 	return db.DB.Close()
@@ -86,4 +122,5 @@ func NewDB(cfg *config.Config, lc fx.Lifecycle) (*DB, error) {
 // Module exports the database module for fx
 var Module = fx.Options(
 	fx.Provide(NewDB),
+	fx.Provide(NewDataSources),
 )
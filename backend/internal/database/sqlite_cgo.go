@@ -0,0 +1,6 @@
+//go:build cgo
+
+package database
+
+// sqliteAvailable reports whether go-sqlite3 was built with cgo
+const sqliteAvailable = true
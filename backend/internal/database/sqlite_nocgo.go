@@ -0,0 +1,6 @@
+//go:build !cgo
+
+package database
+
+// sqliteAvailable reports whether go-sqlite3 was built with cgo; without it the driver is a stub
+const sqliteAvailable = false
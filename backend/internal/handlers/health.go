@@ -10,33 +10,49 @@ import (
 )
 
 type HealthHandler struct {
-	db *database.DB
+	db      *database.DB
+	sources *database.DataSources
 }
 
-func NewHealthHandler(db *database.DB) *HealthHandler {
-	return &HealthHandler{db: db}
+func NewHealthHandler(db *database.DB, sources *database.DataSources) *HealthHandler {
+	return &HealthHandler{db: db, sources: sources}
 }
 
 // @Summary Health Check
-// @Description Check the health status of the application and database connection
+// @Description Check the health status of the application and its data sources. Failing named data sources
+// @Description report the status "degraded"; only a failing default database makes the application unhealthy.
 // @Tags health
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} map[string]interface{} "Application is healthy"
+// @Success 200 {object} map[string]interface{} "Application is healthy or degraded, with the status of each named data source"
 // @Failure 503 {object} map[string]interface{} "Database connection failed"
 // @Router /health [get]
 func (h *HealthHandler) Health(c *gin.Context) {
+	status := "healthy"
+	dataSources := gin.H{}
+	for name, err := range h.sources.Health() {
+		if err != nil {
+			slog.Warn("Data source health check failed", "name", name, "error", err)
+			dataSources[name] = err.Error()
+			status = "degraded"
+			continue
+		}
+		dataSources[name] = "healthy"
+	}
+
 	if err := h.db.Health(); err != nil {
 		slog.Error("Health check failed", "error", err)
 		c.JSON(http.StatusServiceUnavailable, gin.H{
-			"status": "unhealthy",
-			"error":  "Database connection failed",
+			"status":      "unhealthy",
+			"error":       "Database connection failed",
+			"dataSources": dataSources,
 		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"status": "healthy",
+		"status":      status,
+		"dataSources": dataSources,
 	})
 }
 
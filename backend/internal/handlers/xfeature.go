@@ -1,13 +1,17 @@
 package handlers
 
 import (
-	"crypto/md5"
-	"encoding/hex"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
-	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -18,18 +22,171 @@ import (
 	"go.uber.org/fx"
 )
 
+const ndjsonContentType = "application/x-ndjson"
+
+// ndjsonFlushEvery is the number of streamed rows written between flushes to the client
+const ndjsonFlushEvery = 100
+
+// Query string keys of the export endpoint that are not query parameters
+const (
+	exportFormatKey = "format"
+	exportBOMKey    = "bom"
+)
+
+// Query string keys of the reconcile endpoint that are not query parameters
+const (
+	reconcileStatusKey  = "status"
+	reconcileSummaryKey = "summary"
+)
+
+// exportTruncatedTrailer is set to MaxRows when an export was cut off by a MaxRows attribute
+const exportTruncatedTrailer = "X-Truncated"
+
 type XFeatureHandler struct {
-	db  *database.DB
-	cfg *config.Config
+	db       *database.DB
+	cfg      *config.Config
+	registry *xfeature.Registry
+	cache    *xfeature.QueryCache
+	sources  *database.DataSources
+}
+
+func NewXFeatureHandler(db *database.DB, cfg *config.Config, registry *xfeature.Registry, cache *xfeature.QueryCache, sources *database.DataSources) *XFeatureHandler {
+	return &XFeatureHandler{db: db, cfg: cfg, registry: registry, cache: cache, sources: sources}
+}
+
+// executorOptions returns the options of query and action executors, sharing the query cache
+// and the named data sources
+func (h *XFeatureHandler) executorOptions() xfeature.ExecutorOptions {
+	opts := xfeature.ExecutorOptionsFromConfig(h.cfg)
+	opts.Cache = h.cache
+	opts.DataSources = h.sources
+	return opts
+}
+
+// loadFeature looks up a feature definition in the registry and writes an error response if it is unusable:
+// 422 with diagnostics when the definition violates the schema, 404 otherwise
+func (h *XFeatureHandler) loadFeature(c *gin.Context, featureName string) (*xfeature.XFeature, bool) {
+	xf, err := h.registry.Get(featureName)
+	if err != nil {
+		slog.Warn("Failed to load feature definition", "feature", featureName, "error", err)
+		if verr, ok := xfeature.AsValidationError(err); ok {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":       "Feature definition is invalid",
+				"feature":     featureName,
+				"diagnostics": verr.Diagnostics,
+			})
+			return nil, false
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+		return nil, false
+	}
+	return xf, true
+}
+
+// readParams collects request parameters from the query string for GET requests and
+// from the JSON body otherwise. JSON numbers are kept as json.Number so they can be
+// converted exactly by their Mapping DataType. An empty body yields io.EOF.
+// The reserved keys of the endpoint, like page request keys, are not parameters.
+func readParams(c *gin.Context, reserved ...string) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	if c.Request.Method == http.MethodGet {
+		for key, values := range c.Request.URL.Query() {
+			if slices.Contains(reserved, key) {
+				continue
+			}
+			if len(values) == 1 {
+				params[key] = values[0]
+			} else {
+				params[key] = values
+			}
+		}
+		return params, nil
+	}
+
+	if c.Request.Body == nil {
+		return params, io.EOF
+	}
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&params); err != nil {
+		return make(map[string]interface{}), err
+	}
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	return params, nil
+}
+
+// readBatch reads a JSON array body as the parameter sets of a batch. Other bodies are
+// left for readParams and reported as not a batch.
+func readBatch(c *gin.Context) ([]map[string]interface{}, bool, error) {
+	if c.Request.Method == http.MethodGet || c.Request.Body == nil {
+		return nil, false, nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || trimmed[0] != '[' {
+		return nil, false, nil
+	}
+
+	var items []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&items); err != nil {
+		return nil, true, err
+	}
+	for i, item := range items {
+		if item == nil {
+			items[i] = make(map[string]interface{})
+		}
+	}
+	return items, true, nil
 }
 
-func NewXFeatureHandler(db *database.DB, cfg *config.Config) *XFeatureHandler {
-	return &XFeatureHandler{db: db, cfg: cfg}
+// coerceParams converts parameters by their Mapping DataType and writes a 400 response
+// listing every invalid field if any conversion fails
+func coerceParams(c *gin.Context, xf *xfeature.XFeature, params map[string]interface{}) (map[string]interface{}, bool) {
+	coerced, err := xf.CoerceParameters(params)
+	if err != nil {
+		slog.Warn("Invalid parameters", "feature", xf.Name, "error", err)
+		response := gin.H{"error": "Invalid parameters"}
+		if cerr, ok := xfeature.AsCoercionError(err); ok {
+			response["fields"] = cerr.Fields
+		}
+		c.JSON(http.StatusBadRequest, response)
+		return nil, false
+	}
+	return coerced, true
 }
 
-// getFeatureFilePath constructs the file path for a feature definition
-func getFeatureFilePath(featureName, fileLocation string) string {
-	return fileLocation + featureName + ".xml"
+// isRequestError reports whether an execution error was caused by invalid request input
+func isRequestError(err error) bool {
+	return errors.Is(err, xfeature.ErrListTooLarge) || errors.Is(err, xfeature.ErrEmptyNotInList) ||
+		errors.Is(err, xfeature.ErrInvalidPageRequest) || errors.Is(err, xfeature.ErrConcurrencyTokenMissing) ||
+		errors.Is(err, xfeature.ErrAmbiguousParentRow) || errors.Is(err, xfeature.ErrBatchTooLarge)
+}
+
+// executionStatus maps an execution error to an HTTP status: 400 for invalid request
+// input, 409 for concurrency conflicts, 501 for stored procedure calls, returned rows and
+// query plans the driver does not support, 504 when the query exceeded its Timeout and
+// 500 otherwise
+func executionStatus(err error) int {
+	switch {
+	case isRequestError(err):
+		return http.StatusBadRequest
+	case errors.Is(err, xfeature.ErrConcurrencyConflict):
+		return http.StatusConflict
+	case errors.Is(err, xfeature.ErrProcedureUnsupported), errors.Is(err, xfeature.ErrReturningUnsupported),
+		errors.Is(err, xfeature.ErrPlanUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, xfeature.ErrQueryTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
 }
 
 // @Summary Get feature metadata
@@ -40,18 +197,13 @@ func getFeatureFilePath(featureName, fileLocation string) string {
 // @Param name path string true "Feature name"
 // @Success 200 {object} map[string]interface{} "Feature metadata"
 // @Failure 404 {object} map[string]interface{} "Feature not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
 // @Router /api/v1/xfeatures/{name} [get]
 func (h *XFeatureHandler) GetFeature(c *gin.Context) {
 	featureName := c.Param("name")
 
-	xf := &xfeature.XFeature{
-		Logger: slog.Default(),
-	}
-
-	filePath := getFeatureFilePath(featureName, h.cfg.Feature.XFeatureFileLocation)
-	if err := xf.LoadFromFile(filePath); err != nil {
-		slog.Warn("Failed to load feature definition", "feature", featureName, "error", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found", "filePath": filePath})
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
 		return
 	}
 
@@ -73,68 +225,76 @@ func (h *XFeatureHandler) GetFeature(c *gin.Context) {
 }
 
 // @Summary Get feature checksum
-// @Description Calculate the MD5 checksum of a feature's XML definition file
+// @Description Get the MD5 checksum of a feature's XML definition file as last loaded by the registry
 // @Tags xfeatures
 // @Accept  json
 // @Produce  json
 // @Param name path string true "Feature name"
 // @Success 200 {object} map[string]interface{} "Feature checksum"
-// @Failure 404 {object} map[string]interface{} "Feature file not found"
-// @Failure 500 {object} map[string]interface{} "Checksum calculation failed"
+// @Failure 404 {object} map[string]interface{} "Feature not found"
 // @Router /api/v1/xfeatures/{name}/checksum [get]
 func (h *XFeatureHandler) GetFeatureChecksum(c *gin.Context) {
 	featureName := c.Param("name")
-	filePath := getFeatureFilePath(featureName, h.cfg.Feature.XFeatureFileLocation)
 
-	file, err := os.Open(filePath)
-	if err != nil {
-		slog.Warn("Failed to open feature file for checksum", "feature", featureName, "error", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Feature file not found"})
-		return
+	// Pick up changes since the last watcher tick, like ListFeatures
+	if err := h.registry.Reload(); err != nil {
+		slog.Warn("Failed to rescan feature definitions", "dir", h.registry.Dir(), "error", err)
 	}
-	defer file.Close()
 
-	hasher := md5.New()
-	if _, err := io.Copy(hasher, file); err != nil {
-		slog.Error("Failed to calculate feature checksum", "feature", featureName, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate checksum"})
+	entry, ok := h.registry.Entry(featureName)
+	if !ok {
+		slog.Warn("Feature not found for checksum", "feature", featureName)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
 		return
 	}
 
-	checksum := hex.EncodeToString(hasher.Sum(nil))
-
 	c.JSON(http.StatusOK, gin.H{
 		"feature":   featureName,
-		"checksum":  checksum,
+		"key":       entry.Key,
+		"checksum":  entry.Checksum,
 		"algorithm": "md5",
 	})
 }
 
 // @Summary Execute a feature query
-// @Description Execute a SELECT query from a feature definition with parameters
+// @Description Execute a SELECT query from a feature definition with parameters.
+// @Description Send Accept: application/x-ndjson to stream unpaged results one JSON row per line.
+// @Description Results of queries with a Cache attribute are served from an in-process cache; the
+// @Description cache field of the response reports hit or miss.
+// @Description Results are cut off at MaxRows rows; truncated reports this and a streamed response
+// @Description ends with a {"truncated":true,"maxRows":n} line. A query exceeding its Timeout returns 504.
+// @Description Type="Procedure" queries also return the outputs and returnValue of the stored procedure;
+// @Description they cannot be paged or streamed (501).
+// @Description Queries prefilling a Form whose action has a Concurrency column return the concurrencyToken
+// @Description of the first row.
+// @Description Queries returning several result sets return the one shown by their DataTable as results
+// @Description and all of them, named by the ResultSet declarations, as resultSets.
 // @Tags xfeatures
 // @Accept  json
-// @Produce  json
+// @Produce  json,application/x-ndjson
 // @Param name path string true "Feature name"
 // @Param queryId path string true "Query ID"
-// @Param params body map[string]interface{} false "Query parameters"
+// @Param params body map[string]interface{} false "Query parameters (query string for GET)"
+// @Param page query int false "1-based page number, enables server-side paging for DataTable queries"
+// @Param pageSize query int false "Rows per page, defaults to the DataTable PageSize"
+// @Param sort query string false "Comma separated sortable columns, prefix with - for descending"
+// @Param filter query []string false "Filter on a filterable column as column:operator:value (eq, ne, gt, gte, lt, lte, contains, startsWith, endsWith)"
+// @Param search query string false "Search text matched against the filterable columns of a searchable DataTable"
 // @Success 200 {object} map[string]interface{} "Query results"
-// @Failure 400 {object} map[string]interface{} "Invalid request body"
+// @Failure 400 {object} map[string]interface{} "Invalid request body or parameters"
 // @Failure 404 {object} map[string]interface{} "Feature or query not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
 // @Failure 500 {object} map[string]interface{} "Query execution failed"
+// @Failure 501 {object} map[string]interface{} "Stored procedure cannot be paged, streamed or run on this driver"
+// @Failure 504 {object} map[string]interface{} "Query exceeded its Timeout"
 // @Router /api/v1/xfeatures/{name}/queries/{queryId} [post]
+// @Router /api/v1/xfeatures/{name}/query/{queryId} [get]
 func (h *XFeatureHandler) ExecuteQuery(c *gin.Context) {
 	featureName := c.Param("name")
 	queryID := c.Param("queryId")
 
-	xf := &xfeature.XFeature{
-		Logger: slog.Default(),
-	}
-
-	filePath := getFeatureFilePath(featureName, h.cfg.Feature.XFeatureFileLocation)
-	if err := xf.LoadFromFile(filePath); err != nil {
-		slog.Warn("Failed to load feature definition", "feature", featureName, "error", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
 		return
 	}
 
@@ -146,28 +306,77 @@ func (h *XFeatureHandler) ExecuteQuery(c *gin.Context) {
 		return
 	}
 
-	// Parse request body for parameters
-	var params map[string]interface{}
-	if err := c.ShouldBindJSON(&params); err != nil {
-		// Allow empty body for queries without parameters
-		if c.Request.ContentLength > 0 {
-			slog.Warn("Invalid request body", "error", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	// Parse query string or request body for parameters
+	pageKeys := xf.PageRequestKeys(query)
+	params, err := readParams(c, pageKeys...)
+	// Allow empty body for queries without parameters
+	if err != nil && !errors.Is(err, io.EOF) {
+		slog.Warn("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	params, ok = coerceParams(c, xf, params)
+	if !ok {
+		return
+	}
+
+	// Parse server-side paging, sorting, filtering and search arguments
+	page, err := xfeature.ParsePageRequest(c.Request.URL.Query(), pageKeys)
+	if err != nil {
+		slog.Warn("Invalid page request", "feature", featureName, "query", queryID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var table *xfeature.DataTable
+	if page != nil {
+		table = xf.GetDataTableForQuery(queryID)
+		if table == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query is not bound to a DataTable, paging is not available"})
+			return
+		}
+		if err := xf.CoercePageRequest(table, page); err != nil {
+			response := gin.H{"error": "Invalid parameters"}
+			if cerr, ok := xfeature.AsCoercionError(err); ok {
+				response["fields"] = cerr.Fields
+			}
+			c.JSON(http.StatusBadRequest, response)
 			return
 		}
-		params = make(map[string]interface{})
 	}
 
 	// Execute the query
-	queryExecutor := xfeature.NewQueryExecutorWithConfig(
-		slog.Default(),
-		h.cfg.Feature.MockDataSetLocation,
-		h.cfg.Feature.CaptureMockDataSet,
-	)
-	results, err := queryExecutor.Execute(c.Request.Context(), h.db.DB, query, params)
+	queryExecutor := xfeature.NewQueryExecutorWithOptions(slog.Default(), h.executorOptions())
+
+	// Stream rows as NDJSON when the client asks for it
+	if page == nil && wantsNDJSON(c) {
+		h.streamQuery(c, queryExecutor, query, xf.BoundResultSet(queryID), params)
+		return
+	}
+
+	var results []map[string]interface{}
+	var totalCount int64
+	if page != nil {
+		var pageResult *xfeature.PageResult
+		pageResult, err = queryExecutor.ExecutePage(c.Request.Context(), h.db.DB, query, table, params, page)
+		if err == nil {
+			results, totalCount = pageResult.Rows, pageResult.TotalCount
+		}
+	} else {
+		results, err = queryExecutor.Execute(c.Request.Context(), h.db.DB, query, params)
+		if err == nil {
+			// Return the result set shown by the DataTable of the query
+			results, err = queryExecutor.ResultSet(xf.BoundResultSet(queryID))
+		}
+		totalCount = int64(len(results))
+	}
 	if err != nil {
 		slog.Error("Query execution failed", "feature", featureName, "query", queryID, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Query execution failed: " + err.Error()})
+		status := executionStatus(err)
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(status, gin.H{"error": "Query execution failed: " + err.Error()})
 		return
 	}
 
@@ -279,41 +488,361 @@ func (h *XFeatureHandler) ExecuteQuery(c *gin.Context) {
 	}
 
 	// Return results
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"feature":     featureName,
 		"query":       queryID,
 		"resultCount": len(results),
+		"totalCount":  totalCount,
 		"results":     results,
 		"mockDataSet": queryExecutor.LastMockDataSet,
 		"gridColDefs": gridColDefs,
+	}
+	if queryExecutor.LastCacheStatus != "" {
+		response["cache"] = queryExecutor.LastCacheStatus
+	}
+	response["truncated"] = queryExecutor.LastTruncated
+	if queryExecutor.LastTruncated {
+		response["maxRows"] = queryExecutor.LastMaxRows
+	}
+	if page != nil && page.Paged() {
+		response["page"] = page.Page
+		response["pageSize"] = page.PageSize
+	}
+	if column := xf.ConcurrencyColumn(queryID); column != "" && len(results) > 0 {
+		// Forms prefilled from this query send the token back with their action
+		response["concurrencyToken"] = xfeature.EncodeConcurrencyToken(results[0][column])
+	}
+	if queryExecutor.LastProcedure != nil {
+		response["outputs"] = queryExecutor.LastProcedure.Outputs
+		response["returnValue"] = queryExecutor.LastProcedure.ReturnValue
+	}
+	if len(queryExecutor.LastResultSets) > 1 || len(query.ResultSets) > 0 {
+		response["resultSets"] = queryExecutor.LastResultSets
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// wantsNDJSON reports whether the client asked for newline delimited JSON
+func wantsNDJSON(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), ndjsonContentType)
+}
+
+// streamQuery writes query rows to the response as newline delimited JSON while they are scanned.
+// The query is cancelled when the client disconnects.
+func (h *XFeatureHandler) streamQuery(c *gin.Context, executor *xfeature.QueryExecutor, query *xfeature.Query, resultSet string, params map[string]interface{}) {
+	ctx := c.Request.Context()
+	encoder := json.NewEncoder(c.Writer)
+	written := 0
+	started := false
+	start := func() {
+		if !started {
+			c.Header("Content-Type", ndjsonContentType)
+			c.Header("X-Content-Type-Options", "nosniff")
+			c.Status(http.StatusOK)
+			started = true
+		}
+	}
+
+	count, err := executor.StreamResultSet(ctx, h.db.DB, query, resultSet, params, func(row map[string]interface{}) error {
+		start()
+		if err := encoder.Encode(row); err != nil {
+			return err
+		}
+		written++
+		if written%ndjsonFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
 	})
+	if err != nil {
+		if ctx.Err() != nil {
+			slog.Info("Query stream cancelled by client", "feature", query.Parent, "query", query.Id, "rowCount", count)
+			return
+		}
+		slog.Error("Query stream failed", "feature", query.Parent, "query", query.Id, "rowCount", count, "error", err)
+		if !started {
+			c.JSON(executionStatus(err), gin.H{"error": "Query execution failed: " + err.Error()})
+			return
+		}
+		// The status line is already sent, so the failure is reported as the last line
+		_ = encoder.Encode(gin.H{"error": "Query execution failed: " + err.Error()})
+		c.Writer.Flush()
+		return
+	}
+
+	start()
+	if executor.LastTruncated {
+		// Rows are arbitrary objects, so the cut-off is announced by a distinct last line
+		_ = encoder.Encode(gin.H{"truncated": true, "maxRows": executor.LastMaxRows})
+	}
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+}
+
+// @Summary Export query results
+// @Description Export the results of a feature query as a CSV or XLSX file. Columns, headers and
+// @Description formats follow the DataTable bound to the query, like the gridColDefs of ExecuteQuery.
+// @Description Sorting, filtering and search arguments of the DataTable are applied; rows are streamed.
+// @Description An export cut off by a MaxRows attribute carries an X-Truncated trailer.
+// @Description If the query fails after the file has started, the connection is aborted.
+// @Tags xfeatures
+// @Accept  json
+// @Produce text/csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param name path string true "Feature name"
+// @Param queryId path string true "Query ID"
+// @Param format query string false "Export format: csv (default) or xlsx"
+// @Param bom query bool false "Prefix CSV output with a UTF-8 byte order mark (default true)"
+// @Param sort query string false "Comma separated sort columns, prefix with - for descending"
+// @Param filter query []string false "Column filter as column:operator:value"
+// @Param search query string false "Search text across filterable columns"
+// @Param params body map[string]interface{} false "Query parameters (POST only; GET reads them from the query string)"
+// @Success 200 {file} file "Exported file"
+// @Failure 400 {object} map[string]interface{} "Invalid format or parameters"
+// @Failure 404 {object} map[string]interface{} "Feature or query not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
+// @Failure 500 {object} map[string]interface{} "Query execution failed"
+// @Failure 504 {object} map[string]interface{} "Query exceeded its Timeout"
+// @Router /api/v1/xfeatures/{name}/queries/{queryId}/export [get]
+// @Router /api/v1/xfeatures/{name}/queries/{queryId}/export [post]
+func (h *XFeatureHandler) ExportQuery(c *gin.Context) {
+	featureName := c.Param("name")
+	queryID := c.Param("queryId")
+
+	format := c.DefaultQuery(exportFormatKey, xfeature.ExportCSV)
+	if format != xfeature.ExportCSV && format != xfeature.ExportXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported export format %q", format)})
+		return
+	}
+	bom := true
+	if value := c.Query(exportBOMKey); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bom value"})
+			return
+		}
+		bom = parsed
+	}
+
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
+		return
+	}
+
+	query, err := xf.GetQuery(queryID)
+	if err != nil {
+		slog.Warn("Query not found", "feature", featureName, "query", queryID, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Query not found"})
+		return
+	}
+
+	pageKeys := xf.PageRequestKeys(query)
+	params, err := readParams(c, append(pageKeys, exportFormatKey, exportBOMKey)...)
+	if err != nil && !errors.Is(err, io.EOF) {
+		slog.Warn("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	params, ok = coerceParams(c, xf, params)
+	if !ok {
+		return
+	}
+
+	// Apply the sorting, filters and search of the DataTable; paging is honoured if requested
+	page, err := xfeature.ParsePageRequest(c.Request.URL.Query(), pageKeys)
+	if err != nil {
+		slog.Warn("Invalid page request", "feature", featureName, "query", queryID, "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if page != nil {
+		table := xf.GetDataTableForQuery(queryID)
+		if table == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query is not bound to a DataTable, sorting and filtering are not available"})
+			return
+		}
+		if err := xf.CoercePageRequest(table, page); err != nil {
+			response := gin.H{"error": "Invalid parameters"}
+			if cerr, ok := xfeature.AsCoercionError(err); ok {
+				response["fields"] = cerr.Fields
+			}
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+		source, err := h.sources.DataSource(query.DataSource)
+		if err != nil {
+			slog.Error("Failed to resolve data source", "feature", featureName, "query", queryID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		query, params, err = xfeature.PagedQuery(query, table, page, params, source.DriverName())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	writer, err := xfeature.NewExportWriter(format, c.Writer, xfeature.ExportOptions{BOM: bom, SheetName: queryID})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	executor := xfeature.NewQueryExecutorWithOptions(slog.Default(), h.executorOptions())
+	ctx := c.Request.Context()
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		filename := fmt.Sprintf("%s-%s.%s", featureName, queryID, format)
+		c.Header("Content-Type", xfeature.ExportContentType(format))
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Trailer", exportTruncatedTrailer)
+		c.Status(http.StatusOK)
+		return writer.WriteHeader(xf.ExportColumns(queryID, executor.LastColumns))
+	}
+
+	count, err := executor.StreamResultSet(ctx, h.db.DB, query, xf.BoundResultSet(queryID), params, func(row map[string]interface{}) error {
+		if err := start(); err != nil {
+			return err
+		}
+		return writer.WriteRow(row)
+	})
+	if err == nil {
+		// Write the header of an empty result and finish the file
+		if err = start(); err == nil {
+			err = writer.Close()
+		}
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			slog.Info("Query export cancelled by client", "feature", featureName, "query", queryID, "rowCount", count)
+			return
+		}
+		slog.Error("Query export failed", "feature", featureName, "query", queryID, "rowCount", count, "error", err)
+		if !started {
+			c.JSON(executionStatus(err), gin.H{"error": "Query execution failed: " + err.Error()})
+			return
+		}
+		// Once the file has started its status cannot change; abort the connection so the
+		// download fails instead of ending as a truncated file that looks complete
+		panic(http.ErrAbortHandler)
+	}
+
+	if executor.LastTruncated {
+		// Sent after the body, which is only possible as a trailer
+		c.Writer.Header().Set(exportTruncatedTrailer, strconv.Itoa(executor.LastMaxRows))
+		slog.Warn("Query export truncated", "feature", featureName, "query", queryID, "maxRows", executor.LastMaxRows)
+	}
+	slog.Info("Query exported", "feature", featureName, "query", queryID, "format", format, "rowCount", count)
+}
+
+// @Summary Show the plan of a feature query
+// @Description Bind the parameters of a SELECT query exactly as it is executed and return the bound SQL and
+// @Description arguments with the estimated plan instead of running it: SHOWPLAN_XML on SQL Server as xml,
+// @Description EXPLAIN QUERY PLAN on SQLite and EXPLAIN on PostgreSQL and MySQL as rows.
+// @Description Only available outside production.
+// @Tags xfeatures
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Feature name"
+// @Param queryId path string true "Query ID"
+// @Param params body map[string]interface{} false "Query parameters"
+// @Success 200 {object} map[string]interface{} "Bound SQL, arguments and plan"
+// @Failure 400 {object} map[string]interface{} "Invalid request body or parameters"
+// @Failure 404 {object} map[string]interface{} "Feature or query not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
+// @Failure 500 {object} map[string]interface{} "Reading the plan failed"
+// @Failure 501 {object} map[string]interface{} "Stored procedure or driver without query plans"
+// @Failure 504 {object} map[string]interface{} "Query exceeded its Timeout"
+// @Router /api/v1/xfeatures/{name}/queries/{queryId}/plan [post]
+func (h *XFeatureHandler) PlanQuery(c *gin.Context) {
+	featureName := c.Param("name")
+	queryID := c.Param("queryId")
+
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
+		return
+	}
+
+	query, err := xf.GetQuery(queryID)
+	if err != nil {
+		slog.Warn("Query not found", "feature", featureName, "query", queryID, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Query not found"})
+		return
+	}
+
+	params, err := readParams(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		slog.Warn("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	params, ok = coerceParams(c, xf, params)
+	if !ok {
+		return
+	}
+
+	queryExecutor := xfeature.NewQueryExecutorWithOptions(slog.Default(), h.executorOptions())
+	plan, err := queryExecutor.Plan(c.Request.Context(), h.db.DB, query, params)
+	if err != nil {
+		slog.Error("Query plan failed", "feature", featureName, "query", queryID, "error", err)
+		c.JSON(executionStatus(err), gin.H{"error": "Query plan failed: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"feature": featureName,
+		"query":   queryID,
+		"sql":     plan.SQL,
+		"args":    plan.Args,
+	}
+	if plan.XML != "" {
+		response["xml"] = plan.XML
+	} else {
+		response["rows"] = plan.Rows
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // @Summary Execute a feature action
-// @Description Execute an INSERT/UPDATE/DELETE action from a feature definition
+// @Description Execute an INSERT/UPDATE/DELETE action from a feature definition.
+// @Description Cached results of the queries listed in its Invalidates attribute are dropped.
+// @Description Type="Procedure" actions return the result rows, outputs and returnValue of the stored procedure.
+// @Description Actions with Returning="true" return the affected rows as results, through OUTPUT on SQL Server
+// @Description and RETURNING on SQLite and PostgreSQL; other drivers return 501.
+// @Description Actions with a Concurrency column require its original value as the parameter of the same name
+// @Description and return 409 with the current row and its concurrencyToken when no row matched it.
+// @Description With dryRun=true the action runs in a transaction that is rolled back and returns the rows
+// @Description affected, plus the affected rows as results where OUTPUT or RETURNING is available.
+// @Description An array body runs the action once per parameter set: mode=atomic (default) commits all of them
+// @Description or rolls back at the failedIndex, mode=bestEffort runs each on its own and returns the items
+// @Description with the indexes that succeeded and failed.
 // @Tags xfeatures
 // @Accept  json
 // @Produce  json
 // @Param name path string true "Feature name"
 // @Param actionId path string true "Action ID"
-// @Param params body map[string]interface{} true "Action parameters"
+// @Param dryRun query bool false "Preview the changes and roll them back"
+// @Param mode query string false "Batch mode of an array body: atomic or bestEffort"
+// @Param params body map[string]interface{} true "Action parameters, or an array of them for a batch"
 // @Success 200 {object} map[string]interface{} "Action execution result"
-// @Failure 400 {object} map[string]interface{} "Invalid request body"
+// @Failure 400 {object} map[string]interface{} "Invalid request body or parameters"
 // @Failure 404 {object} map[string]interface{} "Feature or action not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
 // @Failure 500 {object} map[string]interface{} "Action execution failed"
+// @Failure 409 {object} map[string]interface{} "The row changed since it was read; current holds the current row"
+// @Failure 501 {object} map[string]interface{} "Stored procedure or Returning action cannot run on this driver"
+// @Failure 504 {object} map[string]interface{} "Action exceeded its Timeout"
 // @Router /api/v1/xfeatures/{name}/actions/{actionId} [post]
 func (h *XFeatureHandler) ExecuteAction(c *gin.Context) {
 	featureName := c.Param("name")
 	actionID := c.Param("actionId")
 
-	xf := &xfeature.XFeature{
-		Logger: slog.Default(),
-	}
-
-	filePath := getFeatureFilePath(featureName, h.cfg.Feature.XFeatureFileLocation)
-	if err := xf.LoadFromFile(filePath); err != nil {
-		slog.Warn("Failed to load feature definition", "feature", featureName, "error", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
 		return
 	}
 
@@ -325,20 +854,63 @@ func (h *XFeatureHandler) ExecuteAction(c *gin.Context) {
 		return
 	}
 
+	// An array body runs the action once for every parameter set
+	items, batch, err := readBatch(c)
+	if err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if batch {
+		h.executeBatch(c, xf, action, items)
+		return
+	}
+
 	// Parse request body for parameters
-	var params map[string]interface{}
-	if err := c.ShouldBindJSON(&params); err != nil {
+	params, err := readParams(c)
+	if err != nil {
 		slog.Warn("Invalid request body", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
+	params, ok = coerceParams(c, xf, params)
+	if !ok {
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dryRun"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dryRun value"})
+			return
+		}
+	}
 
-	// Execute the action
-	actionExecutor := xfeature.NewActionExecutorWithLocation(slog.Default(), h.cfg.Feature.MockDataSetLocation)
-	result, err := actionExecutor.Execute(c.Request.Context(), h.db.DB, action, params)
+	// Execute the action, reading back the affected rows of Returning actions and dry runs
+	actionExecutor := xfeature.NewActionExecutorWithOptions(slog.Default(), h.executorOptions())
+	var result sql.Result
+	var returned []map[string]any
+	switch {
+	case dryRun:
+		result, returned, err = actionExecutor.DryRun(c.Request.Context(), h.db.DB, action, params)
+	case action.Returning && !action.IsProcedure():
+		result, returned, err = actionExecutor.ExecuteWithReturning(c.Request.Context(), h.db.DB, action, params)
+	default:
+		result, err = actionExecutor.Execute(c.Request.Context(), h.db.DB, action, params)
+	}
+	if errors.Is(err, xfeature.ErrConcurrencyConflict) {
+		slog.Warn("Action rejected by concurrency check", "feature", featureName, "action", actionID, "error", err)
+		h.writeConflict(c, xf, action, params, err)
+		return
+	}
 	if err != nil {
 		slog.Error("Action execution failed", "feature", featureName, "action", actionID, "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Action execution failed: " + err.Error()})
+		status := executionStatus(err)
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(status, gin.H{"error": "Action execution failed: " + err.Error()})
 		return
 	}
 
@@ -353,15 +925,354 @@ func (h *XFeatureHandler) ExecuteAction(c *gin.Context) {
 		lastInsertID = -1
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"feature":      featureName,
 		"action":       actionID,
 		"rowsAffected": rowsAffected,
 		"lastInsertId": lastInsertID,
 		"success":      true,
+	}
+	if len(actionExecutor.LastInvalidated) > 0 {
+		response["invalidated"] = actionExecutor.LastInvalidated
+	}
+	if dryRun {
+		response["dryRun"] = true
+		response["rolledBack"] = true
+	}
+	if returned != nil || (action.Returning && !action.IsProcedure()) {
+		response["results"] = returned
+		response["truncated"] = actionExecutor.LastTruncated
+	}
+	if procedure := actionExecutor.LastProcedure; procedure != nil {
+		response["results"] = procedure.Rows
+		response["outputs"] = procedure.Outputs
+		response["returnValue"] = procedure.ReturnValue
+		response["truncated"] = procedure.Truncated
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// executeBatch runs an action for each parameter set of an array body in the batch
+// mode of the request, atomic unless mode=bestEffort
+func (h *XFeatureHandler) executeBatch(c *gin.Context, xf *xfeature.XFeature, action *xfeature.ActionQuery, items []map[string]interface{}) {
+	mode := c.DefaultQuery("mode", xfeature.BatchAtomic)
+	if mode != xfeature.BatchAtomic && mode != xfeature.BatchBestEffort {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch mode, use atomic or bestEffort"})
+		return
+	}
+	if c.Query("dryRun") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dryRun is not available for batches"})
+		return
+	}
+	actionExecutor := xfeature.NewActionExecutorWithOptions(slog.Default(), h.executorOptions())
+	if err := actionExecutor.CheckBatchSize(len(items)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for i, item := range items {
+		coerced, err := xf.CoerceParameters(item)
+		if err != nil {
+			slog.Warn("Invalid parameters", "feature", xf.Name, "index", i, "error", err)
+			response := gin.H{"error": "Invalid parameters", "index": i}
+			if cerr, ok := xfeature.AsCoercionError(err); ok {
+				response["fields"] = cerr.Fields
+			}
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+		items[i] = coerced
+	}
+
+	result, err := actionExecutor.ExecuteBatch(c.Request.Context(), h.db.DB, action, items, mode)
+	if err != nil {
+		slog.Error("Batch failed", "feature", xf.Name, "action", action.Id, "error", err)
+		response := gin.H{"error": "Batch failed: " + err.Error(), "rolledBack": true}
+		if serr, ok := xfeature.AsStepError(err); ok {
+			response["failedIndex"] = serr.Index
+		}
+		c.JSON(executionStatus(err), response)
+		return
+	}
+
+	response := gin.H{
+		"feature":   xf.Name,
+		"action":    action.Id,
+		"mode":      mode,
+		"items":     result.Items,
+		"succeeded": result.Succeeded,
+		"failed":    result.Failed,
+		"success":   len(result.Failed) == 0,
+	}
+	if len(actionExecutor.LastInvalidated) > 0 {
+		response["invalidated"] = actionExecutor.LastInvalidated
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// writeConflict answers an action rejected by its concurrency check with 409 and the
+// current row read, bypassing the cache, by the conflict query of the action, if it has one
+func (h *XFeatureHandler) writeConflict(c *gin.Context, xf *xfeature.XFeature, action *xfeature.ActionQuery, params map[string]interface{}, err error) {
+	response := gin.H{"error": err.Error(), "conflict": true}
+	row, ok, qerr := xf.CurrentRow(c.Request.Context(), h.db.DB, action, params, h.executorOptions())
+	switch {
+	case !ok:
+		// The action has no conflict query
+	case qerr != nil:
+		slog.Error("Failed to read current row", "feature", xf.Name, "action", action.Id, "error", qerr)
+	case row != nil:
+		response["current"] = row
+		response["concurrencyToken"] = xfeature.EncodeConcurrencyToken(row[action.Concurrency])
+	default:
+		// The row was deleted
+		response["current"] = nil
+	}
+	c.JSON(http.StatusConflict, response)
+}
+
+// @Summary Execute a feature action group
+// @Description Execute the steps of an ActionGroup in order in one database transaction. The steps
+// @Description share the request parameters plus the InsertId, returned row and procedure outputs of earlier steps.
+// @Description A failing step rolls back all steps and is reported as failedStep with its actionRef.
+// @Tags xfeatures
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Feature name"
+// @Param groupId path string true "Action group ID"
+// @Param params body map[string]interface{} true "Parameters shared by the steps"
+// @Success 200 {object} map[string]interface{} "Step results and the outputs of the steps"
+// @Failure 400 {object} map[string]interface{} "Invalid request body or parameters"
+// @Failure 404 {object} map[string]interface{} "Feature or action group not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
+// @Failure 500 {object} map[string]interface{} "A step failed, the transaction was rolled back"
+// @Failure 504 {object} map[string]interface{} "Action group exceeded its Timeout"
+// @Router /api/v1/xfeatures/{name}/groups/{groupId} [post]
+func (h *XFeatureHandler) ExecuteActionGroup(c *gin.Context) {
+	featureName := c.Param("name")
+	groupID := c.Param("groupId")
+
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
+		return
+	}
+
+	group, err := xf.GetActionGroup(groupID)
+	if err != nil {
+		slog.Warn("Action group not found", "feature", featureName, "group", groupID, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Action group not found"})
+		return
+	}
+
+	params, err := readParams(c)
+	if err != nil {
+		slog.Warn("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	params, ok = coerceParams(c, xf, params)
+	if !ok {
+		return
+	}
+
+	actionExecutor := xfeature.NewActionExecutorWithOptions(slog.Default(), h.executorOptions())
+	result, err := actionExecutor.ExecuteGroup(c.Request.Context(), h.db.DB, xf, group, params)
+	if err != nil {
+		slog.Error("Action group failed", "feature", featureName, "group", groupID, "error", err)
+		response := gin.H{"error": "Action group failed: " + err.Error(), "rolledBack": true}
+		if serr, ok := xfeature.AsStepError(err); ok {
+			response["failedStep"] = serr.Index
+			response["actionRef"] = serr.ActionRef
+		}
+		c.JSON(executionStatus(err), response)
+		return
+	}
+
+	response := gin.H{
+		"feature": featureName,
+		"group":   groupID,
+		"steps":   result.Steps,
+		"outputs": result.Outputs,
+		"success": true,
+	}
+	if len(actionExecutor.LastInvalidated) > 0 {
+		response["invalidated"] = actionExecutor.LastInvalidated
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Read a master row with its details
+// @Description Run the query of a master DataTable, whose parameters select a single row, and return that row
+// @Description together with the rows of every DataTable declared DetailOf it. The queries of the detail tables take the
+// @Description request parameters, the master columns named by their Bind elements and, for parameters still
+// @Description missing, the master column of the same name.
+// @Tags xfeatures
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Feature name"
+// @Param tableId path string true "Master DataTable ID"
+// @Param params body map[string]interface{} false "Parameters of the master query"
+// @Success 200 {object} map[string]interface{} "Master row and the rows of its detail tables"
+// @Failure 400 {object} map[string]interface{} "Invalid request body or parameters, or more than one master row"
+// @Failure 404 {object} map[string]interface{} "Feature, table or master row not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
+// @Failure 500 {object} map[string]interface{} "Query execution failed"
+// @Failure 504 {object} map[string]interface{} "Query exceeded its Timeout"
+// @Router /api/v1/xfeatures/{name}/tables/{tableId}/detail [post]
+func (h *XFeatureHandler) GetMasterDetail(c *gin.Context) {
+	featureName := c.Param("name")
+	tableID := c.Param("tableId")
+
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
+		return
+	}
+
+	table, err := xf.GetDataTable(tableID)
+	if err != nil {
+		slog.Warn("Data table not found", "feature", featureName, "table", tableID, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Data table not found"})
+		return
+	}
+
+	params, err := readParams(c)
+	if err != nil && !errors.Is(err, io.EOF) {
+		slog.Warn("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	params, ok = coerceParams(c, xf, params)
+	if !ok {
+		return
+	}
+
+	queryExecutor := xfeature.NewQueryExecutorWithOptions(slog.Default(), h.executorOptions())
+	result, err := queryExecutor.ExecuteMasterDetail(c.Request.Context(), h.db.DB, xf, table, params)
+	if errors.Is(err, xfeature.ErrNoParentRow) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Master row not found"})
+		return
+	}
+	if err != nil {
+		slog.Error("Master-detail query failed", "feature", featureName, "table", tableID, "error", err)
+		status := executionStatus(err)
+		if status == http.StatusBadRequest {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(status, gin.H{"error": "Query execution failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"feature": featureName,
+		"table":   tableID,
+		"row":     result.Row,
+		"details": result.Details,
 	})
 }
 
+// @Summary Reconcile two feature queries
+// @Description Run the left and right queries of a Reconcile with the same parameters, pair their rows by the
+// @Description Key columns and report each as matched, mismatched (with the differing Compare columns), onlyLeft
+// @Description or onlyRight, together with a summary counting them. status limits the returned rows to the
+// @Description given statuses and summary=true returns the summary alone.
+// @Tags xfeatures
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Feature name"
+// @Param reconcileId path string true "Reconcile ID"
+// @Param status query string false "Comma separated statuses: matched, mismatched, onlyLeft, onlyRight"
+// @Param summary query bool false "Return only the summary"
+// @Param params body map[string]interface{} false "Parameters of both queries (query string for GET)"
+// @Success 200 {object} map[string]interface{} "Summary and reconciled rows"
+// @Failure 400 {object} map[string]interface{} "Invalid request body or parameters"
+// @Failure 404 {object} map[string]interface{} "Feature or reconcile not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
+// @Failure 500 {object} map[string]interface{} "Query execution failed"
+// @Failure 504 {object} map[string]interface{} "Query exceeded its Timeout"
+// @Router /api/v1/xfeatures/{name}/reconciles/{reconcileId} [post]
+// @Router /api/v1/xfeatures/{name}/reconciles/{reconcileId} [get]
+func (h *XFeatureHandler) ExecuteReconcile(c *gin.Context) {
+	featureName := c.Param("name")
+	reconcileID := c.Param("reconcileId")
+
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
+		return
+	}
+
+	reconcile, err := xf.GetReconcile(reconcileID)
+	if err != nil {
+		slog.Warn("Reconcile not found", "feature", featureName, "reconcile", reconcileID, "error", err)
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reconcile not found"})
+		return
+	}
+
+	statuses := splitStatuses(c.Query(reconcileStatusKey))
+	for _, status := range statuses {
+		switch status {
+		case xfeature.ReconcileMatched, xfeature.ReconcileMismatched, xfeature.ReconcileOnlyLeft, xfeature.ReconcileOnlyRight:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status " + status})
+			return
+		}
+	}
+	summaryOnly := false
+	if value := c.Query(reconcileSummaryKey); value != "" {
+		if summaryOnly, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid summary value"})
+			return
+		}
+	}
+
+	params, err := readParams(c, reconcileStatusKey, reconcileSummaryKey)
+	if err != nil && !errors.Is(err, io.EOF) {
+		slog.Warn("Invalid request body", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	params, ok = coerceParams(c, xf, params)
+	if !ok {
+		return
+	}
+
+	queryExecutor := xfeature.NewQueryExecutorWithOptions(slog.Default(), h.executorOptions())
+	result, err := queryExecutor.ExecuteReconcile(c.Request.Context(), h.db.DB, xf, reconcile, params)
+	if err != nil {
+		slog.Error("Reconcile failed", "feature", featureName, "reconcile", reconcileID, "error", err)
+		c.JSON(executionStatus(err), gin.H{"error": "Reconcile failed: " + err.Error()})
+		return
+	}
+
+	response := gin.H{
+		"feature":        featureName,
+		"reconcile":      reconcileID,
+		"summary":        result.Summary,
+		"leftTruncated":  result.LeftTruncated,
+		"rightTruncated": result.RightTruncated,
+	}
+	if !summaryOnly {
+		rows := result.Rows
+		if len(statuses) > 0 {
+			rows = slices.DeleteFunc(rows, func(row *xfeature.ReconcileRow) bool {
+				return !slices.Contains(statuses, row.Status)
+			})
+		}
+		response["rows"] = rows
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// splitStatuses splits a comma separated status list, dropping empty entries
+func splitStatuses(value string) []string {
+	var statuses []string
+	for _, status := range strings.Split(value, ",") {
+		if status = strings.TrimSpace(status); status != "" {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
 // @Summary Get backend information
 // @Description Retrieve all backend queries and actions with their parameters for a feature
 // @Tags xfeatures
@@ -370,18 +1281,13 @@ func (h *XFeatureHandler) ExecuteAction(c *gin.Context) {
 // @Param name path string true "Feature name"
 // @Success 200 {object} map[string]interface{} "Backend information"
 // @Failure 404 {object} map[string]interface{} "Feature not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
 // @Router /api/v1/xfeatures/{name}/backend [get]
 func (h *XFeatureHandler) GetBackendInfo(c *gin.Context) {
 	featureName := c.Param("name")
 
-	xf := &xfeature.XFeature{
-		Logger: slog.Default(),
-	}
-
-	filePath := getFeatureFilePath(featureName, h.cfg.Feature.XFeatureFileLocation)
-	if err := xf.LoadFromFile(filePath); err != nil {
-		slog.Warn("Failed to load feature definition", "feature", featureName, "error", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
 		return
 	}
 
@@ -404,18 +1310,13 @@ func (h *XFeatureHandler) GetBackendInfo(c *gin.Context) {
 // @Param name path string true "Feature name"
 // @Success 200 {object} map[string]interface{} "Frontend elements"
 // @Failure 404 {object} map[string]interface{} "Feature not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
 // @Router /api/v1/xfeatures/{name}/frontend [get]
 func (h *XFeatureHandler) GetFrontendElements(c *gin.Context) {
 	featureName := c.Param("name")
 
-	xf := &xfeature.XFeature{
-		Logger: slog.Default(),
-	}
-
-	filePath := getFeatureFilePath(featureName, h.cfg.Feature.XFeatureFileLocation)
-	if err := xf.LoadFromFile(filePath); err != nil {
-		slog.Warn("Failed to load feature definition", "feature", featureName, "error", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
 		return
 	}
 
@@ -431,23 +1332,61 @@ func (h *XFeatureHandler) GetFrontendElements(c *gin.Context) {
 }
 
 // @Summary List available features
-// @Description Get information about available features
+// @Description Discover every feature definition under the feature directory, including subdirectories.
+// @Description Definitions that fail to parse are listed with their error.
 // @Tags xfeatures
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} map[string]interface{} "Available features"
 // @Router /api/v1/xfeatures [get]
 func (h *XFeatureHandler) ListFeatures(c *gin.Context) {
-	// This would typically scan the specs/xfeature directory
-	// For now, return a placeholder response
+	// Pick up files added since the last watcher tick; unchanged files are not reparsed
+	if err := h.registry.Reload(); err != nil {
+		slog.Warn("Failed to rescan feature definitions", "dir", h.registry.Dir(), "error", err)
+	}
+
+	entries := h.registry.List()
+	features := make([]gin.H, 0, len(entries))
+	for _, entry := range entries {
+		features = append(features, featureSummary(entry, h.registry.Dir()))
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"features": []string{
-			"user-management-sample",
-		},
-		"message": "Feature definitions can be loaded from specs/xfeature/ directory",
+		"features": features,
+		"count":    len(features),
 	})
 }
 
+// featureSummary describes a registry entry for the feature list
+func featureSummary(entry *xfeature.RegistryEntry, baseDir string) gin.H {
+	relPath, err := filepath.Rel(baseDir, entry.Path)
+	if err != nil {
+		relPath = entry.Path
+	}
+
+	summary := gin.H{
+		"key":      entry.Key,
+		"path":     filepath.ToSlash(relPath),
+		"checksum": entry.Checksum,
+		"valid":    entry.Err == nil,
+	}
+
+	if xf := entry.Feature; xf != nil {
+		summary["name"] = xf.Name
+		summary["version"] = xf.Version
+		summary["queries"] = len(xf.Backend.Queries)
+		summary["actions"] = len(xf.Backend.ActionQueries)
+		summary["forms"] = len(xf.Frontend.Forms)
+		summary["tables"] = len(xf.Frontend.DataTables)
+	}
+
+	if entry.Err != nil {
+		summary["error"] = entry.Err.Error()
+	}
+
+	return summary
+}
+
 // @Summary Resolve feature mappings
 // @Description Resolve all mappings by executing ListQuery and converting to options
 // @Tags xfeatures
@@ -456,18 +1395,13 @@ func (h *XFeatureHandler) ListFeatures(c *gin.Context) {
 // @Param name path string true "Feature name"
 // @Success 200 {object} map[string]interface{} "Resolved mappings"
 // @Failure 404 {object} map[string]interface{} "Feature not found"
+// @Failure 422 {object} map[string]interface{} "Feature definition is invalid"
 // @Router /api/v1/xfeatures/{name}/mappings [get]
 func (h *XFeatureHandler) ResolveMappings(c *gin.Context) {
 	featureName := c.Param("name")
 
-	xf := &xfeature.XFeature{
-		Logger: slog.Default(),
-	}
-
-	filePath := getFeatureFilePath(featureName, h.cfg.Feature.XFeatureFileLocation)
-	if err := xf.LoadFromFile(filePath); err != nil {
-		slog.Warn("Failed to load feature definition", "feature", featureName, "error", err)
-		c.JSON(http.StatusNotFound, gin.H{"error": "Feature not found"})
+	xf, ok := h.loadFeature(c, featureName)
+	if !ok {
 		return
 	}
 
@@ -483,7 +1417,7 @@ func (h *XFeatureHandler) ResolveMappings(c *gin.Context) {
 	}
 
 	// Resolve all Mappings
-	resolvedMappings := xf.ResolveMappings(c.Request.Context(), h.db.DB)
+	resolvedMappings := xf.ResolveMappingsWithOptions(c.Request.Context(), h.db.DB, h.executorOptions())
 
 	// Build response
 	response := gin.H{
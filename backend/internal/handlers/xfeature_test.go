@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+	"github.com/taheri24/xpanel/backend/internal/database"
+	"github.com/taheri24/xpanel/backend/internal/middleware"
+	"github.com/taheri24/xpanel/backend/pkg/config"
+	"github.com/taheri24/xpanel/backend/pkg/xfeature"
+)
+
+// exportTestFeature counts to 3; FailAtThree fails with an integer overflow on its third row
+const exportTestFeature = `<?xml version="1.0" encoding="UTF-8"?>
+<Feature Name="Numbers" Version="1.0">
+  <Backend>
+    <Query Id="Count" Type="Select">
+      <![CDATA[WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 3) SELECT i FROM n]]>
+    </Query>
+    <Query Id="FailAtThree" Type="Select">
+      <![CDATA[WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 3)
+        SELECT i, CASE WHEN i = 3 THEN abs(i - 9223372036854775807 - 4) ELSE i END AS v FROM n]]>
+    </Query>
+  </Backend>
+  <Frontend/>
+</Feature>`
+
+// newTestServer serves exportTestFeature, stored as sales/numbers.xml, over SQLite
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sales"), 0o755); err != nil {
+		t.Fatalf("Failed to create feature directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sales", "numbers.xml"), []byte(exportTestFeature), 0o644); err != nil {
+		t.Fatalf("Failed to write feature: %v", err)
+	}
+	registry := xfeature.NewRegistry(slog.Default(), dir)
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	sqlDB, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	db := &database.DB{DB: sqlDB}
+
+	h := NewXFeatureHandler(db, &config.Config{}, registry, nil, &database.DataSources{Default: db})
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.Recovery())
+	router.GET("/x/:name/checksum", h.GetFeatureChecksum)
+	router.GET("/x/:name/queries/:queryId/export", h.ExportQuery)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestExportQueryFailsMidStream tests that a query failing after the first row aborts the
+// download instead of completing a truncated file
+func TestExportQueryFailsMidStream(t *testing.T) {
+	server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/x/Numbers/queries/Count/export?bom=false")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("Reading the export failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != "i\n1\n2\n3\n" {
+		t.Fatalf("Expected status 200 with 3 rows, got %d: %q", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(server.URL + "/x/Numbers/queries/FailAtThree/export?bom=false")
+	if err == nil {
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil {
+		t.Errorf("Expected the failed export to be aborted, got a complete response: %q", body)
+	}
+}
+
+// TestGetFeatureChecksum tests that the checksum of a feature in a subdirectory is served
+// from the registry
+func TestGetFeatureChecksum(t *testing.T) {
+	server := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/x/Numbers/checksum")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	sum := md5.Sum([]byte(exportTestFeature))
+	if resp.StatusCode != http.StatusOK || body["checksum"] != hex.EncodeToString(sum[:]) || body["key"] != "sales/numbers" {
+		t.Errorf("Expected the checksum of sales/numbers, got %d: %v", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(server.URL + "/x/missing/checksum")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown feature, got %d", resp.StatusCode)
+	}
+}
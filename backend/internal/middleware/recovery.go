@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery turns a panic into a 500 response. http.ErrAbortHandler is passed on to
+// net/http, which then closes the connection instead of completing the response, so
+// a handler can make a response that has already started visibly fail.
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				if e, ok := err.(error); ok && errors.Is(e, http.ErrAbortHandler) {
+					panic(err)
+				}
+				slog.Error("Panic recovered",
+					"method", c.Request.Method,
+					"path", c.Request.URL.Path,
+					"error", err,
+					"stack", string(debug.Stack()),
+				)
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+		}()
+		c.Next()
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/taheri24/xpanel/backend/internal/database"
+	"github.com/taheri24/xpanel/backend/pkg/xfeature"
 	"go.uber.org/fx"
 )
 
@@ -41,7 +42,7 @@ func (r *UserRepository) GetAll(ctx context.Context) ([]User, error) {
 
 func (r *UserRepository) GetByID(ctx context.Context, id int) (*User, error) {
 	var user User
-	query := `SELECT id, username, email, created_at, updated_at FROM users WHERE id = @p1`
+	query := r.db.Rebind(`SELECT id, username, email, created_at, updated_at FROM users WHERE id = ?`)
 
 	err := r.db.GetContext(ctx, &user, query, id)
 	if err != nil {
@@ -54,34 +55,38 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*User, error) {
 }
 
 func (r *UserRepository) Create(ctx context.Context, user *User) error {
-	query := `
+	query := r.db.Rebind(`
 		INSERT INTO users (username, email, created_at, updated_at)
-		VALUES (@p1, @p2, @p3, @p4);
-		SELECT SCOPE_IDENTITY();
-	`
+		VALUES (?, ?, ?, ?)
+	`)
 
 	now := time.Now()
 	user.CreatedAt = now
 	user.UpdatedAt = now
 
-	var id int
-	err := r.db.GetContext(ctx, &id, query, user.Username, user.Email, user.CreatedAt, user.UpdatedAt)
+	// The generated id is read the way the SQL dialect of the driver provides it
+	dialect := xfeature.DialectFor(r.db.DriverName())
+	result, err := xfeature.ExecInsert(ctx, r.db, dialect, query, user.Username, user.Email, user.CreatedAt, user.UpdatedAt)
 	if err != nil {
 		slog.Error("Failed to create user", "username", user.Username, "error", err)
 		return err
 	}
 
-	user.ID = id
+	id, err := result.LastInsertId()
+	if err != nil {
+		slog.Warn("Could not determine the id of the created user", "username", user.Username, "error", err)
+	}
+	user.ID = int(id)
 	slog.Info("User created successfully", "id", user.ID, "username", user.Username)
 	return nil
 }
 
 func (r *UserRepository) Update(ctx context.Context, user *User) error {
-	query := `
+	query := r.db.Rebind(`
 		UPDATE users
-		SET username = @p1, email = @p2, updated_at = @p3
-		WHERE id = @p4
-	`
+		SET username = ?, email = ?, updated_at = ?
+		WHERE id = ?
+	`)
 
 	user.UpdatedAt = time.Now()
 
@@ -97,7 +102,7 @@ func (r *UserRepository) Update(ctx context.Context, user *User) error {
 }
 
 func (r *UserRepository) Delete(ctx context.Context, id int) error {
-	query := `DELETE FROM users WHERE id = @p1`
+	query := r.db.Rebind(`DELETE FROM users WHERE id = ?`)
 
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
@@ -35,7 +35,7 @@ func NewRouter(r moduleSystems) *gin.Engine {
 	router := gin.New()
 
 	// Global middleware
-	router.Use(gin.Recovery())
+	router.Use(middleware.Recovery())
 	router.Use(middleware.Logger())
 	router.Use(middleware.CORS())
 
@@ -72,7 +72,18 @@ func NewRouter(r moduleSystems) *gin.Engine {
 			xs.POST("/:name/queries/:queryId", r.XFeatureHandler.ExecuteQuery)
 			xs.POST("/:name/query/:queryId", r.XFeatureHandler.ExecuteQuery)
 			xs.GET("/:name/query/:queryId", r.XFeatureHandler.ExecuteQuery)
+			xs.GET("/:name/queries/:queryId/export", r.XFeatureHandler.ExportQuery)
+			xs.POST("/:name/queries/:queryId/export", r.XFeatureHandler.ExportQuery)
 			xs.POST("/:name/actions/:actionId", r.XFeatureHandler.ExecuteAction)
+			xs.POST("/:name/groups/:groupId", r.XFeatureHandler.ExecuteActionGroup)
+			xs.POST("/:name/tables/:tableId/detail", r.XFeatureHandler.GetMasterDetail)
+			xs.GET("/:name/reconciles/:reconcileId", r.XFeatureHandler.ExecuteReconcile)
+			xs.POST("/:name/reconciles/:reconcileId", r.XFeatureHandler.ExecuteReconcile)
+
+			// Query plans expose the SQL of a feature, so they are for development only
+			if r.Config.Server.Env != "production" {
+				xs.POST("/:name/queries/:queryId/plan", r.XFeatureHandler.PlanQuery)
+			}
 		}
 	}
 
@@ -9,6 +9,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -23,16 +24,25 @@ import (
 	"github.com/taheri24/xpanel/backend/pkg/cli"
 	"github.com/taheri24/xpanel/backend/pkg/config"
 	"github.com/taheri24/xpanel/backend/pkg/dbutil"
+	"github.com/taheri24/xpanel/backend/pkg/xfeature"
+	"github.com/taheri24/xpanel/backend/pkg/xfeature/lint"
 	"go.uber.org/fx"
 )
 
 func main() {
 	// Check if CLI command is provided
-	if len(os.Args) > 1 && (os.Args[1] == "env" || os.Args[1] == "unzip" || os.Args[1] == "download" || os.Args[1] == "hash") {
+	if len(os.Args) > 1 && (os.Args[1] == "env" || os.Args[1] == "unzip" || os.Args[1] == "download" || os.Args[1] == "hash" || os.Args[1] == "lint") {
 		// Handle CLI commands
 		envPath := ".env"
 		handler := cli.NewCommandHandler(envPath)
 		if err := handler.Execute(os.Args); err != nil {
+			var exitErr *cli.ExitError
+			if errors.As(err, &exitErr) {
+				if exitErr.Err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", exitErr.Err)
+				}
+				os.Exit(exitErr.Code)
+			}
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -52,6 +62,12 @@ func main() {
 		// Provide database utilities
 		dbutil.Module,
 
+		// Provide feature definition registry
+		xfeature.Module,
+
+		// Lint feature definitions on load in development mode
+		lint.Module,
+
 		// Provide repositories
 		models.Module,
 
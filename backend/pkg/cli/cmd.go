@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+
+	"github.com/taheri24/xpanel/backend/pkg/xfeature/lint"
 )
 
 // ANSI color codes
@@ -15,9 +17,33 @@ const (
 	colorGreen  = "\033[32m"
 	colorCyan   = "\033[36m"
 	colorYellow = "\033[33m"
+	colorRed    = "\033[31m"
 	colorReset  = "\033[0m"
 )
 
+// Exit codes returned by the lint command
+const (
+	ExitLintIssues = 1
+	ExitLintFailed = 2
+)
+
+// ExitError carries the process exit code a command wants to terminate with
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
 // CommandHandler handles CLI commands
 type CommandHandler struct {
 	envPath string
@@ -51,6 +77,8 @@ func (ch *CommandHandler) Execute(args []string) error {
 		return ch.handleDownloadCommand(args, flagSet)
 	case "hash":
 		return ch.handleHashCommand(args, flagSet)
+	case "lint":
+		return ch.handleLintCommand(args, flagSet)
 	default:
 		return fmt.Errorf("unknown command: %s", command)
 	}
@@ -192,6 +220,89 @@ func (ch *CommandHandler) handleHashCommand(args []string, flagSet *flag.FlagSet
 	return ch.handleHash(filePath, *outFile)
 }
 
+// handleLintCommand processes lint-specific commands
+func (ch *CommandHandler) handleLintCommand(args []string, flagSet *flag.FlagSet) error {
+	// Define flags
+	strict := flagSet.Bool("strict", false, "treat warnings as failures")
+	quiet := flagSet.Bool("quiet", false, "only report errors")
+
+	// Parse remaining arguments; flags may follow the paths
+	paths, err := parseInterspersed(flagSet, args[2:])
+	if err != nil {
+		return &ExitError{Code: ExitLintFailed, Err: err}
+	}
+
+	// Default to the configured feature location
+	if len(paths) == 0 {
+		location := os.Getenv("XFEATURE_FILE_LOCATION")
+		if location == "" {
+			location = "specs/xfeature/"
+		}
+		paths = []string{location}
+	}
+
+	return ch.handleLint(paths, *strict, *quiet)
+}
+
+// parseInterspersed parses flags that appear before, between or after the positional
+// arguments and returns the positional arguments. Arguments after -- are never flags.
+func parseInterspersed(flagSet *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flagSet.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flagSet.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// handleLint lints feature definitions and maps the outcome to an exit code
+func (ch *CommandHandler) handleLint(paths []string, strict, quiet bool) error {
+	lm := NewLintManager(paths)
+
+	results, err := lm.Run()
+	if err != nil {
+		return &ExitError{Code: ExitLintFailed, Err: fmt.Errorf("lint failed: %w", err)}
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, result := range results {
+		for _, issue := range result.Issues {
+			color := colorYellow
+			if issue.Severity == lint.SeverityError {
+				color = colorRed
+				errorCount++
+			} else {
+				warningCount++
+				if quiet {
+					continue
+				}
+			}
+			fmt.Printf("%s%s%s: %s%s%s [%s] %s: %s\n",
+				colorCyan, relativePath(result.Path), colorReset,
+				color, issue.Severity, colorReset,
+				issue.Code, issue.Element, issue.Message)
+		}
+	}
+
+	fmt.Printf("\n%d file(s) checked, %d error(s), %d warning(s)\n", len(results), errorCount, warningCount)
+
+	if errorCount > 0 || (strict && warningCount > 0) {
+		return &ExitError{Code: ExitLintIssues}
+	}
+
+	fmt.Printf("%s✓ No blocking issues found%s\n", colorGreen, colorReset)
+	return nil
+}
+
 // handleHash performs the hash computation
 func (ch *CommandHandler) handleHash(filePath, outFile string) error {
 	hm := NewHashManager(filePath)
@@ -548,6 +659,31 @@ FILE HASH COMPUTATION:
       exepath hash ./config.yaml --outfile hash.txt
       exepath hash /path/to/app.exe --outfile ./checksums/app.sha256
 
+FEATURE LINT:
+  exepath lint [path...] [-strict] [-quiet]
+
+    Arguments:
+      [path...]         Feature files or directories (default: $XFEATURE_FILE_LOCATION or specs/xfeature/)
+
+    Options:
+      -strict           Fail on warnings as well as errors
+      -quiet            Only print errors
+
+    Checks:
+      Dangling QueryRef/ActionRef/FormActions references, duplicate IDs, unused Mappings,
+      parameters without a Mapping or Field, columns not selected by their query and
+      form fields the referenced action never uses.
+
+    Exit Codes:
+      0                 No errors (and no warnings with -strict)
+      1                 Issues found
+      2                 Definitions could not be scanned
+
+    Examples:
+      exepath lint
+      exepath lint specs/xfeature/user-management-sample.xml
+      exepath lint -strict ./features
+
 `)
 	return flag.ErrHelp
 }
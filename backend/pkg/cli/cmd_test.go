@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// TestParseInterspersed tests that flags are parsed wherever they appear among the paths
+func TestParseInterspersed(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		paths  []string
+		strict bool
+	}{
+		{"Flags first", []string{"-strict", "a.xml", "b"}, []string{"a.xml", "b"}, true},
+		{"Flags last", []string{"../specs/xfeature", "-strict"}, []string{"../specs/xfeature"}, true},
+		{"Flags between", []string{"a.xml", "-strict", "b"}, []string{"a.xml", "b"}, true},
+		{"No flags", []string{"a.xml"}, []string{"a.xml"}, false},
+		{"Terminator", []string{"a.xml", "--", "-strict"}, []string{"a.xml", "-strict"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagSet := flag.NewFlagSet("lint", flag.ContinueOnError)
+			strict := flagSet.Bool("strict", false, "")
+			paths, err := parseInterspersed(flagSet, tt.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(paths, tt.paths) || *strict != tt.strict {
+				t.Errorf("Expected paths %v and strict %v, got %v and %v", tt.paths, tt.strict, paths, *strict)
+			}
+		})
+	}
+
+	flagSet := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flagSet.Usage = func() {}
+	flagSet.SetOutput(io.Discard)
+	if _, err := parseInterspersed(flagSet, []string{"a.xml", "-unknown"}); err == nil {
+		t.Error("Expected an unknown flag after a path to be rejected")
+	}
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/taheri24/xpanel/backend/pkg/xfeature"
+	"github.com/taheri24/xpanel/backend/pkg/xfeature/lint"
+)
+
+// LintFileResult holds the findings for a single feature definition file
+type LintFileResult struct {
+	Path   string
+	Issues []lint.Issue
+}
+
+// LintManager handles linting of feature definition files
+type LintManager struct {
+	paths  []string
+	logger *slog.Logger
+}
+
+// NewLintManager creates a new LintManager for the given files or directories
+func NewLintManager(paths []string) *LintManager {
+	return &LintManager{
+		paths:  paths,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+// Run lints every feature definition found under the configured paths
+func (lm *LintManager) Run() ([]LintFileResult, error) {
+	var results []LintFileResult
+
+	for _, path := range lm.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("error accessing %s: %w", path, err)
+		}
+
+		if !info.IsDir() {
+			results = append(results, lm.lintFile(path))
+			continue
+		}
+
+		registry := xfeature.NewRegistry(lm.logger, path)
+		if err := registry.Reload(); err != nil {
+			return nil, err
+		}
+		for _, entry := range registry.List() {
+			if entry.Err != nil {
+				results = append(results, LintFileResult{Path: entry.Path, Issues: loadFailure(entry.Err)})
+				continue
+			}
+			results = append(results, LintFileResult{Path: entry.Path, Issues: lintFeature(entry.Feature)})
+		}
+	}
+
+	return results, nil
+}
+
+// lintFile parses and lints a single file
+func (lm *LintManager) lintFile(path string) LintFileResult {
+	xf := xfeature.NewXFeature(lm.logger)
+	if err := xf.LoadFromFile(path); err != nil {
+		return LintFileResult{Path: path, Issues: loadFailure(err)}
+	}
+	return LintFileResult{Path: path, Issues: lintFeature(xf)}
+}
+
+// lintFeature runs the linter and orders its findings for display
+func lintFeature(xf *xfeature.XFeature) []lint.Issue {
+	issues := lint.Lint(xf)
+	lint.SortIssues(issues)
+	return issues
+}
+
+// loadFailure converts a load error into issues, one per schema diagnostic when available
+func loadFailure(err error) []lint.Issue {
+	if verr, ok := xfeature.AsValidationError(err); ok {
+		issues := make([]lint.Issue, 0, len(verr.Diagnostics))
+		for _, d := range verr.Diagnostics {
+			element := fmt.Sprintf("line %d", d.Line)
+			if d.Element != "" {
+				element += " <" + d.Element + ">"
+			}
+			issues = append(issues, lint.Issue{
+				Severity: lint.SeverityError,
+				Code:     lint.CodeLoadFailed,
+				Element:  element,
+				Message:  d.Message,
+			})
+		}
+		return issues
+	}
+
+	return []lint.Issue{{
+		Severity: lint.SeverityError,
+		Code:     lint.CodeLoadFailed,
+		Element:  "Feature",
+		Message:  strings.TrimSpace(err.Error()),
+	}}
+}
+
+// relativePath shortens a path for display when it is below the working directory
+func relativePath(path string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return path
+}
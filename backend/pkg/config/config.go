@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"go.uber.org/fx"
@@ -23,18 +26,30 @@ type ServerConfig struct {
 }
 
 type DatabaseConfig struct {
+	// Driver is the database/sql driver of all connections without their own DB_<NAME>_DRIVER
+	Driver   string
 	Host     string
 	Port     string
 	User     string
 	Password string
 	Database string
 	DSN      string
+	// DataSources maps lowercased data source names to the DSNs of DB_<NAME>_DSN variables
+	DataSources map[string]string
+	// DataSourceDrivers maps lowercased data source names to the drivers of DB_<NAME>_DRIVER variables
+	DataSourceDrivers map[string]string
 }
 
 type FeatureConfig struct {
 	XFeatureFileLocation  string
 	MockDataSetLocation   string
 	CaptureMockDataSet    bool
+	ReloadInterval        time.Duration
+	MaxListSize           int
+	MaxBatchSize          int
+	// QueryTimeout and MaxRows apply to queries and actions without Timeout or MaxRows attributes
+	QueryTimeout          time.Duration
+	MaxRows               int
 }
 
 type NgrokConfig struct {
@@ -56,17 +71,25 @@ func Load() (*Config, error) {
 			Env:  getEnv("ENV", "development"),
 		},
 		Database: DatabaseConfig{
+			Driver:   getEnv("DB_DRIVER", "sqlserver"),
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "1433"),
 			User:     getEnv("DB_USER", "sa"),
 			Password: getEnv("DB_PASSWORD", ""),
 			Database: getEnv("DB_NAME", "xpanel"),
 			DSN:      getEnv("DATABASE_URL", ""),
+			DataSources: getNamedEnv(os.Environ(), "_DSN"),
+			DataSourceDrivers: getNamedEnv(os.Environ(), "_DRIVER"),
 		},
 		Feature: FeatureConfig{
 			XFeatureFileLocation: getEnv("XFEATURE_FILE_LOCATION", "specs/xfeature/"),
 			MockDataSetLocation:  getEnv("MOCK_DATA_SET_LOCATION", "specs/mock/"),
 			CaptureMockDataSet:   getBoolEnv("CAPTURE_MOCK_DATASET", false),
+			ReloadInterval:       getDurationEnv("XFEATURE_RELOAD_INTERVAL", 2*time.Second),
+			MaxListSize:          getIntEnv("XFEATURE_MAX_LIST_SIZE", 1000),
+			MaxBatchSize:         getIntEnv("XFEATURE_MAX_BATCH_SIZE", 1000),
+			QueryTimeout:         getDurationEnv("XFEATURE_QUERY_TIMEOUT", 10*time.Second),
+			MaxRows:              getIntEnv("XFEATURE_MAX_ROWS", 10000),
 		},
 		Ngrok: NgrokConfig{
 			Enabled:   getBoolEnv("NGROK_ENABLED", false),
@@ -87,6 +110,15 @@ func (c *DatabaseConfig) ConnectionString() string {
 	if len(c.DSN) > 0 {
 		return c.DSN
 	}
+	switch c.Driver {
+	case "sqlite3":
+		// A local database file named after DB_NAME
+		return c.Database + ".db"
+	case "postgres", "pgx":
+		return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", c.User, c.Password, c.Host, c.Port, c.Database)
+	case "mysql":
+		return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", c.User, c.Password, c.Host, c.Port, c.Database)
+	}
 	return fmt.Sprintf(
 		"sqlserver://%s:%s@%s:%s?database=%s&encrypt=disable&trustServerCertificate=true",
 		c.User,
@@ -112,6 +144,59 @@ func getBoolEnv(key string, defaultValue bool) bool {
 	return value == "true" || value == "1" || value == "yes" || value == "True"
 }
 
+func getIntEnv(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid integer in environment, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("Invalid duration in environment, using default", "key", key, "value", value, "default", defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// DataSourceDriver returns the driver of a named data source
+func (c *DatabaseConfig) DataSourceDriver(name string) string {
+	if driver := c.DataSourceDrivers[name]; driver != "" {
+		return driver
+	}
+	return c.Driver
+}
+
+// getNamedEnv collects the settings of named data sources from DB_<NAME><suffix> variables,
+// e.g. DB_TR_EDOCS_DSN=... sets the DSN of the data source "tr_edocs"
+func getNamedEnv(environ []string, suffix string) map[string]string {
+	sources := make(map[string]string)
+	for _, entry := range environ {
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok || value == "" {
+			continue
+		}
+		upper := strings.ToUpper(key)
+		if !strings.HasPrefix(upper, "DB_") || !strings.HasSuffix(upper, suffix) || len(upper) <= len("DB_")+len(suffix) {
+			continue
+		}
+		name := strings.ToLower(key[len("DB_") : len(key)-len(suffix)])
+		sources[name] = value
+	}
+	return sources
+}
+
 // Module exports the config module for fx
 var Module = fx.Options(
 	fx.Provide(Load),
@@ -7,49 +7,87 @@ import (
 
 // rowsToMaps converts *sql.Rows -> []map[string]any
 func RowsToMaps(rows *sql.Rows) ([]map[string]any, error) {
+	result, _, err := RowsToMapsLimit(rows, 0)
+	return result, err
+}
+
+// RowsToMapsLimit converts at most limit rows and stops scanning there.
+// truncated reports whether more rows were available. A limit <= 0 converts all rows.
+func RowsToMapsLimit(rows *sql.Rows, limit int) (result []map[string]any, truncated bool, err error) {
 	cols, err := rows.Columns()
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	var result []map[string]any
-
 	for rows.Next() {
-		// Create a slice of pointers to empty interfaces to scan into
-		rawValues := make([]any, len(cols))
-		dest := make([]any, len(cols))
-		for i := range rawValues {
-			dest[i] = &rawValues[i]
+		if limit > 0 && len(result) >= limit {
+			return result, true, nil
 		}
-
-		if err := rows.Scan(dest...); err != nil {
-			return nil, err
+		rowMap, err := ScanRow(rows, cols)
+		if err != nil {
+			return nil, false, err
 		}
+		result = append(result, rowMap)
+	}
 
-		rowMap := make(map[string]any, len(cols))
-
-		for i, colName := range cols {
-			val := rawValues[i]
-
-			switch v := val.(type) {
-			case []byte:
-				// Most drivers return TEXT/VARCHAR/etc as []byte
-				rowMap[colName] = string(v)
-			case time.Time:
-				// Convert time to RFC3339 string (or whatever format you want)
-				rowMap[colName] = v.Format(time.RFC3339)
-			default:
-				// int64, float64, bool, nil, etc. go as-is.
-				rowMap[colName] = v
-			}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	return result, false, nil
+}
+
+// EachRow scans *sql.Rows one row at a time and passes each row as a map to fn.
+// Rows are not retained, so arbitrarily large results can be processed.
+// Iteration stops at the first error returned by fn.
+func EachRow(rows *sql.Rows, fn func(map[string]any) error) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		rowMap, err := ScanRow(rows, cols)
+		if err != nil {
+			return err
 		}
+		if err := fn(rowMap); err != nil {
+			return err
+		}
+	}
 
-		result = append(result, rowMap)
+	return rows.Err()
+}
+
+// ScanRow scans the current row into a map keyed by column name
+func ScanRow(rows *sql.Rows, cols []string) (map[string]any, error) {
+	// Create a slice of pointers to empty interfaces to scan into
+	rawValues := make([]any, len(cols))
+	dest := make([]any, len(cols))
+	for i := range rawValues {
+		dest[i] = &rawValues[i]
 	}
 
-	if err := rows.Err(); err != nil {
+	if err := rows.Scan(dest...); err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	rowMap := make(map[string]any, len(cols))
+
+	for i, colName := range cols {
+		val := rawValues[i]
+
+		switch v := val.(type) {
+		case []byte:
+			// Most drivers return TEXT/VARCHAR/etc as []byte
+			rowMap[colName] = string(v)
+		case time.Time:
+			// Convert time to RFC3339 string (or whatever format you want)
+			rowMap[colName] = v.Format(time.RFC3339)
+		default:
+			// int64, float64, bool, nil, etc. go as-is.
+			rowMap[colName] = v
+		}
+	}
+
+	return rowMap, nil
 }
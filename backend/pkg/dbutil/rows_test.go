@@ -2,6 +2,7 @@ package dbutil
 
 import (
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -252,3 +253,69 @@ func TestRowsToMapsWithNullValues(t *testing.T) {
 		t.Errorf("Expected email to be nil, got %v", row["email"])
 	}
 }
+
+func TestEachRowStopsOnError(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 100) SELECT i FROM n")
+	if err != nil {
+		t.Fatalf("Failed to query data: %v", err)
+	}
+	defer rows.Close()
+
+	stop := errors.New("stop")
+	var seen []int64
+	err = EachRow(rows, func(row map[string]any) error {
+		seen = append(seen, row["i"].(int64))
+		if len(seen) == 3 {
+			return stop
+		}
+		return nil
+	})
+
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected the callback error, got %v", err)
+	}
+	if len(seen) != 3 || seen[0] != 1 || seen[2] != 3 {
+		t.Errorf("Expected rows 1 to 3, got %v", seen)
+	}
+}
+
+func TestRowsToMapsLimit(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	const numbers = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 5) SELECT i FROM n"
+	tests := []struct {
+		limit     int
+		rows      int
+		truncated bool
+	}{
+		{0, 5, false},
+		{3, 3, true},
+		{5, 5, false},
+		{10, 5, false},
+	}
+
+	for _, tt := range tests {
+		rows, err := db.Query(numbers)
+		if err != nil {
+			t.Fatalf("Failed to query data: %v", err)
+		}
+		results, truncated, err := RowsToMapsLimit(rows, tt.limit)
+		rows.Close()
+		if err != nil {
+			t.Fatalf("RowsToMapsLimit failed: %v", err)
+		}
+		if len(results) != tt.rows || truncated != tt.truncated {
+			t.Errorf("Limit %d: expected %d rows (truncated %v), got %d (truncated %v)", tt.limit, tt.rows, tt.truncated, len(results), truncated)
+		}
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
@@ -20,25 +20,47 @@ import (
 type ActionExecutor struct {
 	logger              *slog.Logger
 	mockDataSetLocation string
+	maxListSize         int
+	maxBatchSize        int
+	cache               *QueryCache
+	queryTimeout        time.Duration
+	maxRows             int
+	dataSources         DataSources
+	// LastInvalidated lists the queries whose cached results the last action dropped
+	LastInvalidated []string
+	// LastTruncated reports that ExecuteAndFetchRows stopped reading at LastMaxRows rows
+	LastTruncated bool
+	LastMaxRows   int
+	// LastProcedure holds the result rows, outputs and return code after Execute ran a Procedure action
+	LastProcedure *ProcedureResult
 }
 
 // NewActionExecutor creates a new action executor
 func NewActionExecutor(logger *slog.Logger) *ActionExecutor {
-	if logger == nil {
-		logger = slog.Default()
-	}
-	return &ActionExecutor{logger: logger, mockDataSetLocation: "specs/mock/"}
+	return NewActionExecutorWithOptions(logger, ExecutorOptions{})
 }
 
 // NewActionExecutorWithLocation creates a new action executor with a custom mock data set location
 func NewActionExecutorWithLocation(logger *slog.Logger, mockDataSetLocation string) *ActionExecutor {
+	return NewActionExecutorWithOptions(logger, ExecutorOptions{MockDataSetLocation: mockDataSetLocation})
+}
+
+// NewActionExecutorWithOptions creates a new action executor from executor options
+func NewActionExecutorWithOptions(logger *slog.Logger, opts ExecutorOptions) *ActionExecutor {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	if mockDataSetLocation == "" {
-		mockDataSetLocation = "specs/mock/"
+	opts = opts.withDefaults()
+	return &ActionExecutor{
+		logger:              logger,
+		mockDataSetLocation: opts.MockDataSetLocation,
+		maxListSize:         opts.MaxListSize,
+		maxBatchSize:        opts.MaxBatchSize,
+		cache:               opts.Cache,
+		queryTimeout:        opts.QueryTimeout,
+		maxRows:             opts.MaxRows,
+		dataSources:         opts.DataSources,
 	}
-	return &ActionExecutor{logger: logger, mockDataSetLocation: mockDataSetLocation}
 }
 
 // Execute runs an INSERT/UPDATE/DELETE action
@@ -49,6 +71,17 @@ func (ae *ActionExecutor) Execute(
 	params map[string]any,
 ) (sql.Result, error) {
 	startTime := time.Now()
+	ae.LastProcedure = nil
+
+	// Stored procedures report no rows affected, their outputs are kept in LastProcedure
+	if action.IsProcedure() {
+		result, err := ae.ExecuteProcedure(ctx, db, action, params)
+		if err != nil {
+			return nil, err
+		}
+		ae.LastProcedure = result
+		return &MockResult{rowsAffected: -1, lastInsertId: -1}, nil
+	}
 
 	// Check if MockDataSet is specified and exists
 	if action.MockDataSet != "" {
@@ -70,6 +103,13 @@ func (ae *ActionExecutor) Execute(
 		}
 	}
 
+	// Actions with a Concurrency column need the original value of the column
+	params, err := checkConcurrencyToken(action, params)
+	if err != nil {
+		ae.logger.Warn("Concurrency token missing", "actionId", action.Id, "column", action.Concurrency)
+		return nil, err
+	}
+
 	// Extract expected parameters from SQL
 	expectedParams := ExtractParameters(action.SQL)
 
@@ -79,19 +119,51 @@ func (ae *ActionExecutor) Execute(
 		return nil, err
 	}
 
-	// Convert parameters for the database driver
-	sql := action.SQL
-	driverName := db.DriverName()
-	sql = ConvertParametersForDriver(sql, driverName)
+	// Run on the data source named by the action
+	db, err = resolveDataSource(ae.dataSources, db, action.DataSource)
+	if err != nil {
+		ae.logger.Error("Data source not available", "actionId", action.Id, "dataSource", action.DataSource, "error", err)
+		return nil, err
+	}
 
-	// Build args slice in the order of parameters used in SQL
-	sql, args := ae.buildArgs(sql, params, driverName)
+	result, err := ae.exec(ctx, db, action, params)
+	if err != nil {
+		return nil, err
+	}
+	ae.invalidate(action)
+	return result, nil
+}
+
+// exec binds and runs the statement of an action on db, which may be a transaction
+func (ae *ActionExecutor) exec(
+	ctx context.Context,
+	db sqlx.ExtContext,
+	action *ActionQuery,
+	params map[string]any,
+) (sql.Result, error) {
+	startTime := time.Now()
+
+	// Bind parameters for the database driver
+	var result sql.Result
+	sql, args, err := BindParameters(action.SQL, params, db.DriverName(), ae.maxListSize)
+	if err != nil {
+		ae.logger.Error("Parameter binding failed", "actionId", action.Id, "error", err)
+		return nil, err
+	}
 
 	// Log colored SQL for debugging
 	ae.logColoredSQL(fmt.Sprintf("%s/%s", action.Parent, action.Id), sql, action.Type)
 
 	// Execute action
-	result, err := db.ExecContext(ctx, sql, args...)
+	timeout := parseTimeout(action.Timeout, ae.queryTimeout)
+	execCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	if action.Type == "Insert" {
+		// Inserts report the generated id the way the dialect of the connection provides it
+		result, err = ExecInsert(execCtx, db, DialectFor(db.DriverName()), sql, args...)
+	} else {
+		result, err = db.ExecContext(execCtx, sql, args...)
+	}
 	if err != nil {
 		ae.logger.Error("Action execution failed",
 			"actionId", action.Id,
@@ -99,7 +171,7 @@ func (ae *ActionExecutor) Execute(
 			"error", err,
 			"duration_ms", time.Since(startTime).Milliseconds(),
 		)
-		return nil, fmt.Errorf("failed to execute action %s: %w", action.Id, err)
+		return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to execute action %s: %w", action.Id, err), action.Id, timeout)
 	}
 
 	// Log execution details
@@ -111,6 +183,10 @@ func (ae *ActionExecutor) Execute(
 		)
 		rowsAffected = -1
 	}
+	if err := concurrencyConflict(action, rowsAffected); err != nil {
+		ae.logger.Warn("Concurrency conflict", "actionId", action.Id, "column", action.Concurrency)
+		return nil, err
+	}
 
 	ae.logger.Debug("Action executed successfully",
 		"actionId", action.Id,
@@ -119,7 +195,6 @@ func (ae *ActionExecutor) Execute(
 		"duration_ms", time.Since(startTime).Milliseconds(),
 		"params", ae.sanitizeParams(params),
 	)
-
 	return result, nil
 }
 
@@ -133,61 +208,6 @@ func (ae *ActionExecutor) validateParameters(required []string, provided map[str
 	return nil
 }
 
-// buildArgs constructs the arguments slice for the action based on parameter order
-func (ae *ActionExecutor) buildArgs(sql string, params map[string]interface{}, driverName string) (string, []interface{}) {
-	var args []any
-	switch driverName {
-	case "sqlserver":
-		// For SQL Server, extract @param names in order
-		paramRegex := regexp.MustCompile(`@(\w+)`)
-		matches := paramRegex.FindAllStringSubmatch(sql, -1)
-
-		//seen := make(map[string]bool)
-		for _, match := range matches {
-			paramName := match[1]
-			sql = strings.Replace(sql, match[0], fmt.Sprintf("'%s'", params[match[1]]), 1)
-			if val, ok := params[paramName]; ok {
-				args = append(args, val)
-				//					seen[paramName] = true
-			}
-		}
-
-	case "sqlite3", "sqlite":
-		// For SQLite, extract :param names in order
-		paramRegex := regexp.MustCompile(`:(\w+)`)
-		matches := paramRegex.FindAllStringSubmatch(sql, -1)
-
-		seen := make(map[string]bool)
-		for _, match := range matches {
-			paramName := match[1]
-			if !seen[paramName] {
-				if val, ok := params[paramName]; ok {
-					args = append(args, val)
-					seen[paramName] = true
-				}
-			}
-		}
-
-	default:
-		// Generic approach: extract all named parameters
-		paramRegex := regexp.MustCompile(`:\w+`)
-		matches := paramRegex.FindAllString(sql, -1)
-
-		seen := make(map[string]bool)
-		for _, match := range matches {
-			paramName := strings.TrimPrefix(match, ":")
-			if !seen[paramName] {
-				if val, ok := params[paramName]; ok {
-					args = append(args, val)
-					seen[paramName] = true
-				}
-			}
-		}
-	}
-
-	return sql, args
-}
-
 // sanitizeParams removes sensitive information from logs (e.g., passwords)
 func (ae *ActionExecutor) sanitizeParams(params map[string]interface{}) map[string]interface{} {
 	sensitiveKeys := []string{"password", "password_hash", "token", "secret", "api_key"}
@@ -213,15 +233,46 @@ func (ae *ActionExecutor) sanitizeParams(params map[string]interface{}) map[stri
 	return sanitized
 }
 
-// ExecuteWithReturning runs an INSERT/UPDATE/DELETE action with RETURNING clause
-// Returns both the sql.Result and any rows returned by the RETURNING clause
+// ExecuteWithReturning runs an INSERT/UPDATE/DELETE action and returns the affected
+// rows through OUTPUT on SQL Server and RETURNING on SQLite and PostgreSQL, read up to
+// LastMaxRows. Mock data sets provide the rows in their rows field.
 func (ae *ActionExecutor) ExecuteWithReturning(
 	ctx context.Context,
 	db *sqlx.DB,
 	action *ActionQuery,
 	params map[string]interface{},
 ) (sql.Result, []map[string]any, error) {
-	startTime := time.Now()
+	ae.LastTruncated = false
+	ae.LastMaxRows = parseMaxRows(action.MaxRows, ae.maxRows)
+
+	// Check if MockDataSet is specified and exists
+	if action.MockDataSet != "" {
+		if mockResult, err := ae.loadMockDataSet(action.MockDataSet); err == nil {
+			rows := mockResult.rows
+			if ae.LastMaxRows > 0 && len(rows) > ae.LastMaxRows {
+				rows, ae.LastTruncated = rows[:ae.LastMaxRows], true
+			}
+			ae.logger.Debug("Mock action with RETURNING executed successfully",
+				"actionId", action.Id,
+				"mockDataSet", action.MockDataSet,
+				"rowCount", len(rows),
+			)
+			return mockResult, rows, nil
+		} else if !os.IsNotExist(err) {
+			ae.logger.Warn("Mock data set error, falling back to database action",
+				"actionId", action.Id,
+				"mockDataSet", action.MockDataSet,
+				"error", err,
+			)
+		}
+	}
+
+	// Actions with a Concurrency column need the original value of the column
+	params, err := checkConcurrencyToken(action, params)
+	if err != nil {
+		ae.logger.Warn("Concurrency token missing", "actionId", action.Id, "column", action.Concurrency)
+		return nil, nil, err
+	}
 
 	// Extract expected parameters from SQL
 	expectedParams := ExtractParameters(action.SQL)
@@ -232,40 +283,25 @@ func (ae *ActionExecutor) ExecuteWithReturning(
 		return nil, nil, err
 	}
 
-	// Convert parameters for the database driver
-	sql := action.SQL
-	driverName := db.DriverName()
-	sql = ConvertParametersForDriver(sql, driverName)
-
-	// Build args slice in the order of parameters used in SQL
-	sql, args := ae.buildArgs(sql, params, driverName)
-
-	// Log colored SQL for debugging
-	ae.logColoredSQL(fmt.Sprintf("RETURNING %s/%s", action.Parent, action.Id), sql, action.Type)
-
-	// Execute action
-	result, err := db.ExecContext(ctx, sql, args...)
+	// Run on the data source named by the action
+	db, err = resolveDataSource(ae.dataSources, db, action.DataSource)
 	if err != nil {
-		ae.logger.Error("Action execution with RETURNING failed",
-			"actionId", action.Id,
-			"actionType", action.Type,
-			"error", err,
-			"duration_ms", time.Since(startTime).Milliseconds(),
-		)
-		return nil, nil, fmt.Errorf("failed to execute action %s: %w", action.Id, err)
+		ae.logger.Error("Data source not available", "actionId", action.Id, "dataSource", action.DataSource, "error", err)
+		return nil, nil, err
 	}
 
-	ae.logger.Debug("Action with RETURNING executed successfully",
-		"actionId", action.Id,
-		"actionType", action.Type,
-		"duration_ms", time.Since(startTime).Milliseconds(),
-		"params", ae.sanitizeParams(params),
-	)
+	rows, err := ae.queryReturning(ctx, db, action, params)
+	if err != nil {
+		return nil, nil, err
+	}
 
-	// Note: For databases that support RETURNING (PostgreSQL, SQLite),
-	// you would need to use QueryContext instead of ExecContext to get the rows.
-	// This method is a placeholder for future enhancement.
-	return result, []map[string]any{}, nil
+	// Every affected row is returned; the count is unknown once the rows are cut off
+	rowsAffected := int64(len(rows))
+	if ae.LastTruncated {
+		rowsAffected = -1
+	}
+	ae.invalidate(action)
+	return insertResult{rowsAffected: rowsAffected, lastInsertID: -1}, rows, nil
 }
 
 // ExecuteAndFetchRows runs a SELECT-based action (like RETURNING in a query)
@@ -287,19 +323,28 @@ func (ae *ActionExecutor) ExecuteAndFetchRows(
 		return nil, err
 	}
 
-	// Convert parameters for the database driver
-	sql := action.SQL
-	driverName := db.DriverName()
-	sql = ConvertParametersForDriver(sql, driverName)
+	// Run on the data source named by the action
+	db, err := resolveDataSource(ae.dataSources, db, action.DataSource)
+	if err != nil {
+		ae.logger.Error("Data source not available", "actionId", action.Id, "dataSource", action.DataSource, "error", err)
+		return nil, err
+	}
 
-	// Build args slice in the order of parameters used in SQL
-	sql, args := ae.buildArgs(sql, params, driverName)
+	// Bind parameters for the database driver
+	sql, args, err := BindParameters(action.SQL, params, db.DriverName(), ae.maxListSize)
+	if err != nil {
+		ae.logger.Error("Parameter binding failed", "actionId", action.Id, "error", err)
+		return nil, err
+	}
 
 	// Log colored SQL for debugging
 	ae.logColoredSQL(fmt.Sprintf("ACTION %s/%s", action.Parent, action.Id), sql, action.Type)
 
 	// Execute query for row-based actions (e.g., RETURNING clause)
-	sqlRows, err := db.QueryContext(ctx, sql, args...)
+	timeout := parseTimeout(action.Timeout, ae.queryTimeout)
+	execCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	sqlRows, err := db.QueryContext(execCtx, sql, args...)
 	if err != nil {
 		ae.logger.Error("Action query execution failed",
 			"actionId", action.Id,
@@ -307,20 +352,22 @@ func (ae *ActionExecutor) ExecuteAndFetchRows(
 			"error", err,
 			"duration_ms", time.Since(startTime).Milliseconds(),
 		)
-		return nil, fmt.Errorf("failed to execute action %s: %w", action.Id, err)
+		return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to execute action %s: %w", action.Id, err), action.Id, timeout)
 	}
 	defer sqlRows.Close()
 
-	// Convert rows to maps using the dbutil utility
-	rows, err := dbutil.RowsToMaps(sqlRows)
+	// Convert rows to maps using the dbutil utility, stopping at the row limit
+	ae.LastMaxRows = parseMaxRows(action.MaxRows, ae.maxRows)
+	rows, truncated, err := dbutil.RowsToMapsLimit(sqlRows, ae.LastMaxRows)
 	if err != nil {
 		ae.logger.Error("Failed to convert returned rows",
 			"actionId", action.Id,
 			"error", err,
 			"duration_ms", time.Since(startTime).Milliseconds(),
 		)
-		return nil, fmt.Errorf("failed to convert returned rows: %w", err)
+		return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to convert returned rows: %w", err), action.Id, timeout)
 	}
+	ae.LastTruncated = truncated
 
 	ae.logger.Debug("Action with row results executed successfully",
 		"actionId", action.Id,
@@ -330,13 +377,111 @@ func (ae *ActionExecutor) ExecuteAndFetchRows(
 		"params", ae.sanitizeParams(params),
 	)
 
+	ae.invalidate(action)
 	return rows, nil
 }
 
+// ExecuteProcedure runs a Procedure action and returns its result rows, output
+// parameters and return code
+func (ae *ActionExecutor) ExecuteProcedure(
+	ctx context.Context,
+	db *sqlx.DB,
+	action *ActionQuery,
+	params map[string]any,
+) (*ProcedureResult, error) {
+	ae.LastTruncated = false
+
+	if action.MockDataSet != "" {
+		if result, err := loadMockProcedureResult(ae.mockDataSetLocation, action.MockDataSet); err == nil {
+			ae.logger.Debug("Mock procedure action executed successfully",
+				"actionId", action.Id,
+				"mockDataSet", action.MockDataSet,
+				"returnValue", result.ReturnValue,
+			)
+			return result, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			ae.logger.Warn("Mock data set error, falling back to database procedure",
+				"actionId", action.Id,
+				"mockDataSet", action.MockDataSet,
+				"error", err,
+			)
+		}
+	}
+
+	db, err := resolveDataSource(ae.dataSources, db, action.DataSource)
+	if err != nil {
+		ae.logger.Error("Data source not available", "actionId", action.Id, "dataSource", action.DataSource, "error", err)
+		return nil, err
+	}
+
+	result, err := ae.callProcedure(ctx, db, action, params)
+	if err != nil {
+		return nil, err
+	}
+	ae.invalidate(action)
+	return result, nil
+}
+
+// callProcedure runs the procedure of an action on db, which may be a transaction
+func (ae *ActionExecutor) callProcedure(
+	ctx context.Context,
+	db sqlx.ExtContext,
+	action *ActionQuery,
+	params map[string]any,
+) (*ProcedureResult, error) {
+	startTime := time.Now()
+	ae.LastMaxRows = parseMaxRows(action.MaxRows, ae.maxRows)
+	sql, args, err := procedureStatement(db, action.SQL, action.Params, params, ae.maxListSize)
+	if err != nil {
+		ae.logger.Error("Procedure binding failed", "actionId", action.Id, "error", err)
+		return nil, err
+	}
+	ae.logColoredSQL(fmt.Sprintf("%s/%s", action.Parent, action.Id), sql, action.Type)
+
+	timeout := parseTimeout(action.Timeout, ae.queryTimeout)
+	execCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	sqlRows, err := db.QueryContext(execCtx, sql, args...)
+	if err != nil {
+		ae.logger.Error("Procedure execution failed", "actionId", action.Id, "error", err)
+		return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to execute procedure %s: %w", action.Id, err), action.Id, timeout)
+	}
+	defer sqlRows.Close()
+
+	result, err := readProcedureResult(sqlRows, nil, ae.LastMaxRows)
+	if err != nil {
+		ae.logger.Error("Failed to read procedure result", "actionId", action.Id, "error", err)
+		return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to read procedure result %s: %w", action.Id, err), action.Id, timeout)
+	}
+	ae.LastTruncated = result.Truncated
+
+	ae.logger.Debug("Procedure action executed successfully",
+		"actionId", action.Id,
+		"returnValue", result.ReturnValue,
+		"duration_ms", time.Since(startTime).Milliseconds(),
+	)
+	return result, nil
+}
+
+// invalidate drops the cached results of the queries named by the Invalidates attribute
+func (ae *ActionExecutor) invalidate(action *ActionQuery) {
+	ae.LastInvalidated = action.InvalidatedQueries()
+	if ae.cache == nil || len(ae.LastInvalidated) == 0 {
+		return
+	}
+	removed := ae.cache.Invalidate(action.Parent, ae.LastInvalidated...)
+	ae.logger.Debug("Query cache invalidated",
+		"actionId", action.Id,
+		"queries", ae.LastInvalidated,
+		"entries", removed,
+	)
+}
+
 // MockResult implements sql.Result for mock action execution
 type MockResult struct {
 	rowsAffected int64
 	lastInsertId int64
+	rows         []map[string]any
 }
 
 func (mr *MockResult) LastInsertId() (int64, error) {
@@ -351,6 +496,8 @@ func (mr *MockResult) RowsAffected() (int64, error) {
 type MockActionResponse struct {
 	RowsAffected int64 `json:"rowsAffected"`
 	LastInsertId int64 `json:"lastInsertId"`
+	// Rows are the rows returned by actions with Returning="true"
+	Rows []map[string]any `json:"rows,omitempty"`
 }
 
 // loadMockDataSet loads mock action response from a JSON file
@@ -373,6 +520,7 @@ func (ae *ActionExecutor) loadMockDataSet(filePath string) (*MockResult, error)
 	return &MockResult{
 		rowsAffected: mockResponse.RowsAffected,
 		lastInsertId: mockResponse.LastInsertId,
+		rows:         mockResponse.Rows,
 	}, nil
 }
 
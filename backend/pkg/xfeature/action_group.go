@@ -0,0 +1,231 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ActionGroup runs several ActionQueries in order in one database transaction. The
+// steps share one parameter bag: the request parameters plus what earlier steps
+// produced, the generated id of an Insert step named by its InsertId, the first row
+// returned by a Returning step and the output parameters of a Procedure step.
+type ActionGroup struct {
+	Parent      string        `xml:"-" json:"-"`
+	Id          string        `xml:"Id,attr" json:"id"`
+	Description string        `xml:"Description,attr" json:"description"`
+	Timeout     string        `xml:"Timeout,attr" json:"timeout"`
+	DataSource  string        `xml:"DataSource,attr" json:"dataSource"`
+	Steps       []*ActionStep `xml:"Step" json:"steps"`
+}
+
+// ActionStep is one ActionQuery of an ActionGroup
+type ActionStep struct {
+	ActionRef string `xml:"ActionRef,attr" json:"actionRef"`
+	// InsertId names the parameter that receives the id generated by an Insert step
+	InsertId string `xml:"InsertId,attr" json:"insertId,omitempty"`
+}
+
+// StepResult reports what one step of an ActionGroup did
+type StepResult struct {
+	Index        int            `json:"index"`
+	ActionRef    string         `json:"actionRef"`
+	RowsAffected int64          `json:"rowsAffected"`
+	LastInsertId int64          `json:"lastInsertId,omitempty"`
+	Outputs      map[string]any `json:"outputs,omitempty"`
+}
+
+// GroupResult holds the step results of a committed ActionGroup and the parameters
+// the steps added to the bag
+type GroupResult struct {
+	Steps   []*StepResult  `json:"steps"`
+	Outputs map[string]any `json:"outputs"`
+}
+
+// StepError reports the step of an ActionGroup, or the item of an atomic batch, that
+// failed; the transaction was rolled back
+type StepError struct {
+	Index     int
+	ActionRef string
+	Err       error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %d (%s) failed: %v", e.Index, e.ActionRef, e.Err)
+}
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// AsStepError returns the StepError in err's chain, if any
+func AsStepError(err error) (*StepError, bool) {
+	var serr *StepError
+	ok := errors.As(err, &serr)
+	return serr, ok
+}
+
+// GetActionGroup finds an action group by ID
+func (xf *XFeature) GetActionGroup(id string) (*ActionGroup, error) {
+	for _, group := range xf.Backend.ActionGroups {
+		if group.Id == id {
+			if group.Parent == "" {
+				group.Parent = xf.Name
+			}
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("action group not found: %s", id)
+}
+
+// ExecuteGroup runs the steps of an action group in one transaction on the data source
+// of the group and commits them if all succeed. The first failing step rolls the
+// transaction back and is reported as a StepError. Every step must run on the data
+// source of the group; mock data sets do not apply to groups.
+func (ae *ActionExecutor) ExecuteGroup(
+	ctx context.Context,
+	db *sqlx.DB,
+	xf *XFeature,
+	group *ActionGroup,
+	params map[string]any,
+) (*GroupResult, error) {
+	startTime := time.Now()
+	ae.LastInvalidated = nil
+
+	// Resolve the steps before anything runs
+	actions := make([]*ActionQuery, len(group.Steps))
+	for i, step := range group.Steps {
+		action, err := xf.GetActionQuery(step.ActionRef)
+		if err != nil {
+			return nil, &StepError{Index: i, ActionRef: step.ActionRef, Err: err}
+		}
+		if action.DataSource != group.DataSource {
+			err := fmt.Errorf("action runs on data source %q, the group on %q", action.DataSource, group.DataSource)
+			return nil, &StepError{Index: i, ActionRef: step.ActionRef, Err: err}
+		}
+		actions[i] = action
+	}
+
+	db, err := resolveDataSource(ae.dataSources, db, group.DataSource)
+	if err != nil {
+		ae.logger.Error("Data source not available", "groupId", group.Id, "dataSource", group.DataSource, "error", err)
+		return nil, err
+	}
+
+	// The Timeout of the group covers the whole transaction
+	timeout := parseTimeout(group.Timeout, ae.queryTimeout)
+	txCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	tx, err := db.BeginTxx(txCtx, nil)
+	if err != nil {
+		return nil, timeoutError(txCtx, ctx, fmt.Errorf("failed to begin transaction of group %s: %w", group.Id, err), group.Id, timeout)
+	}
+
+	bag := maps.Clone(params)
+	if bag == nil {
+		bag = make(map[string]any)
+	}
+	result := &GroupResult{Steps: make([]*StepResult, 0, len(group.Steps)), Outputs: make(map[string]any)}
+	for i, step := range group.Steps {
+		stepResult, err := ae.executeStep(txCtx, tx, actions[i], step, bag)
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				ae.logger.Error("Rollback failed", "groupId", group.Id, "error", rbErr)
+			}
+			ae.logger.Error("Action group rolled back",
+				"groupId", group.Id,
+				"step", i,
+				"actionRef", step.ActionRef,
+				"error", err,
+				"duration_ms", time.Since(startTime).Milliseconds(),
+			)
+			err = timeoutError(txCtx, ctx, err, group.Id, timeout)
+			return nil, &StepError{Index: i, ActionRef: step.ActionRef, Err: err}
+		}
+		stepResult.Index = i
+		result.Steps = append(result.Steps, stepResult)
+		maps.Copy(result.Outputs, stepResult.Outputs)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, timeoutError(txCtx, ctx, fmt.Errorf("failed to commit group %s: %w", group.Id, err), group.Id, timeout)
+	}
+
+	ae.logger.Debug("Action group committed",
+		"groupId", group.Id,
+		"steps", len(result.Steps),
+		"duration_ms", time.Since(startTime).Milliseconds(),
+		"params", ae.sanitizeParams(bag),
+	)
+
+	// Drop the cached results listed by the Invalidates attributes of the steps
+	var invalidated []string
+	for _, action := range actions {
+		ae.invalidate(action)
+		invalidated = append(invalidated, ae.LastInvalidated...)
+	}
+	ae.LastInvalidated = invalidated
+	return result, nil
+}
+
+// executeStep runs one step of a group in tx and adds what it produced to the bag
+func (ae *ActionExecutor) executeStep(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	action *ActionQuery,
+	step *ActionStep,
+	bag map[string]any,
+) (*StepResult, error) {
+	stepResult := &StepResult{ActionRef: step.ActionRef}
+
+	if action.IsProcedure() {
+		procedure, err := ae.callProcedure(ctx, tx, action, bag)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(bag, procedure.Outputs)
+		stepResult.RowsAffected, stepResult.Outputs = -1, procedure.Outputs
+		return stepResult, nil
+	}
+
+	params, err := checkConcurrencyToken(action, bag)
+	if err != nil {
+		return nil, err
+	}
+	if err := ae.validateParameters(ExtractParameters(action.SQL), params); err != nil {
+		return nil, err
+	}
+
+	// Returning steps add the columns of their first returned row to the bag
+	if action.Returning {
+		rows, err := ae.queryReturning(ctx, tx, action, params)
+		if err != nil {
+			return nil, err
+		}
+		stepResult.RowsAffected = int64(len(rows))
+		if len(rows) > 0 {
+			maps.Copy(bag, rows[0])
+			stepResult.Outputs = rows[0]
+		}
+		return stepResult, nil
+	}
+
+	result, err := ae.exec(ctx, tx, action, params)
+	if err != nil {
+		return nil, err
+	}
+	if stepResult.RowsAffected, err = result.RowsAffected(); err != nil {
+		stepResult.RowsAffected = -1
+	}
+	if step.InsertId != "" {
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("no generated id for %s: %w", step.InsertId, err)
+		}
+		bag[step.InsertId], stepResult.LastInsertId = id, id
+		stepResult.Outputs = map[string]any{step.InsertId: id}
+	}
+	return stepResult, nil
+}
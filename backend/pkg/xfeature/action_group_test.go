@@ -0,0 +1,91 @@
+package xfeature
+
+import (
+	"context"
+	"testing"
+)
+
+const actionGroupFeature = `<Feature Name="accounts" Version="1">
+	<Backend>
+		<ActionQuery Id="CreateUser" Type="Insert">INSERT INTO users (username, email) VALUES (:username, :email)</ActionQuery>
+		<ActionQuery Id="SetRole" Type="Update">UPDATE users SET role = :role WHERE user_id = :user_id</ActionQuery>
+		<ActionQuery Id="Broken" Type="Update">UPDATE missing_table SET role = :role</ActionQuery>
+		<ActionGroup Id="Onboard">
+			<Step ActionRef="CreateUser" InsertId="user_id"/>
+			<Step ActionRef="SetRole"/>
+		</ActionGroup>
+		<ActionGroup Id="OnboardBroken">
+			<Step ActionRef="CreateUser"/>
+			<Step ActionRef="Broken"/>
+		</ActionGroup>
+		<ActionGroup Id="Dangling">
+			<Step ActionRef="Missing"/>
+		</ActionGroup>
+	</Backend>
+	<Frontend/>
+</Feature>`
+
+// TestExecuteGroup tests that the steps of a group share their outputs and commit together
+func TestExecuteGroup(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	// Every connection to :memory: is a new database
+	db.SetMaxOpenConns(1)
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(actionGroupFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	group, err := xf.GetActionGroup("Onboard")
+	if err != nil || group.Parent != "accounts" || len(group.Steps) != 2 {
+		t.Fatalf("Unexpected group: %+v (error %v)", group, err)
+	}
+
+	params := map[string]any{"username": "ada", "email": "ada@example.com", "role": "admin"}
+	result, err := NewActionExecutor(testLogger).ExecuteGroup(context.Background(), db, xf, group, params)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Steps) != 2 || result.Steps[1].RowsAffected != 1 || result.Outputs["user_id"] != int64(1) {
+		t.Errorf("Unexpected group result: %+v %+v, outputs %v", result.Steps[0], result.Steps[1], result.Outputs)
+	}
+	if _, ok := params["user_id"]; ok {
+		t.Errorf("Expected the request parameters to be left unchanged")
+	}
+
+	var role string
+	if err := db.Get(&role, "SELECT role FROM users WHERE username = 'ada'"); err != nil || role != "admin" {
+		t.Errorf("Expected the second step to update the inserted user, got %q (error %v)", role, err)
+	}
+}
+
+// TestExecuteGroupRollback tests that a failing step rolls back the earlier steps
+func TestExecuteGroupRollback(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(actionGroupFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+
+	group, _ := xf.GetActionGroup("OnboardBroken")
+	params := map[string]any{"username": "ada", "email": "ada@example.com", "role": "admin"}
+	_, err := NewActionExecutor(testLogger).ExecuteGroup(context.Background(), db, xf, group, params)
+	serr, ok := AsStepError(err)
+	if !ok || serr.Index != 1 || serr.ActionRef != "Broken" {
+		t.Fatalf("Expected a StepError for step 1, got %v", err)
+	}
+
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM users"); err != nil || count != 0 {
+		t.Errorf("Expected the insert to be rolled back, found %d users (error %v)", count, err)
+	}
+
+	group, _ = xf.GetActionGroup("Dangling")
+	_, err = NewActionExecutor(testLogger).ExecuteGroup(context.Background(), db, xf, group, nil)
+	if serr, ok := AsStepError(err); !ok || serr.Index != 0 || serr.ActionRef != "Missing" {
+		t.Errorf("Expected a StepError for the missing action, got %v", err)
+	}
+}
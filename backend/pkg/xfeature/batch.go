@@ -0,0 +1,178 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Batch modes: atomic batches run every item in one transaction and roll all of them
+// back when one fails, best-effort batches run each item on its own and carry on
+// after failures
+const (
+	BatchAtomic     = "atomic"
+	BatchBestEffort = "bestEffort"
+)
+
+// ErrBatchTooLarge is returned when a batch has more parameter sets than allowed
+var ErrBatchTooLarge = errors.New("batch has too many items")
+
+// BatchItemResult reports what one parameter set of a batch did; Error is set for
+// failed items of best-effort batches
+type BatchItemResult struct {
+	Index        int              `json:"index"`
+	RowsAffected int64            `json:"rowsAffected"`
+	LastInsertId int64            `json:"lastInsertId,omitempty"`
+	Results      []map[string]any `json:"results,omitempty"`
+	Outputs      map[string]any   `json:"outputs,omitempty"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// BatchResult holds the item results of a batch with the indexes of the items that
+// succeeded and failed
+type BatchResult struct {
+	Items     []*BatchItemResult `json:"items"`
+	Succeeded []int              `json:"succeeded"`
+	Failed    []int              `json:"failed"`
+}
+
+// ExecuteBatch runs an action once for every parameter set. Atomic batches commit only
+// if every item succeeds; the first failing item rolls the transaction back and is
+// reported as a StepError with its index. Best-effort batches record failed items in
+// the result and return no error. Batches with more items than MaxBatchSize are
+// rejected with ErrBatchTooLarge. Returning actions return the affected rows of each
+// item; mock data sets do not apply to batches.
+func (ae *ActionExecutor) ExecuteBatch(
+	ctx context.Context,
+	db *sqlx.DB,
+	action *ActionQuery,
+	items []map[string]any,
+	mode string,
+) (*BatchResult, error) {
+	startTime := time.Now()
+	ae.LastInvalidated = nil
+	if mode != BatchAtomic && mode != BatchBestEffort {
+		return nil, fmt.Errorf("unknown batch mode: %s", mode)
+	}
+	if err := ae.CheckBatchSize(len(items)); err != nil {
+		return nil, err
+	}
+
+	db, err := resolveDataSource(ae.dataSources, db, action.DataSource)
+	if err != nil {
+		ae.logger.Error("Data source not available", "actionId", action.Id, "dataSource", action.DataSource, "error", err)
+		return nil, err
+	}
+
+	result := &BatchResult{Items: make([]*BatchItemResult, 0, len(items)), Succeeded: []int{}, Failed: []int{}}
+	if mode == BatchBestEffort {
+		for i, params := range items {
+			item, err := ae.executeItem(ctx, db, action, params)
+			if err != nil {
+				ae.logger.Warn("Batch item failed", "actionId", action.Id, "index", i, "error", err)
+				item = &BatchItemResult{RowsAffected: -1, Error: err.Error()}
+				result.Failed = append(result.Failed, i)
+			} else {
+				result.Succeeded = append(result.Succeeded, i)
+			}
+			item.Index = i
+			result.Items = append(result.Items, item)
+		}
+	} else {
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction of batch %s: %w", action.Id, err)
+		}
+		for i, params := range items {
+			item, err := ae.executeItem(ctx, tx, action, params)
+			if err != nil {
+				if rbErr := tx.Rollback(); rbErr != nil {
+					ae.logger.Error("Rollback failed", "actionId", action.Id, "error", rbErr)
+				}
+				ae.logger.Error("Batch rolled back",
+					"actionId", action.Id,
+					"index", i,
+					"error", err,
+					"duration_ms", time.Since(startTime).Milliseconds(),
+				)
+				return nil, &StepError{Index: i, ActionRef: action.Id, Err: err}
+			}
+			item.Index = i
+			result.Items = append(result.Items, item)
+			result.Succeeded = append(result.Succeeded, i)
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit batch %s: %w", action.Id, err)
+		}
+	}
+
+	ae.logger.Debug("Batch executed",
+		"actionId", action.Id,
+		"mode", mode,
+		"succeeded", len(result.Succeeded),
+		"failed", len(result.Failed),
+		"duration_ms", time.Since(startTime).Milliseconds(),
+	)
+	if len(result.Succeeded) > 0 {
+		ae.invalidate(action)
+	}
+	return result, nil
+}
+
+// CheckBatchSize returns ErrBatchTooLarge if a batch of n items exceeds MaxBatchSize
+func (ae *ActionExecutor) CheckBatchSize(n int) error {
+	if ae.maxBatchSize > 0 && n > ae.maxBatchSize {
+		return fmt.Errorf("%w: %d items, the maximum is %d", ErrBatchTooLarge, n, ae.maxBatchSize)
+	}
+	return nil
+}
+
+// executeItem runs an action with one parameter set of a batch on db, which may be a transaction
+func (ae *ActionExecutor) executeItem(
+	ctx context.Context,
+	db sqlx.ExtContext,
+	action *ActionQuery,
+	params map[string]any,
+) (*BatchItemResult, error) {
+	if action.IsProcedure() {
+		procedure, err := ae.callProcedure(ctx, db, action, params)
+		if err != nil {
+			return nil, err
+		}
+		return &BatchItemResult{RowsAffected: -1, Results: procedure.Rows, Outputs: procedure.Outputs}, nil
+	}
+
+	params, err := checkConcurrencyToken(action, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := ae.validateParameters(ExtractParameters(action.SQL), params); err != nil {
+		return nil, err
+	}
+
+	if action.Returning {
+		rows, err := ae.queryReturning(ctx, db, action, params)
+		if err != nil {
+			return nil, err
+		}
+		return &BatchItemResult{RowsAffected: int64(len(rows)), Results: rows}, nil
+	}
+
+	result, err := ae.exec(ctx, db, action, params)
+	if err != nil {
+		return nil, err
+	}
+	item := &BatchItemResult{}
+	if item.RowsAffected, err = result.RowsAffected(); err != nil {
+		item.RowsAffected = -1
+	}
+	if action.Type == "Insert" {
+		if id, err := result.LastInsertId(); err == nil {
+			item.LastInsertId = id
+		}
+	}
+	return item, nil
+}
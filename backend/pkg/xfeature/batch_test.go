@@ -0,0 +1,92 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+var batchItems = []map[string]any{
+	{"username": "ada", "email": "ada@example.com"},
+	{"username": "ada", "email": "ada2@example.com"},
+	{"username": "bob", "email": "bob@example.com"},
+}
+
+// TestExecuteBatchAtomic tests that a failing item rolls back the whole batch
+func TestExecuteBatchAtomic(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	executor := NewActionExecutor(testLogger)
+	insert := &ActionQuery{Id: "CreateUser", Type: "Insert", SQL: "INSERT INTO users (username, email) VALUES (:username, :email)"}
+
+	_, err := executor.ExecuteBatch(context.Background(), db, insert, batchItems, BatchAtomic)
+	if serr, ok := AsStepError(err); !ok || serr.Index != 1 {
+		t.Fatalf("Expected item 1 to fail, got %v", err)
+	}
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM users"); err != nil || count != 0 {
+		t.Errorf("Expected the batch to be rolled back, found %d users (error %v)", count, err)
+	}
+
+	result, err := executor.ExecuteBatch(context.Background(), db, insert, []map[string]any{batchItems[0], batchItems[2]}, BatchAtomic)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !slices.Equal(result.Succeeded, []int{0, 1}) || result.Items[1].LastInsertId != 2 {
+		t.Errorf("Unexpected batch result: %+v %+v", result.Items[0], result.Items[1])
+	}
+}
+
+// TestExecuteBatchBestEffort tests that failed items are reported by index and the others are kept
+func TestExecuteBatchBestEffort(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewActionExecutor(testLogger)
+	insert := &ActionQuery{Id: "CreateUser", Type: "Insert", SQL: "INSERT INTO users (username, email) VALUES (:username, :email)"}
+	items := append(slices.Clone(batchItems), map[string]any{"username": "eve"})
+
+	result, err := executor.ExecuteBatch(context.Background(), db, insert, items, BatchBestEffort)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !slices.Equal(result.Succeeded, []int{0, 2}) || !slices.Equal(result.Failed, []int{1, 3}) {
+		t.Errorf("Expected items 0 and 2 to succeed, got succeeded %v and failed %v", result.Succeeded, result.Failed)
+	}
+	if len(result.Items) != 4 || result.Items[3].Index != 3 || result.Items[3].Error == "" {
+		t.Errorf("Expected the missing parameter of item 3 to be reported, got %+v", result.Items[3])
+	}
+
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM users"); err != nil || count != 2 {
+		t.Errorf("Expected the successful items to be kept, found %d users (error %v)", count, err)
+	}
+}
+
+// TestExecuteBatchTooLarge tests that batches over MaxBatchSize are rejected before any item runs
+func TestExecuteBatchTooLarge(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewActionExecutorWithOptions(testLogger, ExecutorOptions{MaxBatchSize: 2})
+	insert := &ActionQuery{Id: "CreateUser", Type: "Insert", SQL: "INSERT INTO users (username, email) VALUES (:username, :email)"}
+	items := []map[string]any{
+		{"username": "ada", "email": "ada@example.com"},
+		{"username": "bob", "email": "bob@example.com"},
+		{"username": "cy", "email": "cy@example.com"},
+	}
+
+	if _, err := executor.ExecuteBatch(context.Background(), db, insert, items, BatchBestEffort); !errors.Is(err, ErrBatchTooLarge) {
+		t.Fatalf("Expected ErrBatchTooLarge, got %v", err)
+	}
+	var count int
+	if err := db.Get(&count, "SELECT COUNT(*) FROM users"); err != nil || count != 0 {
+		t.Errorf("Expected no item to run, found %d users (error %v)", count, err)
+	}
+	if err := executor.CheckBatchSize(2); err != nil {
+		t.Errorf("Expected a batch of MaxBatchSize items to be accepted, got %v", err)
+	}
+}
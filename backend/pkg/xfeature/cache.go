@@ -0,0 +1,144 @@
+package xfeature
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache status values reported by executors for queries with a Cache attribute
+const (
+	CacheHit  = "hit"
+	CacheMiss = "miss"
+)
+
+// cacheSweepInterval is the minimum time between removals of expired entries
+const cacheSweepInterval = time.Minute
+
+// CacheTTL returns how long results of the query may be cached, or 0 if the query is not cached
+func (q *Query) CacheTTL() time.Duration {
+	if q.Cache == "" {
+		return 0
+	}
+	ttl, err := time.ParseDuration(strings.TrimSpace(q.Cache))
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// InvalidatedQueries returns the Query Ids listed in the Invalidates attribute of the action
+func (a *ActionQuery) InvalidatedQueries() []string {
+	var ids []string
+	for _, id := range strings.Split(a.Invalidates, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// QueryCache is an in-process cache of query results shared by all executors.
+// Entries are keyed by feature, query Id, data source, query SQL and the normalized parameters,
+// so a reloaded feature definition with changed SQL never serves stale results.
+type QueryCache struct {
+	mu        sync.Mutex
+	entries   map[string]*cacheEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type cacheEntry struct {
+	feature string
+	queryId string
+	value   any
+	expires time.Time
+}
+
+// NewQueryCache creates an empty query cache
+func NewQueryCache() *QueryCache {
+	return &QueryCache{entries: make(map[string]*cacheEntry), now: time.Now}
+}
+
+// cacheKey builds the key of a query result. Parameters are serialized as JSON, which
+// orders map keys, so equal parameters give equal keys regardless of their order.
+// extra distinguishes variants of the same query such as pages. ok is false if the
+// parameters cannot be serialized, in which case the result is not cached.
+func cacheKey(query *Query, params map[string]any, extra any) (key string, ok bool) {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return "", false
+	}
+	extraJSON, err := json.Marshal(extra)
+	if err != nil {
+		return "", false
+	}
+	return strings.Join([]string{query.Parent, query.Id, query.DataSource, query.SQL, string(paramsJSON), string(extraJSON)}, "\x00"), true
+}
+
+// Get returns the cached value of a key if it has not expired
+func (c *QueryCache) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// Set stores the result of a query for ttl. Cached values are shared between
+// requests and must not be modified by callers.
+func (c *QueryCache) Set(key string, query *Query, value any, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if now.Sub(c.lastSweep) >= cacheSweepInterval {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.lastSweep = now
+	}
+
+	c.entries[key] = &cacheEntry{
+		feature: query.Parent,
+		queryId: query.Id,
+		value:   value,
+		expires: now.Add(ttl),
+	}
+}
+
+// Invalidate removes every cached result of the given queries of a feature and
+// returns the number of removed entries
+func (c *QueryCache) Invalidate(feature string, queryIds ...string) int {
+	if len(queryIds) == 0 {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for key, entry := range c.entries {
+		if entry.feature == feature && containsString(queryIds, entry.queryId) {
+			delete(c.entries, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of cached entries, including expired ones not yet removed
+func (c *QueryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,122 @@
+package xfeature
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestQueryCache tests key normalization, expiry and invalidation
+func TestQueryCache(t *testing.T) {
+	cache := NewQueryCache()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	query := &Query{Parent: "Users", Id: "ListUsers", SQL: "SELECT * FROM users WHERE status = :status", Cache: "1m"}
+	if ttl := query.CacheTTL(); ttl != time.Minute {
+		t.Fatalf("Expected a TTL of 1m, got %v", ttl)
+	}
+
+	key1, ok := cacheKey(query, map[string]any{"status": "active", "min_id": int64(5)}, nil)
+	if !ok {
+		t.Fatalf("Expected parameters to be serializable")
+	}
+	key2, _ := cacheKey(query, map[string]any{"min_id": float64(5), "status": "active"}, nil)
+	if key1 != key2 {
+		t.Errorf("Expected equal parameters to give equal keys")
+	}
+	if key3, _ := cacheKey(query, map[string]any{"status": "inactive", "min_id": int64(5)}, nil); key3 == key1 {
+		t.Errorf("Expected different parameters to give different keys")
+	}
+	if key4, _ := cacheKey(query, map[string]any{"status": "active", "min_id": int64(5)}, &PageRequest{Page: 2}); key4 == key1 {
+		t.Errorf("Expected pages to give different keys")
+	}
+
+	cache.Set(key1, query, []map[string]any{{"user_id": int64(1)}}, query.CacheTTL())
+	if _, ok := cache.Get(key1); !ok {
+		t.Errorf("Expected a cache hit")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Get(key1); ok {
+		t.Errorf("Expected the entry to expire after its TTL")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Expected the expired entry to be removed, got %d entries", cache.Len())
+	}
+
+	other := &Query{Parent: "Orders", Id: "ListUsers", SQL: query.SQL, Cache: "1m"}
+	otherKey, _ := cacheKey(other, nil, nil)
+	cache.Set(key1, query, []map[string]any{}, time.Minute)
+	cache.Set(otherKey, other, []map[string]any{}, time.Minute)
+	if removed := cache.Invalidate("Users", "ListUsers", "ListOrders"); removed != 1 {
+		t.Errorf("Expected 1 invalidated entry, got %d", removed)
+	}
+	if _, ok := cache.Get(otherKey); !ok {
+		t.Errorf("Expected the query of another feature to stay cached")
+	}
+
+	if ttl := (&Query{Cache: "later"}).CacheTTL(); ttl != 0 {
+		t.Errorf("Expected an invalid Cache attribute to disable caching, got %v", ttl)
+	}
+}
+
+// TestQueryExecutorCache tests cached execution and invalidation by an action
+func TestQueryExecutorCache(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	opts := ExecutorOptions{Cache: NewQueryCache()}
+	query := &Query{Parent: "Users", Id: "ListUsers", SQL: "SELECT username FROM users WHERE status = :status", Cache: "5m"}
+	action := &ActionQuery{
+		Parent:      "Users",
+		Id:          "CreateUser",
+		Type:        "Insert",
+		SQL:         "INSERT INTO users (username, email, status) VALUES (:username, :email, :status)",
+		Invalidates: "ListUsers, ListOrders",
+	}
+	params := map[string]any{"status": "active"}
+
+	count := func(expectedStatus string) int {
+		t.Helper()
+		executor := NewQueryExecutorWithOptions(testLogger, opts)
+		rows, err := executor.Execute(ctx, db, query, params)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if executor.LastCacheStatus != expectedStatus {
+			t.Errorf("Expected cache %s, got %q", expectedStatus, executor.LastCacheStatus)
+		}
+		return len(rows)
+	}
+
+	if n := count(CacheMiss); n != 0 {
+		t.Fatalf("Expected no users, got %d", n)
+	}
+
+	// A direct insert is not seen while the result is cached
+	if _, err := db.Exec("INSERT INTO users (username, email, status) VALUES ('a', 'a@example.com', 'active')"); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+	if n := count(CacheHit); n != 0 {
+		t.Errorf("Expected the cached empty result, got %d rows", n)
+	}
+
+	actionExecutor := NewActionExecutorWithOptions(testLogger, opts)
+	_, err := actionExecutor.Execute(ctx, db, action, map[string]any{"username": "b", "email": "b@example.com", "status": "active"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(actionExecutor.LastInvalidated) != 2 || actionExecutor.LastInvalidated[0] != "ListUsers" {
+		t.Errorf("Expected ListUsers and ListOrders to be invalidated, got %v", actionExecutor.LastInvalidated)
+	}
+
+	if n := count(CacheMiss); n != 2 {
+		t.Errorf("Expected 2 users after invalidation, got %d", n)
+	}
+
+	// Queries without a Cache attribute are not cached
+	query.Cache = ""
+	count("")
+}
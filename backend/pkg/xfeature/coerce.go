@@ -0,0 +1,385 @@
+package xfeature
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Data types accepted by the Mapping DataType attribute.
+// Any of them may be suffixed with [] to declare an array, e.g. Int[].
+const (
+	DataTypeInt      = "Int"
+	DataTypeDecimal  = "Decimal"
+	DataTypeString   = "String"
+	DataTypeBool     = "Bool"
+	DataTypeDate     = "Date"
+	DataTypeDateTime = "DateTime"
+	DataTypeGuid     = "Guid"
+)
+
+// DataTypes lists the scalar Mapping data types
+var DataTypes = []string{
+	DataTypeInt, DataTypeDecimal, DataTypeString, DataTypeBool,
+	DataTypeDate, DataTypeDateTime, DataTypeGuid,
+}
+
+var guidRegex = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+var dateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// FieldError describes a parameter that could not be converted to its declared type
+type FieldError struct {
+	Field    string `json:"field"`
+	DataType string `json:"dataType"`
+	Message  string `json:"message"`
+}
+
+// CoercionError is returned when one or more parameters have invalid values
+type CoercionError struct {
+	Fields []FieldError
+}
+
+func (e *CoercionError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, fmt.Sprintf("%s: %s", f.Field, f.Message))
+	}
+	return "invalid parameters: " + strings.Join(parts, "; ")
+}
+
+// AsCoercionError extracts a CoercionError from an error chain
+func AsCoercionError(err error) (*CoercionError, bool) {
+	var cerr *CoercionError
+	if errors.As(err, &cerr) {
+		return cerr, true
+	}
+	return nil, false
+}
+
+// CoerceParameters converts incoming parameters to the Go types of their Mapping DataType.
+// Parameters without a Mapping keep their value, except that JSON numbers become
+// int64 when they are integral and float64 otherwise.
+// All conversion failures are collected into a single CoercionError.
+func (xf *XFeature) CoerceParameters(params map[string]any) (map[string]any, error) {
+	mappings := make(map[string]*Mapping, len(xf.Mappings))
+	for _, mapping := range xf.Mappings {
+		mappings[mapping.Name] = mapping
+	}
+
+	coerced := make(map[string]any, len(params))
+	var fields []FieldError
+	for _, name := range sortedKeys(params) {
+		value := params[name]
+		mapping, ok := mappings[name]
+		if !ok || mapping.DataType == "" {
+			coerced[name] = normalizeNumber(value)
+			continue
+		}
+
+		converted, errs := coerceField(name, value, mapping.DataType)
+		if len(errs) > 0 {
+			fields = append(fields, errs...)
+			continue
+		}
+		coerced[name] = converted
+	}
+
+	if len(fields) > 0 {
+		return nil, &CoercionError{Fields: fields}
+	}
+	return coerced, nil
+}
+
+// CoerceValue converts a single value to the given data type
+func CoerceValue(value any, dataType string) (any, error) {
+	converted, errs := coerceField("", value, dataType)
+	if len(errs) > 0 {
+		return nil, errors.New(errs[0].Message)
+	}
+	return converted, nil
+}
+
+// coerceField converts a parameter value, reporting errors against the field name
+func coerceField(field string, value any, dataType string) (any, []FieldError) {
+	elemType, isArray := strings.CutSuffix(dataType, "[]")
+	if !isArray {
+		converted, err := coerceScalar(value, dataType)
+		if err != nil {
+			return nil, []FieldError{{Field: field, DataType: dataType, Message: err.Error()}}
+		}
+		return converted, nil
+	}
+
+	if value == nil {
+		return nil, nil
+	}
+
+	var items []any
+	switch v := value.(type) {
+	case []any:
+		items = v
+	case []string:
+		for _, s := range v {
+			items = append(items, s)
+		}
+	case string:
+		// Comma separated list, as sent in query strings
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+	default:
+		return nil, []FieldError{{Field: field, DataType: dataType, Message: fmt.Sprintf("expected an array of %s", elemType)}}
+	}
+
+	converted := make([]any, 0, len(items))
+	var errs []FieldError
+	for i, item := range items {
+		c, err := coerceScalar(item, elemType)
+		if err != nil {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("%s[%d]", field, i), DataType: elemType, Message: err.Error()})
+			continue
+		}
+		converted = append(converted, c)
+	}
+	return converted, errs
+}
+
+// coerceScalar converts a single value; nil is always passed through as NULL
+func coerceScalar(value any, dataType string) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if values, ok := value.([]string); ok {
+		if len(values) != 1 {
+			return nil, fmt.Errorf("expected a single %s value", dataType)
+		}
+		value = values[0]
+	}
+
+	switch dataType {
+	case DataTypeInt:
+		return toInt(value)
+	case DataTypeDecimal:
+		return toDecimal(value)
+	case DataTypeString:
+		return toString(value)
+	case DataTypeBool:
+		return toBool(value)
+	case DataTypeDate:
+		t, err := toTime(value, DataTypeDate)
+		if err != nil {
+			return nil, err
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	case DataTypeDateTime:
+		return toTime(value, DataTypeDateTime)
+	case DataTypeGuid:
+		return toGuid(value)
+	default:
+		return nil, fmt.Errorf("unsupported data type %q", dataType)
+	}
+}
+
+func toInt(value any) (int64, error) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		// float64(math.MaxInt64) rounds up to 2^63, which is out of range
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= 1<<63 {
+			return 0, fmt.Errorf("expected an integer, got %v", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return parseInt(v.String())
+	case string:
+		return parseInt(strings.TrimSpace(v))
+	}
+	return 0, fmt.Errorf("expected an integer, got %s", describe(value))
+}
+
+func parseInt(s string) (int64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected an integer, got %q", s)
+	}
+	return n, nil
+}
+
+// maxDecimalExponent bounds the exponent of decimal literals, beyond the range of SQL decimals
+const maxDecimalExponent = 64
+
+// decimalRegex matches a decimal literal with an optional exponent
+var decimalRegex = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?$`)
+
+// toDecimal returns a decimal as its canonical literal, so values such as money amounts
+// are bound exactly instead of being rounded to a float64
+func toDecimal(value any) (string, error) {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return "", fmt.Errorf("expected a decimal number, got %v", v)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return parseDecimal(v.String())
+	case string:
+		return parseDecimal(strings.TrimSpace(v))
+	}
+	return "", fmt.Errorf("expected a decimal number, got %s", describe(value))
+}
+
+// parseDecimal validates a decimal literal and returns it without sign, leading zeros
+// or exponent, keeping the scale it was written with, e.g. +012.50 becomes 12.50
+func parseDecimal(s string) (string, error) {
+	if !decimalRegex.MatchString(s) {
+		return "", fmt.Errorf("expected a decimal number, got %q", s)
+	}
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa = s[:i]
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil || e < -maxDecimalExponent || e > maxDecimalExponent {
+			return "", fmt.Errorf("expected a decimal number, got %q", s)
+		}
+		exponent = e
+	}
+	scale := 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		scale = len(mantissa) - i - 1
+	}
+	scale = max(scale-exponent, 0)
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return "", fmt.Errorf("expected a decimal number, got %q", s)
+	}
+	return r.FloatString(scale), nil
+}
+
+func toString(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int, int64, bool:
+		return fmt.Sprint(v), nil
+	}
+	return "", fmt.Errorf("expected a string, got %s", describe(value))
+}
+
+func toBool(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return false, fmt.Errorf("expected true or false, got %q", v)
+		}
+		return b, nil
+	case json.Number, float64, int, int64:
+		switch fmt.Sprint(v) {
+		case "0":
+			return false, nil
+		case "1":
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("expected true or false, got %s", describe(value))
+}
+
+func toTime(value any, dataType string) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected an ISO 8601 %s string, got %s", strings.ToLower(dataType), describe(value))
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if dataType == DataTypeDate {
+		return time.Time{}, fmt.Errorf("expected a date like 2006-01-02, got %q", s)
+	}
+	return time.Time{}, fmt.Errorf("expected a date and time like 2006-01-02T15:04:05Z, got %q", s)
+}
+
+func toGuid(value any) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a GUID string, got %s", describe(value))
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "{"), "}")
+	if !guidRegex.MatchString(s) {
+		return "", fmt.Errorf("expected a GUID like 6F9619FF-8B86-D011-B42D-00C04FC964FF, got %q", value)
+	}
+	return strings.ToUpper(s), nil
+}
+
+// normalizeNumber turns JSON numbers into int64 or float64 so untyped parameters
+// are not sent to the driver as text
+func normalizeNumber(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = normalizeNumber(item)
+		}
+		return items
+	}
+	return value
+}
+
+// describe names the JSON kind of a value for error messages
+func describe(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case json.Number, float64, int, int64:
+		return fmt.Sprintf("number %v", v)
+	case []any, []string:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
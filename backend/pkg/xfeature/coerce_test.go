@@ -0,0 +1,167 @@
+package xfeature
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestCoerceValue tests conversion of single values for every data type
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    any
+		dataType string
+		expected any
+		wantErr  bool
+	}{
+		{"Int from float64", float64(42), "Int", int64(42), false},
+		{"Int from json.Number", json.Number("9007199254740993"), "Int", int64(9007199254740993), false},
+		{"Int from string", " 7 ", "Int", int64(7), false},
+		{"Int rejects fraction", 1.5, "Int", nil, true},
+		{"Int rejects 2^63", float64(9223372036854775808), "Int", nil, true},
+		{"Int accepts -2^63", float64(-9223372036854775808), "Int", int64(math.MinInt64), false},
+		{"Int rejects text", "abc", "Int", nil, true},
+		{"Int rejects bool", true, "Int", nil, true},
+		{"Decimal from string", "12.50", "Decimal", "12.50", false},
+		{"Decimal from json.Number", json.Number("3"), "Decimal", "3", false},
+		{"Decimal keeps precision", json.Number("12345678901234.5678"), "Decimal", "12345678901234.5678", false},
+		{"Decimal canonical", " +0012.50e1 ", "Decimal", "125.0", false},
+		{"Decimal from float64", 0.1, "Decimal", "0.1", false},
+		{"Decimal rejects text", "12,50", "Decimal", nil, true},
+		{"String from number", json.Number("10"), "String", "10", false},
+		{"String rejects object", map[string]any{}, "String", nil, true},
+		{"Bool from string", "false", "Bool", false, false},
+		{"Bool from number", json.Number("1"), "Bool", true, false},
+		{"Bool rejects text", "maybe", "Bool", nil, true},
+		{"Date", "2024-03-05", "Date", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), false},
+		{"Date drops time", "2024-03-05T10:30:00Z", "Date", time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), false},
+		{"Date rejects format", "05/03/2024", "Date", nil, true},
+		{"DateTime", "2024-03-05T10:30:00Z", "DateTime", time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC), false},
+		{"DateTime without zone", "2024-03-05 10:30:00", "DateTime", time.Date(2024, 3, 5, 10, 30, 0, 0, time.UTC), false},
+		{"DateTime rejects number", float64(1700000000), "DateTime", nil, true},
+		{"Guid", "{6f9619ff-8b86-d011-b42d-00c04fc964ff}", "Guid", "6F9619FF-8B86-D011-B42D-00C04FC964FF", false},
+		{"Guid rejects text", "not-a-guid", "Guid", nil, true},
+		{"Nil stays nil", nil, "Int", nil, false},
+		{"Int array", []any{json.Number("1"), "2"}, "Int[]", []any{int64(1), int64(2)}, false},
+		{"Int array from query string", "1, 2,3", "Int[]", []any{int64(1), int64(2), int64(3)}, false},
+		{"Array rejects scalar", float64(1), "Int[]", nil, true},
+		{"Unknown type", "x", "Money", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CoerceValue(tt.value, tt.dataType)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %v (%T)", result, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.expected, tt.expected, result, result)
+			}
+		})
+	}
+}
+
+// TestCoerceParameters tests Mapping-driven conversion and field-level errors
+func TestCoerceParameters(t *testing.T) {
+	xf := NewXFeature(testLogger)
+	xf.Mappings = []*Mapping{
+		{Name: "user_id", DataType: "Int"},
+		{Name: "since", DataType: "Date"},
+		{Name: "ids", DataType: "Int[]"},
+		{Name: "active", DataType: "Bool"},
+	}
+
+	params, err := xf.CoerceParameters(map[string]any{
+		"user_id": float64(5),
+		"since":   "2024-01-31",
+		"ids":     []string{"1", "2"},
+		"active":  nil,
+		"limit":   json.Number("10"),
+		"note":    "free text",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]any{
+		"user_id": int64(5),
+		"since":   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		"ids":     []any{int64(1), int64(2)},
+		"active":  nil,
+		"limit":   int64(10),
+		"note":    "free text",
+	}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("Expected %v, got %v", expected, params)
+	}
+
+	_, err = xf.CoerceParameters(map[string]any{
+		"user_id": "abc",
+		"since":   "yesterday",
+		"ids":     []any{"1", "x"},
+		"active":  true,
+	})
+	cerr, ok := AsCoercionError(err)
+	if !ok {
+		t.Fatalf("Expected CoercionError, got %v", err)
+	}
+
+	fields := make(map[string]string)
+	for _, f := range cerr.Fields {
+		fields[f.Field] = f.DataType
+	}
+	expectedFields := map[string]string{"user_id": "Int", "since": "Date", "ids[1]": "Int"}
+	if !reflect.DeepEqual(fields, expectedFields) {
+		t.Errorf("Expected field errors %v, got %v", expectedFields, cerr.Fields)
+	}
+}
+
+// TestDecimalBindsExactly tests that a Decimal parameter reaches the driver unrounded
+func TestDecimalBindsExactly(t *testing.T) {
+	xf := NewXFeature(testLogger)
+	xf.Mappings = []*Mapping{{Name: "amount", DataType: "Decimal"}}
+
+	params, err := xf.CoerceParameters(map[string]any{"amount": json.Number("12345678901234.5678")})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, args, err := BindParameters("SELECT * FROM invoices WHERE amount = :amount", params, "sqlserver", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(args) != 1 {
+		t.Fatalf("Expected 1 argument, got %v", args)
+	}
+	if named, ok := args[0].(sql.NamedArg); ok {
+		args[0] = named.Value
+	}
+	if args[0] != "12345678901234.5678" {
+		t.Errorf("Expected the exact decimal 12345678901234.5678, got %v (%T)", args[0], args[0])
+	}
+}
+
+// TestMappingDataTypeSchema tests that unknown Mapping data types are rejected at load time
+func TestMappingDataTypeSchema(t *testing.T) {
+	xmlContent := `<Feature Name="x" Version="1"><Backend/><Frontend/>
+  <Mapping Name="ids" DataType="Int[]" Label="IDs"/>
+  <Mapping Name="amount" DataType="Money" Label="Amount"/>
+</Feature>`
+
+	verr, ok := AsValidationError(ValidateSchema([]byte(xmlContent)))
+	if !ok {
+		t.Fatal("Expected ValidationError")
+	}
+	if len(verr.Diagnostics) != 1 || verr.Diagnostics[0].Line != 3 {
+		t.Errorf("Expected a single diagnostic on line 3, got %v", verr.Diagnostics)
+	}
+}
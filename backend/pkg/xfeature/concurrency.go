@@ -0,0 +1,128 @@
+package xfeature
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrConcurrencyConflict is returned when an action with a Concurrency column changed
+// no rows because the row was modified or deleted since it was read
+var ErrConcurrencyConflict = errors.New("concurrency conflict")
+
+// ErrConcurrencyTokenMissing is returned when an action with a Concurrency column is
+// run without the original value of the column
+var ErrConcurrencyTokenMissing = errors.New("concurrency token missing")
+
+// checkConcurrencyToken requires the original value of the Concurrency column of the
+// action as the parameter named by the column. Hex tokens of binary columns like
+// rowversion are decoded, so the returned parameters may be a copy.
+func checkConcurrencyToken(action *ActionQuery, params map[string]any) (map[string]any, error) {
+	if action.Concurrency == "" {
+		return params, nil
+	}
+	token, ok := params[action.Concurrency]
+	if !ok || token == nil || token == "" {
+		return nil, fmt.Errorf("%w: action %s needs the original %s", ErrConcurrencyTokenMissing, action.Id, action.Concurrency)
+	}
+	if decoded, ok := DecodeConcurrencyToken(token); ok {
+		params = maps.Clone(params)
+		params[action.Concurrency] = decoded
+	}
+	return params, nil
+}
+
+// concurrencyConflict returns ErrConcurrencyConflict if an action with a Concurrency
+// column affected no rows
+func concurrencyConflict(action *ActionQuery, rowsAffected int64) error {
+	if action.Concurrency == "" || rowsAffected != 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: action %s changed no rows, %s no longer matches", ErrConcurrencyConflict, action.Id, action.Concurrency)
+}
+
+// EncodeConcurrencyToken returns the value of a Concurrency column the way clients send
+// it back: binary values like rowversion as 0x-prefixed hex, anything else unchanged
+func EncodeConcurrencyToken(value any) any {
+	if b, ok := value.([]byte); ok {
+		return "0x" + strings.ToUpper(hex.EncodeToString(b))
+	}
+	return value
+}
+
+// DecodeConcurrencyToken turns a 0x-prefixed hex token back into bytes
+func DecodeConcurrencyToken(token any) ([]byte, bool) {
+	s, ok := token.(string)
+	if !ok || len(s) < 3 || !strings.HasPrefix(strings.ToLower(s), "0x") {
+		return nil, false
+	}
+	b, err := hex.DecodeString(s[2:])
+	return b, err == nil
+}
+
+// ConflictQuery returns the query that reads the current row of an action with a
+// Concurrency column: its ConflictQueryRef, or the QueryRef of the Form submitting it
+func (xf *XFeature) ConflictQuery(action *ActionQuery) *Query {
+	queryID := action.ConflictQueryRef
+	if queryID == "" {
+		for _, form := range xf.Frontend.Forms {
+			if form.ActionRef == action.Id && form.QueryRef != "" {
+				queryID = form.QueryRef
+				break
+			}
+		}
+	}
+	if queryID == "" {
+		return nil
+	}
+	query, err := xf.GetQuery(queryID)
+	if err != nil {
+		return nil
+	}
+	return query
+}
+
+// CurrentRow reads the current row of an action rejected by its concurrency check with
+// its ConflictQuery. The cache of opts is bypassed: a cached row would carry the stale
+// token the action was just rejected for. ok is false when the action has no conflict
+// query; a nil row means the row was deleted.
+func (xf *XFeature) CurrentRow(
+	ctx context.Context,
+	db *sqlx.DB,
+	action *ActionQuery,
+	params map[string]any,
+	opts ExecutorOptions,
+) (row map[string]any, ok bool, err error) {
+	query := xf.ConflictQuery(action)
+	if query == nil {
+		return nil, false, nil
+	}
+	opts.Cache = nil
+	rows, err := NewQueryExecutorWithOptions(xf.Logger, opts).Execute(ctx, db, query, params)
+	if err != nil {
+		return nil, true, fmt.Errorf("conflict query %s: %w", query.Id, err)
+	}
+	if len(rows) == 0 {
+		return nil, true, nil
+	}
+	return rows[0], true, nil
+}
+
+// ConcurrencyColumn returns the Concurrency column of the action submitted by a Form
+// that prefills from the query, or an empty string
+func (xf *XFeature) ConcurrencyColumn(queryID string) string {
+	for _, form := range xf.Frontend.Forms {
+		if form.QueryRef != queryID || form.ActionRef == "" {
+			continue
+		}
+		if action, err := xf.GetActionQuery(form.ActionRef); err == nil && action.Concurrency != "" {
+			return action.Concurrency
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package xfeature
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+const concurrencyFeature = `<Feature Name="accounts" Version="1">
+	<Backend>
+		<Query Id="GetUser" Type="Select">SELECT user_id, email, version FROM users WHERE user_id = :user_id</Query>
+		<ActionQuery Id="UpdateEmail" Type="Update" Concurrency="version">
+			UPDATE users SET email = :email, version = version + 1 WHERE user_id = :user_id AND version = :version
+		</ActionQuery>
+	</Backend>
+	<Frontend>
+		<Form Id="EditUserForm" Mode="Edit" Dialog="true" Title="Edit" ActionRef="UpdateEmail" QueryRef="GetUser">
+			<Field Name="email" Type="Email"/>
+		</Form>
+	</Frontend>
+</Feature>`
+
+// TestConcurrencyConflict tests that updates with a stale token are rejected
+func TestConcurrencyConflict(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+		INSERT INTO users (username, email) VALUES ('ada', 'ada@example.com')`); err != nil {
+		t.Fatalf("Failed to prepare test data: %v", err)
+	}
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(concurrencyFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	action, _ := xf.GetActionQuery("UpdateEmail")
+	executor := NewActionExecutor(testLogger)
+	ctx := context.Background()
+
+	_, err := executor.Execute(ctx, db, action, map[string]any{"user_id": 1, "email": "a@example.com"})
+	if !errors.Is(err, ErrConcurrencyTokenMissing) {
+		t.Errorf("Expected ErrConcurrencyTokenMissing, got %v", err)
+	}
+
+	if _, err := executor.Execute(ctx, db, action, map[string]any{"user_id": 1, "email": "a@example.com", "version": 1}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The second editor still holds version 1
+	_, err = executor.Execute(ctx, db, action, map[string]any{"user_id": 1, "email": "b@example.com", "version": 1})
+	if !errors.Is(err, ErrConcurrencyConflict) {
+		t.Fatalf("Expected ErrConcurrencyConflict, got %v", err)
+	}
+	var email string
+	if err := db.Get(&email, "SELECT email FROM users WHERE user_id = 1"); err != nil || email != "a@example.com" {
+		t.Errorf("Expected the first change to be kept, got %q (error %v)", email, err)
+	}
+
+	// The current row comes from the QueryRef of the Form submitting the action
+	query := xf.ConflictQuery(action)
+	if query == nil || query.Id != "GetUser" || xf.ConcurrencyColumn("GetUser") != "version" {
+		t.Errorf("Expected GetUser to read the current row, got %v", query)
+	}
+}
+
+// TestCurrentRowBypassesCache tests that the current row of a conflict is read from the
+// database even when the prefill query is cached
+func TestCurrentRowBypassesCache(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+		INSERT INTO users (username, email) VALUES ('ada', 'ada@example.com')`); err != nil {
+		t.Fatalf("Failed to prepare test data: %v", err)
+	}
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(concurrencyFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	query, _ := xf.GetQuery("GetUser")
+	query.Cache = "5m"
+	action, _ := xf.GetActionQuery("UpdateEmail")
+	opts := ExecutorOptions{Cache: NewQueryCache()}
+	ctx := context.Background()
+	params := map[string]any{"user_id": 1}
+
+	// The form prefill caches version 1
+	if _, err := NewQueryExecutorWithOptions(testLogger, opts).Execute(ctx, db, query, params); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := db.Exec("UPDATE users SET version = 2 WHERE user_id = 1"); err != nil {
+		t.Fatalf("Failed to update test data: %v", err)
+	}
+
+	row, ok, err := xf.CurrentRow(ctx, db, action, params, opts)
+	if err != nil || !ok {
+		t.Fatalf("Expected the current row, got ok %v, error %v", ok, err)
+	}
+	if version := normalizeNumber(row["version"]); version != int64(2) {
+		t.Errorf("Expected the current version 2 instead of the cached one, got %v", version)
+	}
+
+	if _, err := db.Exec("DELETE FROM users WHERE user_id = 1"); err != nil {
+		t.Fatalf("Failed to delete test data: %v", err)
+	}
+	if row, ok, err := xf.CurrentRow(ctx, db, action, params, opts); err != nil || !ok || row != nil {
+		t.Errorf("Expected no row for a deleted row, got %v, ok %v, error %v", row, ok, err)
+	}
+}
+
+// TestConcurrencyToken tests the encoding of binary tokens like rowversion
+func TestConcurrencyToken(t *testing.T) {
+	rowversion := []byte{0, 0, 0, 0, 0, 0, 0x07, 0xD1}
+	token := EncodeConcurrencyToken(rowversion)
+	if token != "0x00000000000007D1" {
+		t.Errorf("Unexpected token %v", token)
+	}
+	if EncodeConcurrencyToken(int64(3)) != int64(3) {
+		t.Errorf("Expected non-binary values to be left unchanged")
+	}
+
+	action := &ActionQuery{Id: "UpdateUser", Concurrency: "row_version"}
+	params := map[string]any{"row_version": token}
+	checked, err := checkConcurrencyToken(action, params)
+	if err != nil || !bytes.Equal(checked["row_version"].([]byte), rowversion) {
+		t.Errorf("Expected the token to be decoded, got %v (error %v)", checked["row_version"], err)
+	}
+	if params["row_version"] != token {
+		t.Errorf("Expected the request parameters to be left unchanged")
+	}
+}
@@ -0,0 +1,31 @@
+package xfeature
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultDataSource names the main database connection
+const DefaultDataSource = "default"
+
+// ErrUnknownDataSource is returned when a query names a data source that is not configured
+var ErrUnknownDataSource = errors.New("unknown data source")
+
+// DataSources resolves the DataSource attribute of queries and actions to a connection pool
+type DataSources interface {
+	DataSource(name string) (*sqlx.DB, error)
+}
+
+// resolveDataSource returns the pool of a named data source. Queries without a
+// DataSource, or naming DefaultDataSource, run on db.
+func resolveDataSource(sources DataSources, db *sqlx.DB, name string) (*sqlx.DB, error) {
+	if name == "" || name == DefaultDataSource {
+		return db, nil
+	}
+	if sources == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDataSource, name)
+	}
+	return sources.DataSource(name)
+}
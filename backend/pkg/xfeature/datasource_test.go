@@ -0,0 +1,99 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// testDataSources resolves data sources from a map
+type testDataSources map[string]*sqlx.DB
+
+func (s testDataSources) DataSource(name string) (*sqlx.DB, error) {
+	db, ok := s[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDataSource, name)
+	}
+	return db, nil
+}
+
+// TestDataSourceRouting tests that queries and actions run on the data source they name
+func TestDataSourceRouting(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	archive := setupTestDB(t)
+	defer archive.Close()
+
+	if _, err := archive.Exec("INSERT INTO users (username, email) VALUES ('archived', 'archived@example.com')"); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	xf := NewXFeature(testLogger)
+	err := xf.Parse([]byte(`<Feature Name="users" Version="1" DataSource="archive">
+	<Backend>
+		<Query Id="ArchivedUsers" Type="Select">SELECT username FROM users</Query>
+		<Query Id="CurrentUsers" Type="Select" DataSource="default">SELECT username FROM users</Query>
+		<ActionQuery Id="Archive" Type="Insert">INSERT INTO users (username, email) VALUES (:username, :email)</ActionQuery>
+	</Backend>
+	<Frontend/>
+</Feature>`))
+	if err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+
+	ctx := context.Background()
+	opts := ExecutorOptions{DataSources: testDataSources{"archive": archive}}
+
+	archived, _ := xf.GetQuery("ArchivedUsers")
+	if archived.DataSource != "archive" {
+		t.Fatalf("Expected the query to inherit DataSource archive, got %q", archived.DataSource)
+	}
+	rows, err := NewQueryExecutorWithOptions(testLogger, opts).Execute(ctx, db, archived, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rows) != 1 || rows[0]["username"] != "archived" {
+		t.Errorf("Expected the archived user, got %v", rows)
+	}
+
+	current, _ := xf.GetQuery("CurrentUsers")
+	rows, err = NewQueryExecutorWithOptions(testLogger, opts).Execute(ctx, db, current, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("Expected no users on the default data source, got %v", rows)
+	}
+
+	action, _ := xf.GetActionQuery("Archive")
+	params := map[string]any{"username": "moved", "email": "moved@example.com"}
+	if _, err := NewActionExecutorWithOptions(testLogger, opts).Execute(ctx, db, action, params); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var count int
+	if err := archive.Get(&count, "SELECT COUNT(*) FROM users"); err != nil || count != 2 {
+		t.Errorf("Expected the action to insert into the archive, got %d users (error %v)", count, err)
+	}
+}
+
+// TestUnknownDataSource tests that naming an unconfigured data source fails
+func TestUnknownDataSource(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	query := &Query{Id: "Users", SQL: "SELECT username FROM users", DataSource: "missing"}
+
+	_, err := NewQueryExecutor(testLogger).Execute(context.Background(), db, query, nil)
+	if !errors.Is(err, ErrUnknownDataSource) {
+		t.Errorf("Expected ErrUnknownDataSource without data sources, got %v", err)
+	}
+
+	opts := ExecutorOptions{DataSources: testDataSources{}}
+	_, err = NewQueryExecutorWithOptions(testLogger, opts).Execute(context.Background(), db, query, nil)
+	if !errors.Is(err, ErrUnknownDataSource) {
+		t.Errorf("Expected ErrUnknownDataSource, got %v", err)
+	}
+}
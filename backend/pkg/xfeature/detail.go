@@ -0,0 +1,176 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrNoParentRow is returned when the query of a master DataTable finds no row
+var ErrNoParentRow = errors.New("no parent row")
+
+// ErrAmbiguousParentRow is returned when the query of a master DataTable finds more than
+// one row, so its parameters do not select a single parent row
+var ErrAmbiguousParentRow = errors.New("more than one parent row")
+
+// errParentFound stops reading the master query after the row following the parent row
+var errParentFound = errors.New("parent row found")
+
+// Bind maps a column of the parent row of a detail DataTable to a parameter of its query
+type Bind struct {
+	Column string `xml:"Column,attr" json:"column"`
+	Param  string `xml:"Param,attr" json:"param"`
+}
+
+// DetailRows are the rows a detail DataTable shows for a parent row
+type DetailRows struct {
+	Table     string           `json:"table"`
+	QueryRef  string           `json:"queryRef"`
+	Rows      []map[string]any `json:"rows"`
+	Truncated bool             `json:"truncated"`
+}
+
+// MasterDetail is a parent row of a DataTable with the rows of its detail DataTables
+type MasterDetail struct {
+	Row     map[string]any `json:"row"`
+	Details []*DetailRows  `json:"details"`
+}
+
+// DetailTables returns the DataTables declared DetailOf the given table
+func (xf *XFeature) DetailTables(tableID string) []*DataTable {
+	var details []*DataTable
+	for _, table := range xf.Frontend.DataTables {
+		if table.DetailOf == tableID {
+			details = append(details, table)
+		}
+	}
+	return details
+}
+
+// DetailParams returns the parameters of the query of a detail table for a parent row:
+// the request parameters, the parent columns named by the Bind elements and, for
+// parameters that are still missing, the parent column of the same name
+func (dt *DataTable) DetailParams(query *Query, row map[string]any, params map[string]any) map[string]any {
+	detailParams := maps.Clone(params)
+	if detailParams == nil {
+		detailParams = make(map[string]any)
+	}
+	for _, bind := range dt.Binds {
+		detailParams[bind.Param] = row[bind.Column]
+	}
+	for _, param := range query.Parameters {
+		if _, ok := detailParams[param]; ok {
+			continue
+		}
+		if value, ok := row[param]; ok {
+			detailParams[param] = value
+		}
+	}
+	return detailParams
+}
+
+// ExecuteMasterDetail runs the query of a master DataTable, whose parameters must select
+// a single parent row such as WHERE invoice_id = :invoice_id, and returns that row
+// together with the rows its detail DataTables show for it. A query finding no row
+// returns ErrNoParentRow and one finding several ErrAmbiguousParentRow; at most two
+// rows are read. Only one level of details is read.
+func (qe *QueryExecutor) ExecuteMasterDetail(
+	ctx context.Context,
+	db *sqlx.DB,
+	xf *XFeature,
+	table *DataTable,
+	params map[string]any,
+) (*MasterDetail, error) {
+	row, err := qe.parentRow(ctx, db, xf, table, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MasterDetail{Row: row, Details: []*DetailRows{}}
+	for _, detail := range xf.DetailTables(table.Id) {
+		query, err := xf.GetQuery(detail.QueryRef)
+		if err != nil {
+			return nil, err
+		}
+		detailRows, truncated, err := qe.executeTable(ctx, db, xf, detail, detail.DetailParams(query, result.Row, params))
+		if err != nil {
+			return nil, fmt.Errorf("detail table %s: %w", detail.Id, err)
+		}
+		result.Details = append(result.Details, &DetailRows{
+			Table:     detail.Id,
+			QueryRef:  detail.QueryRef,
+			Rows:      detailRows,
+			Truncated: truncated,
+		})
+	}
+	return result, nil
+}
+
+// parentRow reads the single row the query of a master table selects. Rows are streamed
+// so a list query is not loaded just to be rejected; procedures are executed.
+func (qe *QueryExecutor) parentRow(
+	ctx context.Context,
+	db *sqlx.DB,
+	xf *XFeature,
+	table *DataTable,
+	params map[string]any,
+) (map[string]any, error) {
+	query, err := xf.GetQuery(table.QueryRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]any
+	if query.IsProcedure() {
+		if rows, _, err = qe.executeTable(ctx, db, xf, table, params); err != nil {
+			return nil, err
+		}
+	} else {
+		_, err = qe.StreamResultSet(ctx, db, query, table.ResultSet, params, func(row map[string]any) error {
+			rows = append(rows, row)
+			if len(rows) > 1 {
+				return errParentFound
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errParentFound) {
+			return nil, err
+		}
+	}
+
+	switch len(rows) {
+	case 0:
+		return nil, fmt.Errorf("%w: table %s", ErrNoParentRow, table.Id)
+	case 1:
+		return rows[0], nil
+	default:
+		return nil, fmt.Errorf("%w: the query of table %s must select a single row by its parameters", ErrAmbiguousParentRow, table.Id)
+	}
+}
+
+// executeTable runs the query of a DataTable and returns the rows of the result set it shows
+func (qe *QueryExecutor) executeTable(
+	ctx context.Context,
+	db *sqlx.DB,
+	xf *XFeature,
+	table *DataTable,
+	params map[string]any,
+) ([]map[string]any, bool, error) {
+	query, err := xf.GetQuery(table.QueryRef)
+	if err != nil {
+		return nil, false, err
+	}
+	rows, err := qe.Execute(ctx, db, query, params)
+	if err != nil {
+		return nil, false, err
+	}
+	if table.ResultSet != "" {
+		if rows, err = qe.ResultSet(table.ResultSet); err != nil {
+			return nil, false, err
+		}
+	}
+	return rows, qe.LastTruncated, nil
+}
@@ -0,0 +1,72 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const masterDetailFeature = `<Feature Name="invoices" Version="1">
+	<Backend>
+		<Query Id="GetInvoice" Type="Select">SELECT invoice_no, customer FROM invoices WHERE invoice_no = :invoice_no</Query>
+		<Query Id="ListLines" Type="Select">SELECT item, qty FROM invoice_lines WHERE belgeno = :invoiceNo ORDER BY item</Query>
+		<Query Id="ListPayments" Type="Select">SELECT amount FROM payments WHERE customer = :customer</Query>
+	</Backend>
+	<Frontend>
+		<DataTable Id="InvoiceTable" QueryRef="GetInvoice" Title="Invoices"/>
+		<DataTable Id="LinesTable" QueryRef="ListLines" Title="Lines" DetailOf="InvoiceTable">
+			<Bind Column="invoice_no" Param="invoiceNo"/>
+		</DataTable>
+		<DataTable Id="PaymentsTable" QueryRef="ListPayments" Title="Payments" DetailOf="InvoiceTable"/>
+	</Frontend>
+</Feature>`
+
+// TestExecuteMasterDetail tests that detail tables are read with the parameters bound from the parent row
+func TestExecuteMasterDetail(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`
+		CREATE TABLE invoices (invoice_no TEXT, customer TEXT);
+		CREATE TABLE invoice_lines (belgeno TEXT, item TEXT, qty INTEGER);
+		CREATE TABLE payments (customer TEXT, amount INTEGER);
+		INSERT INTO invoices VALUES ('INV1', 'acme'), ('INV2', 'globex');
+		INSERT INTO invoice_lines VALUES ('INV1', 'bolt', 10), ('INV1', 'nut', 20), ('INV2', 'gear', 1);
+		INSERT INTO payments VALUES ('acme', 100), ('globex', 50)`); err != nil {
+		t.Fatalf("Failed to prepare test data: %v", err)
+	}
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(masterDetailFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	table, _ := xf.GetDataTable("InvoiceTable")
+	executor := NewQueryExecutor(testLogger)
+
+	result, err := executor.ExecuteMasterDetail(context.Background(), db, xf, table, map[string]any{"invoice_no": "INV1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Row["customer"] != "acme" || len(result.Details) != 2 {
+		t.Fatalf("Unexpected master row %v with %d details", result.Row, len(result.Details))
+	}
+	lines, payments := result.Details[0], result.Details[1]
+	if lines.Table != "LinesTable" || len(lines.Rows) != 2 || lines.Rows[1]["item"] != "nut" {
+		t.Errorf("Expected the lines of INV1 through the Bind, got %+v", lines)
+	}
+	if payments.Table != "PaymentsTable" || len(payments.Rows) != 1 || payments.Rows[0]["amount"] != int64(100) {
+		t.Errorf("Expected the payments of acme through the customer column, got %+v", payments)
+	}
+
+	_, err = executor.ExecuteMasterDetail(context.Background(), db, xf, table, map[string]any{"invoice_no": "INV9"})
+	if !errors.Is(err, ErrNoParentRow) {
+		t.Errorf("Expected ErrNoParentRow, got %v", err)
+	}
+
+	// A list query does not select a parent row
+	xf.Backend.Queries = append(xf.Backend.Queries, &Query{Id: "ListInvoices", SQL: "SELECT invoice_no, customer FROM invoices"})
+	list := &DataTable{Id: "InvoiceListTable", QueryRef: "ListInvoices"}
+	if _, err := executor.ExecuteMasterDetail(context.Background(), db, xf, list, nil); !errors.Is(err, ErrAmbiguousParentRow) {
+		t.Errorf("Expected ErrAmbiguousParentRow, got %v", err)
+	}
+}
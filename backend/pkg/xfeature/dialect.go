@@ -0,0 +1,304 @@
+package xfeature
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Dialect covers the SQL differences between database drivers: placeholder style,
+// identifier quoting, LIKE matching, paging syntax and how the id generated by
+// an INSERT is obtained. Statements in feature definitions are always written with
+// :param placeholders and are rewritten by the dialect of the connection.
+type Dialect interface {
+	// Name returns the database/sql driver name of the dialect
+	Name() string
+	// Bind rewrites the :param placeholders of a statement and returns the arguments
+	// in the form the driver expects
+	Bind(sqlStr string, params map[string]any) (string, []any)
+	// QuoteIdentifier quotes a column name
+	QuoteIdentifier(name string) string
+	// EscapeLike escapes LIKE wildcards so the value is matched literally
+	EscapeLike(value string) string
+	// Like returns a condition matching column against the LIKE pattern in param,
+	// escaped with EscapeLike and matched case-insensitively
+	Like(column, param string) string
+	// DerivedTable prepares a statement to be wrapped as a derived table
+	DerivedTable(sqlStr string) string
+	// Paging returns the ORDER BY and paging clauses selecting :xf_limit rows after
+	// :xf_offset rows; orderBy is the ORDER BY clause of the request
+	Paging(orderBy string) string
+	// InsertIDSQL returns a statement running an INSERT and returning one row with the
+	// rows_affected and last_insert_id columns. ok is false if the driver reports the
+	// generated id through sql.Result instead.
+	InsertIDSQL(sqlStr string) (query string, ok bool)
+}
+
+var dialects = map[string]Dialect{}
+
+// RegisterDialect makes a dialect available for the given driver names
+func RegisterDialect(dialect Dialect, driverNames ...string) {
+	dialects[dialect.Name()] = dialect
+	for _, name := range driverNames {
+		dialects[name] = dialect
+	}
+}
+
+func init() {
+	RegisterDialect(SQLServerDialect{}, "mssql")
+	RegisterDialect(SQLiteDialect{}, "sqlite")
+	RegisterDialect(PostgresDialect{}, "pgx")
+	RegisterDialect(MySQLDialect{})
+}
+
+// DialectFor returns the dialect of a driver. Unknown drivers get a generic dialect
+// that keeps :param placeholders and passes arguments by position.
+func DialectFor(driverName string) Dialect {
+	if dialect, ok := dialects[driverName]; ok {
+		return dialect
+	}
+	return genericDialect{name: driverName}
+}
+
+// LookupDialect returns the dialect of a driver, or an error if none is registered
+func LookupDialect(driverName string) (Dialect, error) {
+	dialect, ok := dialects[driverName]
+	if !ok {
+		return nil, fmt.Errorf("no SQL dialect for driver %q", driverName)
+	}
+	return dialect, nil
+}
+
+// ExecInsert runs an INSERT statement bound by dialect and returns a result that
+// reports the generated id, using InsertIDSQL where the driver needs it
+func ExecInsert(ctx context.Context, db sqlx.ExtContext, dialect Dialect, sqlStr string, args ...any) (sql.Result, error) {
+	query, ok := dialect.InsertIDSQL(sqlStr)
+	if !ok {
+		return db.ExecContext(ctx, sqlStr, args...)
+	}
+	var rowsAffected int64
+	var lastInsertID sql.NullInt64
+	if err := db.QueryRowxContext(ctx, query, args...).Scan(&rowsAffected, &lastInsertID); err != nil {
+		return nil, err
+	}
+	if !lastInsertID.Valid {
+		lastInsertID.Int64 = -1
+	}
+	return insertResult{rowsAffected: rowsAffected, lastInsertID: lastInsertID.Int64}, nil
+}
+
+// insertResult is the sql.Result of a statement whose counts are read from rows, like
+// an INSERT run through InsertIDSQL
+type insertResult struct {
+	rowsAffected int64
+	lastInsertID int64
+}
+
+func (r insertResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r insertResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+// bindNamed keeps one named argument per distinct parameter, for drivers that bind by name
+func bindNamed(sqlStr string, params map[string]any) []any {
+	var args []any
+	for _, name := range ExtractParameters(sqlStr) {
+		if val, ok := params[name]; ok {
+			args = append(args, sql.Named(name, val))
+		}
+	}
+	return args
+}
+
+// likeEscaper escapes LIKE wildcards with \ as escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// limitOffset is the paging clause of dialects supporting LIMIT ... OFFSET
+func limitOffset(orderBy string) string {
+	return orderBy + "\nLIMIT :xf_limit OFFSET :xf_offset"
+}
+
+// SQLServerDialect is the dialect of Microsoft SQL Server (driver sqlserver)
+type SQLServerDialect struct{}
+
+func (SQLServerDialect) Name() string { return "sqlserver" }
+
+// Bind converts :param to @param; the driver binds by name, so every distinct
+// parameter is passed once regardless of how often it appears
+func (SQLServerDialect) Bind(sqlStr string, params map[string]any) (string, []any) {
+	args := bindNamed(sqlStr, params)
+	return replaceParameters(sqlStr, func(match string) string {
+		return "@" + strings.TrimPrefix(match, ":")
+	}), args
+}
+
+func (SQLServerDialect) QuoteIdentifier(name string) string {
+	return "[" + strings.ReplaceAll(name, "]", "]]") + "]"
+}
+
+// EscapeLike also escapes [, which starts a character class in SQL Server patterns
+func (SQLServerDialect) EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `[`, `\[`).Replace(value)
+}
+
+func (SQLServerDialect) Like(column, param string) string {
+	return column + " LIKE :" + param + " ESCAPE '\\'"
+}
+
+// DerivedTable appends OFFSET 0 ROWS to an ordered statement, as SQL Server only
+// accepts ORDER BY in a derived table together with OFFSET or TOP
+func (SQLServerDialect) DerivedTable(sqlStr string) string {
+	if hasTopLevelKeyword(sqlStr, "ORDER") && !hasTopLevelKeyword(sqlStr, "OFFSET") && !hasTopLevelKeyword(sqlStr, "TOP") {
+		return sqlStr + " OFFSET 0 ROWS"
+	}
+	return sqlStr
+}
+
+func (SQLServerDialect) Paging(orderBy string) string {
+	return orderBy + "\nOFFSET :xf_offset ROWS FETCH NEXT :xf_limit ROWS ONLY"
+}
+
+// InsertIDSQL reads SCOPE_IDENTITY in the same batch, as the driver does not support
+// LastInsertId. Statements with an OUTPUT clause already return rows and are left alone.
+func (SQLServerDialect) InsertIDSQL(sqlStr string) (string, bool) {
+	if hasTopLevelKeyword(sqlStr, "OUTPUT") {
+		return "", false
+	}
+	return strings.TrimRight(strings.TrimSpace(sqlStr), "; \t\r\n") +
+		";\nSELECT CAST(@@ROWCOUNT AS bigint) AS rows_affected, CAST(SCOPE_IDENTITY() AS bigint) AS last_insert_id", true
+}
+
+// SQLiteDialect is the dialect of SQLite (driver sqlite3)
+type SQLiteDialect struct{}
+
+func (SQLiteDialect) Name() string { return "sqlite3" }
+
+// Bind keeps :param, which the driver binds by name
+func (SQLiteDialect) Bind(sqlStr string, params map[string]any) (string, []any) {
+	return sqlStr, bindNamed(sqlStr, params)
+}
+
+func (SQLiteDialect) QuoteIdentifier(name string) string { return quoteANSI(name) }
+
+func (SQLiteDialect) EscapeLike(value string) string { return likeEscaper.Replace(value) }
+
+func (SQLiteDialect) Like(column, param string) string {
+	return column + " LIKE :" + param + " ESCAPE '\\'"
+}
+
+func (SQLiteDialect) DerivedTable(sqlStr string) string { return sqlStr }
+
+func (SQLiteDialect) Paging(orderBy string) string { return limitOffset(orderBy) }
+
+func (SQLiteDialect) InsertIDSQL(string) (string, bool) { return "", false }
+
+// PostgresDialect is the dialect of PostgreSQL (drivers postgres and pgx)
+type PostgresDialect struct{}
+
+func (PostgresDialect) Name() string { return "postgres" }
+
+// Bind converts :param to $n, numbering distinct parameters in order of first appearance
+func (PostgresDialect) Bind(sqlStr string, params map[string]any) (string, []any) {
+	positions := make(map[string]int)
+	var args []any
+	sqlStr = replaceParameters(sqlStr, func(match string) string {
+		name := strings.TrimPrefix(match, ":")
+		pos, ok := positions[name]
+		if !ok {
+			args = append(args, params[name])
+			pos = len(args)
+			positions[name] = pos
+		}
+		return fmt.Sprintf("$%d", pos)
+	})
+	return sqlStr, args
+}
+
+func (PostgresDialect) QuoteIdentifier(name string) string { return quoteANSI(name) }
+
+func (PostgresDialect) EscapeLike(value string) string { return likeEscaper.Replace(value) }
+
+// Like casts the column to text, as PostgreSQL has no implicit casts for LIKE, and
+// uses ILIKE to match case-insensitively like the other dialects
+func (PostgresDialect) Like(column, param string) string {
+	return "CAST(" + column + " AS text) ILIKE :" + param + " ESCAPE '\\'"
+}
+
+func (PostgresDialect) DerivedTable(sqlStr string) string { return sqlStr }
+
+func (PostgresDialect) Paging(orderBy string) string { return limitOffset(orderBy) }
+
+// InsertIDSQL is not used: PostgreSQL has no generic way to return a generated id
+// without naming the column, so statements declare RETURNING themselves
+func (PostgresDialect) InsertIDSQL(string) (string, bool) { return "", false }
+
+// MySQLDialect is the dialect of MySQL and MariaDB (driver mysql)
+type MySQLDialect struct{}
+
+func (MySQLDialect) Name() string { return "mysql" }
+
+// Bind converts every :param to ?, passing one argument per occurrence
+func (MySQLDialect) Bind(sqlStr string, params map[string]any) (string, []any) {
+	var args []any
+	sqlStr = replaceParameters(sqlStr, func(match string) string {
+		args = append(args, params[strings.TrimPrefix(match, ":")])
+		return "?"
+	})
+	return sqlStr, args
+}
+
+func (MySQLDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (MySQLDialect) EscapeLike(value string) string { return likeEscaper.Replace(value) }
+
+// Like relies on \ being the default LIKE escape character of MySQL, which avoids
+// writing a backslash into a string literal
+func (MySQLDialect) Like(column, param string) string {
+	return column + " LIKE :" + param
+}
+
+func (MySQLDialect) DerivedTable(sqlStr string) string { return sqlStr }
+
+func (MySQLDialect) Paging(orderBy string) string { return limitOffset(orderBy) }
+
+func (MySQLDialect) InsertIDSQL(string) (string, bool) { return "", false }
+
+// genericDialect keeps :param placeholders and passes arguments by position in
+// order of first appearance
+type genericDialect struct {
+	name string
+}
+
+func (d genericDialect) Name() string { return d.name }
+
+func (genericDialect) Bind(sqlStr string, params map[string]any) (string, []any) {
+	var args []any
+	for _, name := range ExtractParameters(sqlStr) {
+		if val, ok := params[name]; ok {
+			args = append(args, val)
+		}
+	}
+	return sqlStr, args
+}
+
+func (genericDialect) QuoteIdentifier(name string) string { return quoteANSI(name) }
+
+func (genericDialect) EscapeLike(value string) string { return likeEscaper.Replace(value) }
+
+func (genericDialect) Like(column, param string) string {
+	return column + " LIKE :" + param + " ESCAPE '\\'"
+}
+
+func (genericDialect) DerivedTable(sqlStr string) string { return sqlStr }
+
+func (genericDialect) Paging(orderBy string) string { return limitOffset(orderBy) }
+
+func (genericDialect) InsertIDSQL(string) (string, bool) { return "", false }
+
+// quoteANSI quotes an identifier with double quotes
+func quoteANSI(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
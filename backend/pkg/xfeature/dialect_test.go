@@ -0,0 +1,155 @@
+package xfeature
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestDialectBind tests the placeholders and arguments of each dialect
+func TestDialectBind(t *testing.T) {
+	params := map[string]any{"a": 1, "b": "two"}
+	tests := []struct {
+		driver       string
+		expectedSQL  string
+		expectedArgs int
+	}{
+		{driver: "sqlserver", expectedSQL: "SELECT @b, @a, @b", expectedArgs: 2},
+		{driver: "sqlite3", expectedSQL: "SELECT :b, :a, :b", expectedArgs: 2},
+		{driver: "postgres", expectedSQL: "SELECT $1, $2, $1", expectedArgs: 2},
+		{driver: "mysql", expectedSQL: "SELECT ?, ?, ?", expectedArgs: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			sqlStr, args, err := BindParameters("SELECT :b, :a, :b", params, tt.driver, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if sqlStr != tt.expectedSQL || len(args) != tt.expectedArgs {
+				t.Errorf("Expected %s with %d args, got %s with %v", tt.expectedSQL, tt.expectedArgs, sqlStr, args)
+			}
+		})
+	}
+
+	_, args, _ := BindParameters("SELECT :b, :a, :b", params, "postgres", 0)
+	if !reflect.DeepEqual(args, []any{"two", 1}) {
+		t.Errorf("Expected postgres args [two 1], got %v", args)
+	}
+	_, args, _ = BindParameters("SELECT :b, :a, :b", params, "mysql", 0)
+	if !reflect.DeepEqual(args, []any{"two", 1, "two"}) {
+		t.Errorf("Expected mysql args [two 1 two], got %v", args)
+	}
+}
+
+// TestDialectBindCast tests that PostgreSQL :: casts are not taken for parameters
+func TestDialectBindCast(t *testing.T) {
+	sqlStr := "SELECT amount::numeric FROM invoices WHERE id = :id AND total > :min::numeric"
+	if params := ExtractParameters(sqlStr); !reflect.DeepEqual(params, []string{"id", "min"}) {
+		t.Errorf("Expected parameters [id min], got %v", params)
+	}
+
+	bound, args, err := BindParameters(sqlStr, map[string]any{"id": 7, "min": "10.5"}, "postgres", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "SELECT amount::numeric FROM invoices WHERE id = $1 AND total > $2::numeric"
+	if bound != expected || !reflect.DeepEqual(args, []any{7, "10.5"}) {
+		t.Errorf("Expected %s with [7 10.5], got %s with %v", expected, bound, args)
+	}
+}
+
+// TestDialectLookup tests driver aliases and unknown drivers
+func TestDialectLookup(t *testing.T) {
+	for driver, expected := range map[string]string{"mssql": "sqlserver", "sqlite": "sqlite3", "pgx": "postgres", "mysql": "mysql"} {
+		if name := DialectFor(driver).Name(); name != expected {
+			t.Errorf("Expected dialect %s for driver %s, got %s", expected, driver, name)
+		}
+	}
+
+	if _, err := LookupDialect("odbc"); err == nil {
+		t.Errorf("Expected an error for a driver without dialect")
+	}
+	if DialectFor("odbc").QuoteIdentifier(`a"b`) != `"a""b"` {
+		t.Errorf("Expected the generic dialect to quote with double quotes")
+	}
+}
+
+// TestBuildPagedSQLDialects tests the quoting, matching and paging of the other dialects
+func TestBuildPagedSQLDialects(t *testing.T) {
+	tests := []struct {
+		driver   string
+		expected string
+	}{
+		{
+			driver: "postgres",
+			expected: "SELECT * FROM (\nSELECT * FROM users ORDER BY created_at\n) AS xf_page" +
+				"\nWHERE CAST(\"email\" AS text) ILIKE :xf_filter_1 ESCAPE '\\'" +
+				"\nORDER BY \"username\"\nLIMIT :xf_limit OFFSET :xf_offset",
+		},
+		{
+			driver: "mysql",
+			expected: "SELECT * FROM (\nSELECT * FROM users ORDER BY created_at\n) AS xf_page" +
+				"\nWHERE `email` LIKE :xf_filter_1" +
+				"\nORDER BY `username`\nLIMIT :xf_limit OFFSET :xf_offset",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			page := &PageRequest{
+				Page:    1,
+				Sort:    []SortField{{Column: "username"}},
+				Filters: []Filter{{Column: "email", Operator: FilterContains, Value: "a_b"}},
+			}
+			paged, err := BuildPagedSQL("SELECT * FROM users ORDER BY created_at", pagingTestTable(), page, tt.driver)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if paged.DataSQL != tt.expected {
+				t.Errorf("Expected data SQL:\n%s\nGot:\n%s", tt.expected, paged.DataSQL)
+			}
+			if paged.Params["xf_filter_1"] != `%a\_b%` {
+				t.Errorf("Expected escaped pattern, got %v", paged.Params["xf_filter_1"])
+			}
+		})
+	}
+}
+
+// TestInsertIDSQL tests how SQL Server inserts report their generated id
+func TestInsertIDSQL(t *testing.T) {
+	query, ok := SQLServerDialect{}.InsertIDSQL("INSERT INTO users (username) VALUES (@username);")
+	if !ok || !strings.HasPrefix(query, "INSERT INTO users (username) VALUES (@username);\nSELECT") ||
+		!strings.Contains(query, "SCOPE_IDENTITY()") {
+		t.Errorf("Unexpected insert id statement: %s", query)
+	}
+
+	if _, ok := (SQLServerDialect{}).InsertIDSQL("INSERT INTO users (username) OUTPUT INSERTED.user_id VALUES (@username)"); ok {
+		t.Errorf("Expected statements with OUTPUT to be left alone")
+	}
+	if _, ok := (SQLiteDialect{}).InsertIDSQL("INSERT INTO users (username) VALUES (:username)"); ok {
+		t.Errorf("Expected SQLite to report the id through sql.Result")
+	}
+}
+
+// TestInsertActionLastInsertID tests that Insert actions report the generated id
+func TestInsertActionLastInsertID(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	action := &ActionQuery{Id: "CreateUser", Type: "Insert", SQL: "INSERT INTO users (username, email) VALUES (:username, :email)"}
+	executor := NewActionExecutor(testLogger)
+	ctx := context.Background()
+
+	for i, name := range []string{"first", "second"} {
+		result, err := executor.Execute(ctx, db, action, map[string]any{"username": name, "email": name + "@example.com"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil || id != int64(i+1) {
+			t.Errorf("Expected id %d, got %d (error %v)", i+1, id, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package xfeature
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DryRun runs an action in a transaction that is always rolled back and returns what
+// it would change: the rows affected and, where the dialect can return them, the
+// affected rows as OUTPUT or RETURNING reads them, up to LastMaxRows. Procedure actions
+// leave their result in LastProcedure. Cached results are not invalidated and mock data
+// sets are returned as they are.
+func (ae *ActionExecutor) DryRun(
+	ctx context.Context,
+	db *sqlx.DB,
+	action *ActionQuery,
+	params map[string]any,
+) (sql.Result, []map[string]any, error) {
+	startTime := time.Now()
+	ae.LastInvalidated = nil
+	ae.LastProcedure = nil
+	ae.LastTruncated = false
+	ae.LastMaxRows = parseMaxRows(action.MaxRows, ae.maxRows)
+
+	if action.MockDataSet != "" {
+		if mockResult, err := ae.loadMockDataSet(action.MockDataSet); err == nil {
+			return mockResult, mockResult.rows, nil
+		} else if !os.IsNotExist(err) {
+			ae.logger.Warn("Mock data set error, falling back to database action",
+				"actionId", action.Id,
+				"mockDataSet", action.MockDataSet,
+				"error", err,
+			)
+		}
+	}
+
+	params, err := checkConcurrencyToken(action, params)
+	if err != nil {
+		ae.logger.Warn("Concurrency token missing", "actionId", action.Id, "column", action.Concurrency)
+		return nil, nil, err
+	}
+	if !action.IsProcedure() {
+		if err := ae.validateParameters(ExtractParameters(action.SQL), params); err != nil {
+			ae.logger.Error("Parameter validation failed", "actionId", action.Id, "error", err)
+			return nil, nil, err
+		}
+	}
+
+	db, err = resolveDataSource(ae.dataSources, db, action.DataSource)
+	if err != nil {
+		ae.logger.Error("Data source not available", "actionId", action.Id, "dataSource", action.DataSource, "error", err)
+		return nil, nil, err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction of dry run %s: %w", action.Id, err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			ae.logger.Error("Rollback of dry run failed", "actionId", action.Id, "error", err)
+		}
+	}()
+
+	var result sql.Result
+	var rows []map[string]any
+	switch {
+	case action.IsProcedure():
+		ae.LastProcedure, err = ae.callProcedure(ctx, tx, action, params)
+		result = &MockResult{rowsAffected: -1, lastInsertId: -1}
+	case canReturn(tx.DriverName(), action):
+		rows, err = ae.queryReturning(ctx, tx, action, params)
+		rowsAffected := int64(len(rows))
+		if ae.LastTruncated {
+			rowsAffected = -1
+		}
+		result = insertResult{rowsAffected: rowsAffected, lastInsertID: -1}
+	default:
+		result, err = ae.exec(ctx, tx, action, params)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ae.logger.Info("Dry run rolled back",
+		"actionId", action.Id,
+		"actionType", action.Type,
+		"rowCount", len(rows),
+		"duration_ms", time.Since(startTime).Milliseconds(),
+	)
+	return result, rows, nil
+}
+
+// canReturn reports whether the dialect of driverName can return the rows affected by
+// the statement of an action
+func canReturn(driverName string, action *ActionQuery) bool {
+	dialect, ok := DialectFor(driverName).(ReturningDialect)
+	if !ok {
+		return false
+	}
+	_, err := dialect.ReturningSQL(action.SQL, action.Type)
+	return err == nil
+}
@@ -0,0 +1,40 @@
+package xfeature
+
+import (
+	"context"
+	"testing"
+)
+
+// TestDryRun tests that a dry run previews the affected rows and leaves the table unchanged
+func TestDryRun(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`INSERT INTO users (username, email, role) VALUES ('ada', 'ada@example.com', 'user'), ('bob', 'bob@example.com', 'user')`); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	executor := NewActionExecutor(testLogger)
+	update := &ActionQuery{Id: "PromoteUsers", Type: "Update", Invalidates: "ListUsers",
+		SQL: "UPDATE users SET role = :role WHERE username <> :except"}
+	result, rows, err := executor.DryRun(context.Background(), db, update, map[string]any{"role": "admin", "except": "bob"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected != 1 || len(rows) != 1 || rows[0]["username"] != "ada" || rows[0]["role"] != "admin" {
+		t.Errorf("Expected a preview of the updated row, got %v (rows affected %d)", rows, rowsAffected)
+	}
+	if len(executor.LastInvalidated) != 0 {
+		t.Errorf("Expected no cached results to be dropped, got %v", executor.LastInvalidated)
+	}
+
+	var admins int
+	if err := db.Get(&admins, "SELECT COUNT(*) FROM users WHERE role = 'admin'"); err != nil || admins != 0 {
+		t.Errorf("Expected the update to be rolled back, found %d admins (error %v)", admins, err)
+	}
+
+	if _, _, err := executor.DryRun(context.Background(), db, update, map[string]any{"role": "admin"}); err == nil {
+		t.Errorf("Expected missing parameters to be rejected")
+	}
+}
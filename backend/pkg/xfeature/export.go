@@ -0,0 +1,260 @@
+package xfeature
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Export formats supported by NewExportWriter
+const (
+	ExportCSV  = "csv"
+	ExportXLSX = "xlsx"
+)
+
+// utf8BOM lets spreadsheet applications detect UTF-8 in CSV files
+const utf8BOM = "\uFEFF"
+
+// ExportColumn describes a column of an exported file
+type ExportColumn struct {
+	Name   string
+	Header string
+	// Type and Format come from the DataTable Column, if any
+	Type   string
+	Format string
+}
+
+// ExportOptions configures an export writer
+type ExportOptions struct {
+	// BOM prefixes CSV output with a UTF-8 byte order mark
+	BOM bool
+	// SheetName names the XLSX worksheet
+	SheetName string
+}
+
+// ExportWriter writes query rows to a file format one row at a time
+type ExportWriter interface {
+	WriteHeader(columns []ExportColumn) error
+	WriteRow(row map[string]any) error
+	// Close flushes buffered output; it does not close the underlying writer
+	Close() error
+}
+
+// NewExportWriter creates a writer for the given export format
+func NewExportWriter(format string, w io.Writer, opts ExportOptions) (ExportWriter, error) {
+	switch format {
+	case ExportCSV:
+		return &csvExportWriter{w: w, csv: csv.NewWriter(w), bom: opts.BOM}, nil
+	case ExportXLSX:
+		return newXLSXExportWriter(w, opts.SheetName), nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q (expected %s or %s)", format, ExportCSV, ExportXLSX)
+	}
+}
+
+// ExportContentType returns the MIME type of an export format
+func ExportContentType(format string) string {
+	switch format {
+	case ExportXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// ExportColumns determines the columns of an export in the same order and with the
+// same headers as the gridColDefs of ExecuteQuery: the columns of the DataTable that
+// references the query come first, or the Mappings when there is no DataTable,
+// followed by any remaining result columns labelled by their Mapping if one exists.
+func (xf *XFeature) ExportColumns(queryId string, resultColumns []string) []ExportColumn {
+	present := make(map[string]bool, len(resultColumns))
+	for _, name := range resultColumns {
+		present[name] = true
+	}
+	mappings := make(map[string]*Mapping, len(xf.Mappings))
+	for _, mapping := range xf.Mappings {
+		mappings[mapping.Name] = mapping
+	}
+
+	var columns []ExportColumn
+	done := make(map[string]bool)
+	add := func(col ExportColumn) {
+		if col.Header == "" {
+			col.Header = col.Name
+		}
+		columns = append(columns, col)
+		done[col.Name] = true
+	}
+
+	if table := xf.GetDataTableForQuery(queryId); table != nil && len(table.Columns) > 0 {
+		for _, col := range table.Columns {
+			if present[col.Name] && !done[col.Name] {
+				add(ExportColumn{Name: col.Name, Header: col.Label, Type: col.Type, Format: col.Format})
+			}
+		}
+	} else {
+		for _, mapping := range xf.Mappings {
+			if present[mapping.Name] && !done[mapping.Name] {
+				add(ExportColumn{Name: mapping.Name, Header: mapping.Label})
+			}
+		}
+	}
+
+	for _, name := range resultColumns {
+		if done[name] {
+			continue
+		}
+		col := ExportColumn{Name: name}
+		if mapping, ok := mappings[name]; ok {
+			col.Header = mapping.Label
+		}
+		add(col)
+	}
+
+	return columns
+}
+
+// sortedRowKeys returns the column names of a row in a stable order
+func sortedRowKeys(row map[string]any) []string {
+	keys := make([]string, 0, len(row))
+	for key := range row {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type csvExportWriter struct {
+	w       io.Writer
+	csv     *csv.Writer
+	bom     bool
+	columns []ExportColumn
+	record  []string
+}
+
+func (cw *csvExportWriter) WriteHeader(columns []ExportColumn) error {
+	cw.columns = columns
+	cw.record = make([]string, len(columns))
+	if cw.bom {
+		if _, err := io.WriteString(cw.w, utf8BOM); err != nil {
+			return err
+		}
+	}
+	for i, col := range columns {
+		cw.record[i] = col.Header
+	}
+	return cw.csv.Write(cw.record)
+}
+
+func (cw *csvExportWriter) WriteRow(row map[string]any) error {
+	for i, col := range cw.columns {
+		cw.record[i] = formatCSVValue(row[col.Name], col)
+	}
+	return cw.csv.Write(cw.record)
+}
+
+func (cw *csvExportWriter) Close() error {
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
+
+// formatCSVValue renders a value as text, applying the column Format to dates and
+// escaping text that would be evaluated as a formula
+func formatCSVValue(value any, col ExportColumn) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		if col.Format != "" && (col.Type == "Date" || col.Type == "DateTime") {
+			if t, ok := parseExportTime(v); ok {
+				return t.Format(goDateLayout(col.Format))
+			}
+		}
+		return escapeFormula(v)
+	case []byte:
+		return escapeFormula(string(v))
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		if col.Format != "" {
+			return v.Format(goDateLayout(col.Format))
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return escapeFormula(fmt.Sprint(v))
+	}
+}
+
+// escapeFormula prefixes text that spreadsheet applications would evaluate as a formula,
+// starting with =, +, -, @, tab or carriage return, with a quote so it stays text.
+// Numbers such as -12.50 are left unchanged.
+func escapeFormula(text string) string {
+	if text == "" || !strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return text
+	}
+	if _, err := strconv.ParseFloat(text, 64); err == nil {
+		return text
+	}
+	return "'" + text
+}
+
+// parseExportTime parses date and time values as returned by the database or mock data sets
+func parseExportTime(value string) (time.Time, bool) {
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dateFormatTokens maps the date tokens used in Column Format attributes
+// (e.g. MM/DD/YYYY HH:mm) to Go layouts and Excel number formats, longest first
+var dateFormatTokens = []struct {
+	token string
+	goFmt string
+	excel string
+}{
+	{"YYYY", "2006", "yyyy"},
+	{"YY", "06", "yy"},
+	{"MM", "01", "mm"},
+	{"DD", "02", "dd"},
+	{"HH", "15", "hh"},
+	{"hh", "03", "hh"},
+	{"mm", "04", "mm"},
+	{"ss", "05", "ss"},
+	{"A", "PM", "AM/PM"},
+}
+
+// convertDateFormat rewrites a Column Format date pattern token by token
+func convertDateFormat(format string, pick func(goFmt, excel string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		matched := false
+		for _, t := range dateFormatTokens {
+			if strings.HasPrefix(format[i:], t.token) {
+				b.WriteString(pick(t.goFmt, t.excel))
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(format[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+func goDateLayout(format string) string {
+	return convertDateFormat(format, func(goFmt, _ string) string { return goFmt })
+}
+
+func excelDateFormat(format string) string {
+	return convertDateFormat(format, func(_, excel string) string { return excel })
+}
@@ -0,0 +1,252 @@
+package xfeature
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// exportTestFeature binds a DataTable with Persian labels to the ListUsers query
+func exportTestFeature() *XFeature {
+	xf := NewXFeature(testLogger)
+	xf.Mappings = []*Mapping{
+		{Name: "email", Label: "ایمیل"},
+		{Name: "status", Label: "وضعیت"},
+	}
+	xf.Frontend.DataTables = []*DataTable{{
+		Id:       "UsersTable",
+		QueryRef: "ListUsers",
+		Columns: []*Column{
+			{Name: "username", Label: "نام کاربری"},
+			{Name: "created_at", Label: "تاریخ", Type: "Date", Format: "DD/MM/YYYY"},
+			{Name: "missing", Label: "Missing"},
+		},
+	}}
+	return xf
+}
+
+// TestExportColumns tests that export columns follow the DataTable, then the remaining result columns
+func TestExportColumns(t *testing.T) {
+	xf := exportTestFeature()
+
+	columns := xf.ExportColumns("ListUsers", []string{"user_id", "email", "created_at", "username"})
+	expected := []ExportColumn{
+		{Name: "username", Header: "نام کاربری"},
+		{Name: "created_at", Header: "تاریخ", Type: "Date", Format: "DD/MM/YYYY"},
+		{Name: "user_id", Header: "user_id"},
+		{Name: "email", Header: "ایمیل"},
+	}
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, columns)
+	}
+
+	// Without a DataTable the Mappings define the order
+	columns = xf.ExportColumns("OtherQuery", []string{"user_id", "status", "email"})
+	expected = []ExportColumn{
+		{Name: "email", Header: "ایمیل"},
+		{Name: "status", Header: "وضعیت"},
+		{Name: "user_id", Header: "user_id"},
+	}
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, columns)
+	}
+}
+
+// TestCSVExport tests CSV output with a BOM, Persian headers and formatted dates
+func TestCSVExport(t *testing.T) {
+	columns := exportTestFeature().ExportColumns("ListUsers", []string{"username", "created_at", "user_id"})
+
+	var buf bytes.Buffer
+	writer, err := NewExportWriter(ExportCSV, &buf, ExportOptions{BOM: true})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := writer.WriteHeader(columns); err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	rows := []map[string]any{
+		{"username": "علی, رضا", "created_at": "2024-03-05T00:00:00Z", "user_id": int64(1)},
+		{"username": "bob", "created_at": nil, "user_id": int64(2)},
+	}
+	for _, row := range rows {
+		if err := writer.WriteRow(row); err != nil {
+			t.Fatalf("Failed to write row: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	expected := utf8BOM + "نام کاربری,تاریخ,user_id\n\"علی, رضا\",05/03/2024,1\nbob,,2\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expected, buf.String())
+	}
+
+	buf.Reset()
+	writer, _ = NewExportWriter(ExportCSV, &buf, ExportOptions{})
+	writer.WriteHeader(columns)
+	writer.Close()
+	if strings.HasPrefix(buf.String(), utf8BOM) {
+		t.Errorf("Expected no BOM, got %q", buf.String())
+	}
+
+	if _, err := NewExportWriter("pdf", &buf, ExportOptions{}); err == nil {
+		t.Errorf("Expected an error for an unsupported format")
+	}
+}
+
+// TestExportEscapesFormulas tests that text cells cannot inject spreadsheet formulas
+func TestExportEscapesFormulas(t *testing.T) {
+	tests := map[string]string{
+		"=HYPERLINK(\"http://x\")": "'=HYPERLINK(\"http://x\")",
+		"+1+cmd":                   "'+1+cmd",
+		"-2+3":                     "'-2+3",
+		"@SUM(A1)":                 "'@SUM(A1)",
+		"\tx":                      "'\tx",
+		"\r=1":                     "'\r=1",
+		"-12.50":                   "-12.50",
+		"a=b":                      "a=b",
+		"":                         "",
+	}
+	for value, expected := range tests {
+		if escaped := formatCSVValue(value, ExportColumn{}); escaped != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, value, escaped)
+		}
+	}
+
+	var buf bytes.Buffer
+	writer, _ := NewExportWriter(ExportXLSX, &buf, ExportOptions{})
+	writer.WriteHeader([]ExportColumn{{Name: "username", Header: "username"}})
+	writer.WriteRow(map[string]any{"username": "=1+1"})
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Export is not a zip archive: %v", err)
+	}
+	rc, err := archive.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatalf("Failed to open the worksheet: %v", err)
+	}
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	if !strings.Contains(string(content), "<t xml:space=\"preserve\">&#39;=1+1</t>") {
+		t.Errorf("Expected an escaped text cell, got %s", content)
+	}
+}
+
+// TestXLSXExport tests that streamed query rows produce a readable workbook
+func TestXLSXExport(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("INSERT INTO users (username, email, status) VALUES (?, ?, ?), (?, ?, ?)",
+		"علی", "ali@example.com", "active", "bob & co", "bob@example.com", "inactive")
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	xf := exportTestFeature()
+	query := &Query{Id: "ListUsers", SQL: "SELECT user_id, username, email FROM users ORDER BY user_id"}
+	executor := NewQueryExecutor(testLogger)
+
+	var buf bytes.Buffer
+	writer, err := NewExportWriter(ExportXLSX, &buf, ExportOptions{SheetName: "Users/Active"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	headerWritten := false
+	_, err = executor.Stream(context.Background(), db, query, nil, func(row map[string]any) error {
+		if !headerWritten {
+			if err := writer.WriteHeader(xf.ExportColumns(query.Id, executor.LastColumns)); err != nil {
+				return err
+			}
+			headerWritten = true
+		}
+		return writer.WriteRow(row)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Export is not a zip archive: %v", err)
+	}
+	parts := make(map[string]string)
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", file.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[file.Name] = string(content)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("Expected part %s in the workbook", name)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Users_Active"`) {
+		t.Errorf("Expected a sanitized sheet name, got %s", parts["xl/workbook.xml"])
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref    string `xml:"r,attr"`
+				Type   string `xml:"t,attr"`
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(parts["xl/worksheets/sheet1.xml"]), &sheet); err != nil {
+		t.Fatalf("Worksheet is not valid XML: %v", err)
+	}
+	if len(sheet.Rows) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d rows", len(sheet.Rows))
+	}
+
+	header := sheet.Rows[0].Cells
+	if len(header) != 3 || header[0].Inline != "نام کاربری" || header[1].Inline != "user_id" || header[2].Inline != "ایمیل" {
+		t.Errorf("Unexpected header cells: %+v", header)
+	}
+	last := sheet.Rows[2].Cells
+	if last[0].Ref != "A3" || last[0].Inline != "bob & co" {
+		t.Errorf("Expected escaped text in A3, got %+v", last[0])
+	}
+	if last[1].Type != "" || last[1].Value != "2" {
+		t.Errorf("Expected a numeric user_id cell, got %+v", last[1])
+	}
+}
+
+// TestXLSXHelpers tests column names, date serials and sheet names
+func TestXLSXHelpers(t *testing.T) {
+	for index, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := xlsxColumnName(index); got != name {
+			t.Errorf("Expected column %d to be %s, got %s", index, name, got)
+		}
+	}
+
+	if got, _ := parseExportTime("2024-01-01T12:00:00Z"); xlsxSerial(got) != "45292.5" {
+		t.Errorf("Expected serial 45292.5, got %s", xlsxSerial(got))
+	}
+
+	if got := xlsxSheetName(strings.Repeat("x", 40)); len(got) != 31 {
+		t.Errorf("Expected the sheet name to be limited to 31 characters, got %d", len(got))
+	}
+	if got := excelDateFormat("DD/MM/YYYY HH:mm"); got != "dd/mm/yyyy hh:mm" {
+		t.Errorf("Expected dd/mm/yyyy hh:mm, got %s", got)
+	}
+}
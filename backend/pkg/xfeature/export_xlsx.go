@@ -0,0 +1,295 @@
+package xfeature
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	xlsxMainNS = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxRelNS  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xlsxPkgNS  = "http://schemas.openxmlformats.org/package/2006/relationships"
+
+	// Cell style indexes in styles.xml; column formats are appended after these
+	xlsxStyleDefault = 0
+	xlsxStyleHeader  = 1
+
+	// Custom number formats start at this id in SpreadsheetML
+	xlsxFirstCustomNumFmt = 164
+)
+
+// Default number formats of typed columns without a Format attribute
+var xlsxDefaultFormats = map[string]string{
+	"Date":       "yyyy-mm-dd",
+	"DateTime":   "yyyy-mm-dd hh:mm:ss",
+	"Currency":   "#,##0.00",
+	"Percentage": "0.00%",
+}
+
+// xlsxEpoch is day zero of the 1900 date system as used by Excel
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxExportWriter writes a single worksheet workbook. Rows are streamed into the
+// worksheet entry of the zip archive; the remaining parts are written on Close.
+type xlsxExportWriter struct {
+	zip       *zip.Writer
+	sheet     *bufio.Writer
+	sheetName string
+	columns   []ExportColumn
+	styles    []int
+	numFmts   []string
+	rowNum    int
+}
+
+func newXLSXExportWriter(w io.Writer, sheetName string) *xlsxExportWriter {
+	return &xlsxExportWriter{zip: zip.NewWriter(w), sheetName: xlsxSheetName(sheetName)}
+}
+
+func (xw *xlsxExportWriter) WriteHeader(columns []ExportColumn) error {
+	xw.columns = columns
+	xw.styles = make([]int, len(columns))
+	for i, col := range columns {
+		xw.styles[i] = xw.columnStyle(col)
+	}
+
+	entry, err := xw.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	xw.sheet = bufio.NewWriter(entry)
+	xw.sheet.WriteString(xml.Header)
+	fmt.Fprintf(xw.sheet, `<worksheet xmlns="%s"><sheetData>`, xlsxMainNS)
+
+	xw.startRow()
+	for i, col := range columns {
+		xw.writeString(i, col.Header, xlsxStyleHeader)
+	}
+	return xw.endRow()
+}
+
+func (xw *xlsxExportWriter) WriteRow(row map[string]any) error {
+	xw.startRow()
+	for i, col := range xw.columns {
+		xw.writeCell(i, row[col.Name], col, xw.styles[i])
+	}
+	return xw.endRow()
+}
+
+func (xw *xlsxExportWriter) Close() error {
+	if xw.sheet == nil {
+		if err := xw.WriteHeader(nil); err != nil {
+			return err
+		}
+	}
+	xw.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := xw.sheet.Flush(); err != nil {
+		return err
+	}
+
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="` + xlsxPkgNS + `">` +
+			`<Relationship Id="rId1" Type="` + xlsxRelNS + `/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="` + xlsxMainNS + `" xmlns:r="` + xlsxRelNS + `"><sheets>` +
+			`<sheet name="` + xmlEscape(xw.sheetName) + `" sheetId="1" r:id="rId1"/>` +
+			`</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="` + xlsxPkgNS + `">` +
+			`<Relationship Id="rId1" Type="` + xlsxRelNS + `/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Type="` + xlsxRelNS + `/styles" Target="styles.xml"/>` +
+			`</Relationships>`},
+		{"xl/styles.xml", xw.stylesXML()},
+	}
+	for _, part := range parts {
+		entry, err := xw.zip.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(entry, xml.Header+part.content); err != nil {
+			return err
+		}
+	}
+
+	return xw.zip.Close()
+}
+
+// columnStyle registers the number format of a column and returns its cell style index
+func (xw *xlsxExportWriter) columnStyle(col ExportColumn) int {
+	format := col.Format
+	switch col.Type {
+	case "Date", "DateTime":
+		if format != "" {
+			format = excelDateFormat(format)
+		}
+	case "Number", "Currency", "Percentage":
+	default:
+		return xlsxStyleDefault
+	}
+	if format == "" {
+		format = xlsxDefaultFormats[col.Type]
+	}
+	if format == "" {
+		return xlsxStyleDefault
+	}
+
+	for i, existing := range xw.numFmts {
+		if existing == format {
+			return xlsxStyleHeader + 1 + i
+		}
+	}
+	xw.numFmts = append(xw.numFmts, format)
+	return xlsxStyleHeader + len(xw.numFmts)
+}
+
+func (xw *xlsxExportWriter) stylesXML() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<styleSheet xmlns="%s">`, xlsxMainNS)
+	if len(xw.numFmts) > 0 {
+		fmt.Fprintf(&b, `<numFmts count="%d">`, len(xw.numFmts))
+		for i, format := range xw.numFmts {
+			fmt.Fprintf(&b, `<numFmt numFmtId="%d" formatCode="%s"/>`, xlsxFirstCustomNumFmt+i, xmlEscape(format))
+		}
+		b.WriteString(`</numFmts>`)
+	}
+	b.WriteString(`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font>` +
+		`<font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>`)
+	fmt.Fprintf(&b, `<cellXfs count="%d">`, 2+len(xw.numFmts))
+	b.WriteString(`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>`)
+	for i := range xw.numFmts {
+		fmt.Fprintf(&b, `<xf numFmtId="%d" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>`, xlsxFirstCustomNumFmt+i)
+	}
+	b.WriteString(`</cellXfs><cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles></styleSheet>`)
+	return b.String()
+}
+
+func (xw *xlsxExportWriter) startRow() {
+	xw.rowNum++
+	fmt.Fprintf(xw.sheet, `<row r="%d">`, xw.rowNum)
+}
+
+func (xw *xlsxExportWriter) endRow() error {
+	_, err := xw.sheet.WriteString(`</row>`)
+	return err
+}
+
+// writeCell writes a value as a number, boolean, date or text cell depending on its
+// Go type and the column type; empty values produce no cell
+func (xw *xlsxExportWriter) writeCell(index int, value any, col ExportColumn, style int) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case bool:
+		b := "0"
+		if v {
+			b = "1"
+		}
+		fmt.Fprintf(xw.sheet, `<c r="%s" t="b"><v>%s</v></c>`, xw.cellRef(index), b)
+	case int, int32, int64, uint, uint32, uint64:
+		xw.writeNumber(index, fmt.Sprint(v), style)
+	case float32:
+		xw.writeNumber(index, strconv.FormatFloat(float64(v), 'f', -1, 32), style)
+	case float64:
+		xw.writeNumber(index, strconv.FormatFloat(v, 'f', -1, 64), style)
+	case json.Number:
+		xw.writeNumber(index, v.String(), style)
+	case time.Time:
+		xw.writeNumber(index, xlsxSerial(v), style)
+	case []byte:
+		xw.writeString(index, escapeFormula(string(v)), style)
+	case string:
+		switch col.Type {
+		case "Date", "DateTime":
+			if t, ok := parseExportTime(v); ok {
+				xw.writeNumber(index, xlsxSerial(t), style)
+				return
+			}
+		case "Number", "Currency", "Percentage":
+			// Decimal columns are scanned as text
+			if _, err := strconv.ParseFloat(v, 64); err == nil {
+				xw.writeNumber(index, v, style)
+				return
+			}
+		}
+		xw.writeString(index, escapeFormula(v), style)
+	default:
+		xw.writeString(index, escapeFormula(fmt.Sprint(v)), style)
+	}
+}
+
+func (xw *xlsxExportWriter) writeNumber(index int, number string, style int) {
+	fmt.Fprintf(xw.sheet, `<c r="%s"%s><v>%s</v></c>`, xw.cellRef(index), styleAttr(style), number)
+}
+
+func (xw *xlsxExportWriter) writeString(index int, text string, style int) {
+	fmt.Fprintf(xw.sheet, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">`, xw.cellRef(index), styleAttr(style))
+	xml.EscapeText(xw.sheet, []byte(text))
+	xw.sheet.WriteString(`</t></is></c>`)
+}
+
+func (xw *xlsxExportWriter) cellRef(index int) string {
+	return xlsxColumnName(index) + strconv.Itoa(xw.rowNum)
+}
+
+func styleAttr(style int) string {
+	if style == xlsxStyleDefault {
+		return ""
+	}
+	return fmt.Sprintf(` s="%d"`, style)
+}
+
+// xlsxColumnName converts a 0-based column index to a column name: A, B, ..., Z, AA, ...
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxSerial converts a time to an Excel date serial number, keeping its wall clock
+func xlsxSerial(t time.Time) string {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	days := wall.Sub(xlsxEpoch).Hours() / 24
+	return strconv.FormatFloat(days, 'f', -1, 64)
+}
+
+// xlsxSheetName removes characters Excel does not allow in sheet names and limits the length to 31
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		name = "Sheet1"
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
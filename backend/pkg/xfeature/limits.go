@@ -0,0 +1,67 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrQueryTimeout is returned when a query or action exceeds its Timeout
+var ErrQueryTimeout = errors.New("query timed out")
+
+// errRowLimit stops a row stream once MaxRows rows have been passed on
+var errRowLimit = errors.New("row limit reached")
+
+// parseTimeout parses a Timeout attribute; fallback is used when it is unset or invalid
+func parseTimeout(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	timeout, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || timeout <= 0 {
+		return fallback
+	}
+	return timeout
+}
+
+// parseMaxRows returns the MaxRows attribute, or fallback when it is unset
+func parseMaxRows(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// withTimeout derives a context that is cancelled after timeout; a timeout <= 0 adds no deadline
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// withStartTimeout derives a context that is cancelled if timeout passes before started
+// is called, as streams do on their first row; afterwards only ctx bounds it. A timeout
+// <= 0 adds no limit.
+func withStartTimeout(ctx context.Context, timeout time.Duration) (context.Context, func(), context.CancelFunc) {
+	startCtx, cancel := context.WithCancelCause(ctx)
+	if timeout <= 0 {
+		return startCtx, func() {}, func() { cancel(nil) }
+	}
+	timer := time.AfterFunc(timeout, func() { cancel(context.DeadlineExceeded) })
+	return startCtx, func() { timer.Stop() }, func() {
+		timer.Stop()
+		cancel(nil)
+	}
+}
+
+// timeoutError reports err as ErrQueryTimeout if ctx ran past its own deadline.
+// Cancellation by the caller, such as a client disconnect, is returned unchanged.
+func timeoutError(ctx, parent context.Context, err error, id string, timeout time.Duration) error {
+	if errors.Is(context.Cause(ctx), context.DeadlineExceeded) && parent.Err() == nil {
+		return fmt.Errorf("%w: %s exceeded %s", ErrQueryTimeout, id, timeout)
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// endlessQuery never finishes on its own
+const endlessQuery = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT COUNT(*) AS total FROM n"
+
+// TestQueryMaxRows tests that results are cut off at MaxRows and marked truncated
+func TestQueryMaxRows(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	for i := 1; i <= 5; i++ {
+		_, err := db.Exec("INSERT INTO users (username, email) VALUES (?, ?)", fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i))
+		if err != nil {
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	query := &Query{Id: "ListUsers", SQL: "SELECT user_id FROM users ORDER BY user_id", MaxRows: 3}
+	executor := NewQueryExecutorWithOptions(testLogger, ExecutorOptions{MaxRows: 4})
+
+	rows, err := executor.Execute(ctx, db, query, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rows) != 3 || !executor.LastTruncated || executor.LastMaxRows != 3 {
+		t.Errorf("Expected 3 truncated rows, got %d (truncated %v, maxRows %d)", len(rows), executor.LastTruncated, executor.LastMaxRows)
+	}
+
+	// The default applies without a MaxRows attribute
+	query.MaxRows = 0
+	rows, err = executor.Execute(ctx, db, query, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rows) != 4 || !executor.LastTruncated {
+		t.Errorf("Expected 4 truncated rows, got %d (truncated %v)", len(rows), executor.LastTruncated)
+	}
+
+	query.MaxRows = 5
+	rows, err = executor.Execute(ctx, db, query, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rows) != 5 || executor.LastTruncated {
+		t.Errorf("Expected all 5 rows without truncation, got %d (truncated %v)", len(rows), executor.LastTruncated)
+	}
+
+	// Streams ignore the default but honour the attribute
+	query.MaxRows = 0
+	count, err := executor.Stream(ctx, db, query, nil, func(map[string]any) error { return nil })
+	if err != nil || count != 5 || executor.LastTruncated {
+		t.Errorf("Expected 5 streamed rows, got %d (truncated %v, error %v)", count, executor.LastTruncated, err)
+	}
+	query.MaxRows = 2
+	count, err = executor.Stream(ctx, db, query, nil, func(map[string]any) error { return nil })
+	if err != nil || count != 2 || !executor.LastTruncated {
+		t.Errorf("Expected 2 truncated streamed rows, got %d (truncated %v, error %v)", count, executor.LastTruncated, err)
+	}
+}
+
+// TestQueryTimeout tests that a query is cancelled at its Timeout
+func TestQueryTimeout(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	query := &Query{Id: "Endless", SQL: endlessQuery, Timeout: "50ms"}
+	executor := NewQueryExecutorWithOptions(testLogger, ExecutorOptions{QueryTimeout: time.Minute})
+
+	start := time.Now()
+	_, err := executor.Execute(context.Background(), db, query, nil)
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("Expected ErrQueryTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the query to stop near its timeout, took %v", elapsed)
+	}
+
+	// Cancellation by the caller is not a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	query.Timeout = ""
+	_, err = executor.Execute(ctx, db, query, nil)
+	if err == nil || errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("Expected a cancellation error other than ErrQueryTimeout, got %v", err)
+	}
+
+	action := &ActionQuery{Id: "EndlessAction", Type: "Insert", SQL: "INSERT INTO users (username, email) SELECT 'x', 'x@example.com' WHERE (" + endlessQuery + ") > 0", Timeout: "50ms"}
+	_, err = NewActionExecutor(testLogger).Execute(context.Background(), db, action, nil)
+	if !errors.Is(err, ErrQueryTimeout) {
+		t.Errorf("Expected ErrQueryTimeout for the action, got %v", err)
+	}
+}
+
+// TestStreamTimeout tests that a stream times out before its first row but not while rows flow
+func TestStreamTimeout(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+	executor := NewQueryExecutorWithOptions(testLogger, ExecutorOptions{QueryTimeout: 50 * time.Millisecond})
+
+	endless := &Query{Id: "Endless", SQL: endlessQuery}
+	if _, err := executor.Stream(ctx, db, endless, nil, func(map[string]any) error { return nil }); !errors.Is(err, ErrQueryTimeout) {
+		t.Fatalf("Expected ErrQueryTimeout before the first row, got %v", err)
+	}
+
+	// A slow consumer keeps reading past the timeout once rows flow
+	rows := &Query{Id: "Numbers", SQL: "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 5) SELECT i FROM n"}
+	count, err := executor.Stream(ctx, db, rows, nil, func(map[string]any) error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	})
+	if err != nil || count != 5 {
+		t.Errorf("Expected all 5 rows of a stream outlasting its timeout, got %d (error %v)", count, err)
+	}
+}
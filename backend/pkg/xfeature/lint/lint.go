@@ -0,0 +1,385 @@
+// Package lint performs semantic checks on XFeature definitions that the XML
+// structure alone cannot catch, such as references between elements and
+// parameters that have no source.
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/taheri24/xpanel/backend/pkg/xfeature"
+)
+
+// Severity classifies how serious an issue is
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Issue codes reported by the linter
+const (
+	CodeDanglingRef     = "dangling-ref"
+	CodeDuplicateID     = "duplicate-id"
+	CodeUnusedMapping   = "unused-mapping"
+	CodeUnboundParam    = "unbound-parameter"
+	CodeUnselectedCol   = "unselected-column"
+	CodeUnusedFormField = "unused-form-field"
+	CodeUncheckedToken  = "unchecked-concurrency-token"
+	CodeLoadFailed      = "load-failed"
+)
+
+// Issue is a single finding in a feature definition
+type Issue struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Element  string   `json:"element"`
+	Message  string   `json:"message"`
+}
+
+// String formats the issue for console output
+func (i Issue) String() string {
+	return fmt.Sprintf("%s [%s] %s: %s", i.Severity, i.Code, i.Element, i.Message)
+}
+
+// HasErrors reports whether any issue has error severity
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint runs every check against a parsed feature definition
+func Lint(xf *xfeature.XFeature) []Issue {
+	l := &linter{xf: xf}
+	l.checkDuplicateIDs()
+	l.checkReferences()
+	l.checkParameters()
+	l.checkMappings()
+	l.checkColumns()
+	l.checkFormFields()
+	l.checkConcurrency()
+	return l.issues
+}
+
+type linter struct {
+	xf     *xfeature.XFeature
+	issues []Issue
+}
+
+func (l *linter) report(severity Severity, code, element, format string, args ...any) {
+	l.issues = append(l.issues, Issue{
+		Severity: severity,
+		Code:     code,
+		Element:  element,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// checkDuplicateIDs reports elements of the same kind that share an Id or Name
+func (l *linter) checkDuplicateIDs() {
+	check := func(kind string, ids []string) {
+		seen := make(map[string]bool)
+		for _, id := range ids {
+			if id == "" {
+				continue
+			}
+			if seen[id] {
+				l.report(SeverityError, CodeDuplicateID, kind+" "+id, "Id is declared more than once")
+			}
+			seen[id] = true
+		}
+	}
+
+	var queries, actions, groups, reconciles, forms, tables, mappings, listQueries []string
+	for _, q := range l.xf.Backend.Queries {
+		queries = append(queries, q.Id)
+	}
+	for _, a := range l.xf.Backend.ActionQueries {
+		actions = append(actions, a.Id)
+	}
+	for _, g := range l.xf.Backend.ActionGroups {
+		groups = append(groups, g.Id)
+	}
+	for _, r := range l.xf.Backend.Reconciles {
+		reconciles = append(reconciles, r.Id)
+	}
+	for _, f := range l.xf.Frontend.Forms {
+		forms = append(forms, f.Id)
+	}
+	for _, t := range l.xf.Frontend.DataTables {
+		tables = append(tables, t.Id)
+	}
+	for _, m := range l.xf.Mappings {
+		mappings = append(mappings, m.Name)
+		if m.ListQuery != nil {
+			listQueries = append(listQueries, m.ListQuery.Id)
+		}
+	}
+
+	check("Query", queries)
+	check("ActionQuery", actions)
+	check("ActionGroup", groups)
+	check("Reconcile", reconciles)
+	check("Form", forms)
+	check("DataTable", tables)
+	check("Mapping", mappings)
+	check("ListQuery", listQueries)
+}
+
+// checkReferences reports QueryRef, ResultSet, DetailOf, Bind, LeftRef, RightRef, ActionRef, FormActions and
+// Invalidates entries that point nowhere
+func (l *linter) checkReferences() {
+	for _, action := range l.xf.Backend.ActionQueries {
+		for _, queryID := range action.InvalidatedQueries() {
+			if !l.hasQuery(queryID) {
+				l.report(SeverityError, CodeDanglingRef, "ActionQuery "+action.Id, "Invalidates entry %q does not match any Query", queryID)
+			}
+		}
+		if action.ConflictQueryRef != "" && !l.hasQuery(action.ConflictQueryRef) {
+			l.report(SeverityError, CodeDanglingRef, "ActionQuery "+action.Id, "ConflictQueryRef %q does not match any Query", action.ConflictQueryRef)
+		}
+	}
+
+	for _, group := range l.xf.Backend.ActionGroups {
+		for i, step := range group.Steps {
+			if !l.hasAction(step.ActionRef) {
+				l.report(SeverityError, CodeDanglingRef, "ActionGroup "+group.Id, "Step %d ActionRef %q does not match any ActionQuery", i, step.ActionRef)
+			}
+		}
+	}
+
+	for _, reconcile := range l.xf.Backend.Reconciles {
+		if !l.hasQuery(reconcile.LeftRef) {
+			l.report(SeverityError, CodeDanglingRef, "Reconcile "+reconcile.Id, "LeftRef %q does not match any Query", reconcile.LeftRef)
+		}
+		if !l.hasQuery(reconcile.RightRef) {
+			l.report(SeverityError, CodeDanglingRef, "Reconcile "+reconcile.Id, "RightRef %q does not match any Query", reconcile.RightRef)
+		}
+	}
+
+	for _, table := range l.xf.Frontend.DataTables {
+		element := "DataTable " + table.Id
+		if query, err := l.xf.GetQuery(table.QueryRef); err != nil {
+			l.report(SeverityError, CodeDanglingRef, element, "QueryRef %q does not match any Query", table.QueryRef)
+		} else if _, ok := query.ResultSetIndex(table.ResultSet); !ok {
+			l.report(SeverityError, CodeDanglingRef, element, "ResultSet %q does not match any ResultSet of Query %s", table.ResultSet, query.Id)
+		}
+		if table.DetailOf != "" {
+			if _, err := l.xf.GetDataTable(table.DetailOf); err != nil {
+				l.report(SeverityError, CodeDanglingRef, element, "DetailOf %q does not match any DataTable", table.DetailOf)
+			}
+		}
+		if query, err := l.xf.GetQuery(table.QueryRef); err == nil {
+			for _, bind := range table.Binds {
+				if !slices.Contains(query.Parameters, bind.Param) {
+					l.report(SeverityError, CodeDanglingRef, element, "Bind Param %q is not a parameter of Query %s", bind.Param, query.Id)
+				}
+			}
+		}
+		for _, formID := range splitList(table.FormActions) {
+			if _, err := l.xf.GetForm(formID); err != nil {
+				l.report(SeverityError, CodeDanglingRef, element, "FormActions entry %q does not match any Form", formID)
+			}
+		}
+	}
+
+	for _, form := range l.xf.Frontend.Forms {
+		element := "Form " + form.Id
+		if form.ActionRef != "" && !l.hasAction(form.ActionRef) {
+			l.report(SeverityError, CodeDanglingRef, element, "ActionRef %q does not match any ActionQuery", form.ActionRef)
+		}
+		if form.QueryRef != "" && !l.hasQuery(form.QueryRef) {
+			l.report(SeverityError, CodeDanglingRef, element, "QueryRef %q does not match any Query", form.QueryRef)
+		}
+		for _, button := range form.Buttons {
+			if button.ActionRef != "" && !l.hasAction(button.ActionRef) {
+				l.report(SeverityError, CodeDanglingRef, element, "Button %q ActionRef %q does not match any ActionQuery", button.Label, button.ActionRef)
+			}
+		}
+	}
+}
+
+// checkParameters reports SQL parameters that have neither a Mapping, a Form Field nor a Bind feeding them
+func (l *linter) checkParameters() {
+	mappings := l.mappingNames()
+
+	for _, query := range l.xf.Backend.Queries {
+		fields := l.fieldNames(func(f *xfeature.Form) bool { return f.QueryRef == query.Id })
+		binds := l.bindParams(query.Id)
+		for _, param := range xfeature.ExtractParameters(query.SQL) {
+			if !mappings[param] && !fields[param] && !binds[param] {
+				l.report(SeverityWarning, CodeUnboundParam, "Query "+query.Id, "parameter :%s has no Mapping or Form Field", param)
+			}
+		}
+	}
+
+	for _, action := range l.xf.Backend.ActionQueries {
+		fields := l.fieldNames(func(f *xfeature.Form) bool { return f.ActionRef == action.Id })
+		for _, param := range xfeature.ExtractParameters(action.SQL) {
+			if !mappings[param] && !fields[param] {
+				l.report(SeverityWarning, CodeUnboundParam, "ActionQuery "+action.Id, "parameter :%s has no Mapping or Form Field", param)
+			}
+		}
+	}
+}
+
+// checkMappings reports Mappings that no parameter, column or field refers to
+func (l *linter) checkMappings() {
+	used := make(map[string]bool)
+	for _, query := range l.xf.Backend.Queries {
+		for _, param := range xfeature.ExtractParameters(query.SQL) {
+			used[param] = true
+		}
+	}
+	for _, action := range l.xf.Backend.ActionQueries {
+		for _, param := range xfeature.ExtractParameters(action.SQL) {
+			used[param] = true
+		}
+	}
+	for _, table := range l.xf.Frontend.DataTables {
+		for _, col := range table.Columns {
+			used[col.Name] = true
+		}
+	}
+	for _, form := range l.xf.Frontend.Forms {
+		for _, field := range form.Fields {
+			used[field.Name] = true
+		}
+	}
+
+	for _, mapping := range l.xf.Mappings {
+		if !used[mapping.Name] {
+			l.report(SeverityWarning, CodeUnusedMapping, "Mapping "+mapping.Name, "not used by any parameter, column or field")
+		}
+	}
+}
+
+// checkColumns reports DataTable columns that the referenced query does not select
+func (l *linter) checkColumns() {
+	for _, table := range l.xf.Frontend.DataTables {
+		query, err := l.xf.GetQuery(table.QueryRef)
+		if err != nil || table.ResultSet != "" {
+			continue
+		}
+		selected, ok := SelectedColumns(query.SQL)
+		if !ok {
+			continue
+		}
+		for _, col := range table.Columns {
+			if !selected[strings.ToLower(col.Name)] {
+				l.report(SeverityWarning, CodeUnselectedCol, "DataTable "+table.Id, "column %q is not selected by Query %s", col.Name, query.Id)
+			}
+		}
+	}
+}
+
+// checkFormFields reports Form fields that the referenced action never uses
+func (l *linter) checkFormFields() {
+	for _, form := range l.xf.Frontend.Forms {
+		if form.ActionRef == "" {
+			continue
+		}
+		action, err := l.xf.GetActionQuery(form.ActionRef)
+		if err != nil {
+			continue
+		}
+		params := make(map[string]bool)
+		for _, param := range xfeature.ExtractParameters(action.SQL) {
+			params[param] = true
+		}
+		for _, field := range form.Fields {
+			if !params[field.Name] {
+				l.report(SeverityWarning, CodeUnusedFormField, "Form "+form.Id, "field %q is not used by ActionQuery %s", field.Name, action.Id)
+			}
+		}
+	}
+}
+
+// checkConcurrency reports actions with a Concurrency column whose SQL never compares
+// the original value, so concurrent changes go undetected
+func (l *linter) checkConcurrency() {
+	for _, action := range l.xf.Backend.ActionQueries {
+		if action.Concurrency == "" || action.IsProcedure() {
+			continue
+		}
+		if !slices.Contains(xfeature.ExtractParameters(action.SQL), action.Concurrency) {
+			l.report(SeverityWarning, CodeUncheckedToken, "ActionQuery "+action.Id, "Concurrency column %q is not compared, the SQL does not use :%s", action.Concurrency, action.Concurrency)
+		}
+	}
+}
+
+// bindParams returns the parameters of a query fed by the Bind elements of detail tables showing it
+func (l *linter) bindParams(queryID string) map[string]bool {
+	params := make(map[string]bool)
+	for _, table := range l.xf.Frontend.DataTables {
+		if table.DetailOf == "" || table.QueryRef != queryID {
+			continue
+		}
+		for _, bind := range table.Binds {
+			params[bind.Param] = true
+		}
+	}
+	return params
+}
+
+func (l *linter) hasQuery(id string) bool {
+	_, err := l.xf.GetQuery(id)
+	return err == nil
+}
+
+func (l *linter) hasAction(id string) bool {
+	_, err := l.xf.GetActionQuery(id)
+	return err == nil
+}
+
+func (l *linter) mappingNames() map[string]bool {
+	names := make(map[string]bool)
+	for _, mapping := range l.xf.Mappings {
+		names[mapping.Name] = true
+	}
+	return names
+}
+
+func (l *linter) fieldNames(match func(*xfeature.Form) bool) map[string]bool {
+	names := make(map[string]bool)
+	for _, form := range l.xf.Frontend.Forms {
+		if !match(form) {
+			continue
+		}
+		for _, field := range form.Fields {
+			names[field.Name] = true
+		}
+	}
+	return names
+}
+
+// splitList splits a comma separated attribute value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// SortIssues orders issues by severity, element and message
+func SortIssues(issues []Issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Severity != issues[j].Severity {
+			return issues[i].Severity == SeverityError
+		}
+		if issues[i].Element != issues[j].Element {
+			return issues[i].Element < issues[j].Element
+		}
+		return issues[i].Message < issues[j].Message
+	})
+}
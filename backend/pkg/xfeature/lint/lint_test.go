@@ -0,0 +1,178 @@
+package lint
+
+import (
+	"log/slog"
+	"testing"
+
+	"github.com/taheri24/xpanel/backend/pkg/xfeature"
+)
+
+func parseFeature(t *testing.T, content string) *xfeature.XFeature {
+	t.Helper()
+	xf := xfeature.NewXFeature(slog.Default())
+	if err := xf.Parse([]byte(content)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	return xf
+}
+
+// findIssue returns the first issue with the given code and element
+func findIssue(issues []Issue, code, element string) *Issue {
+	for i := range issues {
+		if issues[i].Code == code && issues[i].Element == element {
+			return &issues[i]
+		}
+	}
+	return nil
+}
+
+const lintTestXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Feature Name="LintTest" Version="1.0">
+  <Backend>
+    <Query Id="ListUsers" Type="Select">
+      <![CDATA[SELECT user_id, u.username, COUNT(*) AS total FROM users u WHERE status = :status GROUP BY user_id, u.username]]>
+    </Query>
+    <Query Id="ListUsers" Type="Select">SELECT 1</Query>
+    <ActionQuery Id="CreateUser" Type="Insert" Invalidates="ListUsers,MissingQuery">
+      <![CDATA[INSERT INTO users (username, password_hash) VALUES (:username, :password_hash)]]>
+    </ActionQuery>
+    <ActionQuery Id="RenameUser" Type="Update" Concurrency="updated_at" ConflictQueryRef="MissingQuery">
+      <![CDATA[UPDATE users SET username = :username WHERE user_id = :user_id]]>
+    </ActionQuery>
+    <ActionGroup Id="Onboard">
+      <Step ActionRef="CreateUser" InsertId="user_id"/>
+      <Step ActionRef="MissingAction"/>
+    </ActionGroup>
+    <Reconcile Id="CompareUsers" LeftRef="ListUsers" RightRef="MissingQuery">
+      <Key Left="user_id"/>
+    </Reconcile>
+  </Backend>
+  <Frontend>
+    <DataTable Id="UsersTable" QueryRef="ListUsers" Title="Users" FormActions="CreateUserForm,MissingForm">
+      <Column Name="user_id" Label="ID"/>
+      <Column Name="username" Label="Username"/>
+      <Column Name="total" Label="Total"/>
+      <Column Name="email" Label="Email"/>
+    </DataTable>
+    <DataTable Id="OrphanTable" QueryRef="MissingQuery" Title="Orphan"/>
+    <DataTable Id="TotalsTable" QueryRef="ListUsers" Title="Totals" ResultSet="totals"/>
+    <DataTable Id="DetailTable" QueryRef="ListUsers" Title="Detail" DetailOf="MissingTable">
+      <Bind Column="user_id" Param="user_id"/>
+    </DataTable>
+    <Form Id="CreateUserForm" Mode="Create" Dialog="true" ActionRef="CreateUser" QueryRef="GetUserDetails" Title="Create">
+      <Field Name="username" Type="Text"/>
+      <Field Name="password" Type="Password"/>
+      <Button Type="Submit" Label="Save" Style="Primary" ActionRef="MissingAction"/>
+    </Form>
+  </Frontend>
+  <Mapping Name="status" DataType="String" Label="Status"/>
+  <Mapping Name="priority" DataType="String" Label="Priority"/>
+</Feature>`
+
+// TestLint tests every check against a definition with known problems
+func TestLint(t *testing.T) {
+	issues := Lint(parseFeature(t, lintTestXML))
+
+	tests := []struct {
+		code     string
+		element  string
+		severity Severity
+	}{
+		{CodeDuplicateID, "Query ListUsers", SeverityError},
+		{CodeDanglingRef, "DataTable OrphanTable", SeverityError},
+		{CodeDanglingRef, "DataTable UsersTable", SeverityError},
+		{CodeDanglingRef, "DataTable TotalsTable", SeverityError},
+		{CodeDanglingRef, "DataTable DetailTable", SeverityError},
+		{CodeDanglingRef, "Form CreateUserForm", SeverityError},
+		{CodeDanglingRef, "ActionQuery CreateUser", SeverityError},
+		{CodeDanglingRef, "ActionGroup Onboard", SeverityError},
+		{CodeDanglingRef, "Reconcile CompareUsers", SeverityError},
+		{CodeDanglingRef, "ActionQuery RenameUser", SeverityError},
+		{CodeUncheckedToken, "ActionQuery RenameUser", SeverityWarning},
+		{CodeUnboundParam, "ActionQuery CreateUser", SeverityWarning},
+		{CodeUnusedMapping, "Mapping priority", SeverityWarning},
+		{CodeUnselectedCol, "DataTable UsersTable", SeverityWarning},
+		{CodeUnusedFormField, "Form CreateUserForm", SeverityWarning},
+	}
+
+	for _, tt := range tests {
+		issue := findIssue(issues, tt.code, tt.element)
+		if issue == nil {
+			t.Errorf("Expected %s issue for %s, got none", tt.code, tt.element)
+			continue
+		}
+		if issue.Severity != tt.severity {
+			t.Errorf("Expected %s severity for %s %s, got %s", tt.severity, tt.code, tt.element, issue.Severity)
+		}
+	}
+
+	if !HasErrors(issues) {
+		t.Error("Expected HasErrors to be true")
+	}
+
+	// :status is covered by a Mapping and must not be reported
+	if issue := findIssue(issues, CodeUnboundParam, "Query ListUsers"); issue != nil {
+		t.Errorf("Expected no unbound parameter issue for ListUsers, got %s", issue.Message)
+	}
+	if issue := findIssue(issues, CodeUnusedMapping, "Mapping status"); issue != nil {
+		t.Errorf("Expected Mapping status to be used, got %s", issue.Message)
+	}
+
+	// Only email is missing from the select list
+	for _, issue := range issues {
+		if issue.Code == CodeUnselectedCol && issue.Message != `column "email" is not selected by Query ListUsers` {
+			t.Errorf("Unexpected unselected column issue: %s", issue.Message)
+		}
+	}
+}
+
+// TestLintSampleFeature tests that the bundled sample has no blocking issues
+func TestLintSampleFeature(t *testing.T) {
+	xf := xfeature.NewXFeature(slog.Default())
+	if err := xf.LoadFromFile("../../../../specs/xfeature/user-management-sample.xml"); err != nil {
+		t.Fatalf("Failed to load sample feature: %v", err)
+	}
+
+	issues := Lint(xf)
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			t.Errorf("Unexpected error in sample feature: %s", issue)
+		}
+	}
+}
+
+// TestSelectedColumns tests select-list column extraction
+func TestSelectedColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		sql      string
+		expected []string
+		ok       bool
+	}{
+		{"Plain columns", "SELECT id, name FROM users", []string{"id", "name"}, true},
+		{"Qualified and aliased", "SELECT u.id, u.first_name + ' ' + u.last_name full_name, COUNT(*) AS Total FROM users u", []string{"id", "full_name", "total"}, true},
+		{"TSQL alias", "SELECT TOP 10 [Invoice No] = h.InvoiceNo, h.[Date] FROM inv h", []string{"invoice no", "date"}, true},
+		{"Distinct with subquery", "SELECT DISTINCT status, (SELECT MAX(x) FROM t) AS mx FROM users", []string{"status", "mx"}, true},
+		{"CTE", "WITH x AS (SELECT a FROM t) SELECT b, c FROM x", []string{"b", "c"}, true},
+		{"Wildcard", "SELECT * FROM users", nil, false},
+		{"Qualified wildcard", "SELECT u.* FROM users u", nil, false},
+		{"Comment", "SELECT id -- , hidden\n, name FROM users", []string{"id", "name"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, ok := SelectedColumns(tt.sql)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok=%v, got %v", tt.ok, ok)
+			}
+			if len(columns) != len(tt.expected) {
+				t.Errorf("Expected %d columns, got %d (%v)", len(tt.expected), len(columns), columns)
+			}
+			for _, col := range tt.expected {
+				if !columns[col] {
+					t.Errorf("Expected column %q in %v", col, columns)
+				}
+			}
+		})
+	}
+}
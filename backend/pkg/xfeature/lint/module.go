@@ -0,0 +1,39 @@
+package lint
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/taheri24/xpanel/backend/pkg/config"
+	"github.com/taheri24/xpanel/backend/pkg/xfeature"
+	"go.uber.org/fx"
+)
+
+// RegisterDevelopmentHook lints every feature definition as it is loaded when running in development mode
+func RegisterDevelopmentHook(cfg *config.Config, registry *xfeature.Registry) {
+	if cfg.Server.Env != "development" {
+		return
+	}
+
+	registry.OnLoad(func(entry *xfeature.RegistryEntry) {
+		issues := Lint(entry.Feature)
+		SortIssues(issues)
+		for _, issue := range issues {
+			level := slog.LevelWarn
+			if issue.Severity == SeverityError {
+				level = slog.LevelError
+			}
+			slog.Log(context.Background(), level, "Feature lint issue",
+				"feature", entry.Key,
+				"code", issue.Code,
+				"element", issue.Element,
+				"message", issue.Message,
+			)
+		}
+	})
+}
+
+// Module exports the development lint hook as an FX module
+var Module = fx.Options(
+	fx.Invoke(RegisterDevelopmentHook),
+)
@@ -0,0 +1,212 @@
+package lint
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SelectedColumns returns the lower-cased output column names of the outermost SELECT.
+// The second return value is false when the names cannot be determined statically,
+// for example when the select list contains a wildcard.
+func SelectedColumns(sqlStr string) (map[string]bool, bool) {
+	tokens := tokenize(sqlStr)
+
+	start := -1
+	depth := 0
+	for i, tok := range tokens {
+		switch {
+		case tok == "(":
+			depth++
+		case tok == ")":
+			depth--
+		case depth == 0 && strings.EqualFold(tok, "SELECT"):
+			start = i + 1
+		}
+		if start >= 0 {
+			break
+		}
+	}
+	if start < 0 {
+		return nil, false
+	}
+
+	// Skip select modifiers
+	for start < len(tokens) {
+		tok := strings.ToUpper(tokens[start])
+		if tok == "DISTINCT" || tok == "ALL" {
+			start++
+			continue
+		}
+		if tok == "TOP" && start+1 < len(tokens) {
+			start += 2
+			if tokens[start-1] == "(" {
+				for start < len(tokens) && tokens[start-1] != ")" {
+					start++
+				}
+			}
+			continue
+		}
+		break
+	}
+
+	var exprs [][]string
+	var current []string
+	depth = 0
+	for _, tok := range tokens[start:] {
+		if depth == 0 && (strings.EqualFold(tok, "FROM") || strings.EqualFold(tok, "INTO")) {
+			break
+		}
+		switch tok {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 && tok == "," {
+			exprs = append(exprs, current)
+			current = nil
+			continue
+		}
+		current = append(current, tok)
+	}
+	if len(current) > 0 {
+		exprs = append(exprs, current)
+	}
+
+	columns := make(map[string]bool)
+	for _, expr := range exprs {
+		name, ok := outputName(expr)
+		if !ok {
+			return nil, false
+		}
+		if name != "" {
+			columns[strings.ToLower(name)] = true
+		}
+	}
+	return columns, true
+}
+
+// outputName determines the column name produced by a select-list expression.
+// An empty name means the expression is unnamed.
+func outputName(expr []string) (string, bool) {
+	if len(expr) == 0 {
+		return "", true
+	}
+	last := expr[len(expr)-1]
+	if last == "*" || strings.HasSuffix(last, ".*") {
+		return "", false
+	}
+
+	// T-SQL style: alias = expression
+	if len(expr) > 2 && expr[1] == "=" && isIdentifier(expr[0]) {
+		return unquote(expr[0]), true
+	}
+
+	// expression AS alias
+	if len(expr) >= 3 && strings.EqualFold(expr[len(expr)-2], "AS") {
+		return unquote(last), true
+	}
+
+	// Plain (optionally qualified) column reference
+	if len(expr) == 1 && isIdentifier(last) {
+		parts := strings.Split(last, ".")
+		return unquote(parts[len(parts)-1]), true
+	}
+
+	// expression alias
+	if len(expr) >= 2 && isIdentifier(last) && !strings.EqualFold(last, "END") {
+		return unquote(last), true
+	}
+
+	return "", true
+}
+
+// tokenize splits SQL into identifiers, literals and punctuation, dropping comments
+func tokenize(sqlStr string) []string {
+	var tokens []string
+	runes := []rune(sqlStr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/') {
+				i++
+			}
+			i += 2
+		case r == '\'':
+			j := i + 1
+			for j < len(runes) {
+				if runes[j] == '\'' {
+					if j+1 < len(runes) && runes[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, string(runes[i:min(j+1, len(runes))]))
+			i = j + 1
+		case isWordRune(r) || r == '[' || r == '"' || r == '`':
+			j := i
+			for j < len(runes) {
+				c := runes[j]
+				if c == '[' || c == '"' || c == '`' {
+					closing := c
+					if c == '[' {
+						closing = ']'
+					}
+					j++
+					for j < len(runes) && runes[j] != closing {
+						j++
+					}
+					j++
+					continue
+				}
+				if isWordRune(c) || c == '.' || (c == '*' && j > i && runes[j-1] == '.') {
+					j++
+					continue
+				}
+				break
+			}
+			tokens = append(tokens, string(runes[i:min(j, len(runes))]))
+			i = j
+		default:
+			tokens = append(tokens, string(r))
+			i++
+		}
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '@' || r == '#' || r == ':' || r == '$'
+}
+
+func isIdentifier(tok string) bool {
+	if tok == "" || strings.HasPrefix(tok, "'") || strings.HasPrefix(tok, ":") || strings.HasPrefix(tok, "@") {
+		return false
+	}
+	first := []rune(tok)[0]
+	return unicode.IsLetter(first) || first == '_' || first == '[' || first == '"' || first == '`'
+}
+
+// unquote strips identifier quoting used by SQL Server, ANSI SQL and MySQL
+func unquote(name string) string {
+	if len(name) >= 2 {
+		switch {
+		case name[0] == '[' && name[len(name)-1] == ']',
+			name[0] == '"' && name[len(name)-1] == '"',
+			name[0] == '`' && name[len(name)-1] == '`':
+			return name[1 : len(name)-1]
+		}
+	}
+	return name
+}
@@ -0,0 +1,48 @@
+package xfeature
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/taheri24/xpanel/backend/pkg/config"
+	"go.uber.org/fx"
+)
+
+// NewRegistryFromConfig loads every feature definition at startup and watches
+// the definition directory for changes while the application runs
+func NewRegistryFromConfig(lc fx.Lifecycle, cfg *config.Config) *Registry {
+	registry := NewRegistry(slog.Default(), cfg.Feature.XFeatureFileLocation)
+	if err := registry.Reload(); err != nil {
+		slog.Warn("Feature definitions could not be loaded", "error", err)
+	}
+
+	interval := cfg.Feature.ReloadInterval
+	if interval <= 0 {
+		return registry
+	}
+
+	var cancel context.CancelFunc
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			var watchCtx context.Context
+			watchCtx, cancel = context.WithCancel(context.Background())
+			go registry.Watch(watchCtx, interval)
+			slog.Info("Watching feature definitions", "dir", registry.Dir(), "interval", interval)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			if cancel != nil {
+				cancel()
+			}
+			return nil
+		},
+	})
+
+	return registry
+}
+
+// Module exports the feature registry and the shared query cache as an FX module
+var Module = fx.Options(
+	fx.Provide(NewRegistryFromConfig),
+	fx.Provide(NewQueryCache),
+)
@@ -0,0 +1,106 @@
+package xfeature
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Optional is a fragment of Query SQL, like AND username LIKE :keyword, that is only
+// included when its Param is supplied. Parameters used only in Optional fragments are
+// not required.
+type Optional struct {
+	Param string `xml:"Param,attr" json:"param"`
+	SQL   string `xml:",chardata" json:"sql"`
+}
+
+// sqlFragment is a piece of compiled Query SQL; fragments with a Param are optional
+type sqlFragment struct {
+	param string
+	sql   string
+}
+
+// compileOptional splits the inner XML of a Query into its SQL text and Optional
+// fragments, in document order. Param and ResultSet declarations are skipped.
+func compileOptional(body string) ([]sqlFragment, error) {
+	decoder := xml.NewDecoder(strings.NewReader("<SQL>" + body + "</SQL>"))
+	var fragments []sqlFragment
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return fragments, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile query SQL: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				continue
+			}
+			if t.Name.Local != "Optional" {
+				if err := decoder.Skip(); err != nil {
+					return nil, fmt.Errorf("failed to compile query SQL: %w", err)
+				}
+				depth--
+				continue
+			}
+			var optional Optional
+			if err := decoder.DecodeElement(&optional, &t); err != nil {
+				return nil, fmt.Errorf("failed to compile query SQL: %w", err)
+			}
+			depth--
+			fragments = append(fragments, sqlFragment{param: optional.Param, sql: optional.SQL})
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 1 {
+				fragments = append(fragments, sqlFragment{sql: string(t)})
+			}
+		}
+	}
+}
+
+// renderFragments joins the fragments, leaving out the optional ones include rejects
+func renderFragments(fragments []sqlFragment, include func(param string) bool) string {
+	var b strings.Builder
+	for _, fragment := range fragments {
+		if fragment.param != "" && !include(fragment.param) {
+			continue
+		}
+		b.WriteString(fragment.sql)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// optionalSupplied reports whether a parameter value switches on its Optional
+// fragments: nil, empty strings and empty lists do not
+func optionalSupplied(value any) bool {
+	if value == nil || value == "" {
+		return false
+	}
+	if values, ok := listValues(value); ok {
+		return len(values) > 0
+	}
+	return true
+}
+
+// ResolveOptional returns the query with the Optional fragments whose parameters are
+// supplied and without the others. Queries without Optional fragments are returned
+// as they are.
+func (q *Query) ResolveOptional(params map[string]any) *Query {
+	if len(q.fragments) == 0 {
+		return q
+	}
+	resolved := *q
+	resolved.SQL = renderFragments(q.fragments, func(param string) bool {
+		return optionalSupplied(params[param])
+	})
+	resolved.fragments = nil
+	return &resolved
+}
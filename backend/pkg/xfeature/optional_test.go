@@ -0,0 +1,87 @@
+package xfeature
+
+import (
+	"context"
+	"testing"
+)
+
+const optionalFeature = `<Feature Name="accounts" Version="1">
+	<Backend>
+		<Query Id="SearchUsers" Type="Select">
+			SELECT username FROM users WHERE status = :status
+			<Optional Param="keyword">AND username LIKE :keyword</Optional>
+			<Optional Param="min_id">AND user_id &gt;= :min_id</Optional>
+			ORDER BY username
+		</Query>
+	</Backend>
+	<Frontend/>
+</Feature>`
+
+// TestResolveOptional tests that Optional fragments are kept in place and dropped without their parameter
+func TestResolveOptional(t *testing.T) {
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(optionalFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	query, _ := xf.GetQuery("SearchUsers")
+	if len(query.Parameters) != 3 || len(query.Optionals) != 2 {
+		t.Errorf("Expected every parameter and two Optional fragments, got %v and %v", query.Parameters, query.Optionals)
+	}
+
+	tests := []struct {
+		name     string
+		params   map[string]any
+		expected string
+	}{
+		{
+			name:     "none supplied",
+			params:   map[string]any{"status": "active", "keyword": ""},
+			expected: "SELECT username FROM users WHERE status = :status\n\t\t\t\n\t\t\t\n\t\t\tORDER BY username",
+		},
+		{
+			name:     "keyword supplied",
+			params:   map[string]any{"status": "active", "keyword": "a%"},
+			expected: "SELECT username FROM users WHERE status = :status\n\t\t\tAND username LIKE :keyword\n\t\t\t\n\t\t\tORDER BY username",
+		},
+		{
+			name:     "both supplied",
+			params:   map[string]any{"status": "active", "keyword": "a%", "min_id": 2},
+			expected: "SELECT username FROM users WHERE status = :status\n\t\t\tAND username LIKE :keyword\n\t\t\tAND user_id >= :min_id\n\t\t\tORDER BY username",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := query.ResolveOptional(tt.params).SQL; got != tt.expected {
+				t.Errorf("Expected:\n%q\nGot:\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestExecuteOptional tests that parameters used only in Optional fragments are not required
+func TestExecuteOptional(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO users (username, email) VALUES ('ada', 'ada@example.com'), ('alan', 'alan@example.com'), ('bob', 'bob@example.com')`); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(optionalFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	query, _ := xf.GetQuery("SearchUsers")
+	executor := NewQueryExecutor(testLogger)
+
+	rows, err := executor.Execute(context.Background(), db, query, map[string]any{"status": "active"})
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("Expected all users without a keyword, got %v (error %v)", rows, err)
+	}
+	rows, err = executor.Execute(context.Background(), db, query, map[string]any{"status": "active", "keyword": "a%"})
+	if err != nil || len(rows) != 2 || rows[1]["username"] != "alan" {
+		t.Errorf("Expected the users matching the keyword, got %v (error %v)", rows, err)
+	}
+	if _, err := executor.Execute(context.Background(), db, query, map[string]any{"keyword": "a%"}); err == nil {
+		t.Errorf("Expected the status parameter to stay required")
+	}
+}
@@ -0,0 +1,62 @@
+package xfeature
+
+import (
+	"time"
+
+	"github.com/taheri24/xpanel/backend/pkg/config"
+)
+
+// DefaultMockDataSetLocation is used when no mock data set location is configured
+const DefaultMockDataSetLocation = "specs/mock/"
+
+// DefaultMaxListSize limits how many values an array parameter may expand to.
+// SQL Server accepts at most 2100 parameters in a single statement.
+const DefaultMaxListSize = 1000
+
+// DefaultMaxBatchSize limits how many parameter sets a batch may run
+const DefaultMaxBatchSize = 1000
+
+// ExecutorOptions configures query and action executors
+type ExecutorOptions struct {
+	MockDataSetLocation string
+	CaptureMockDataSet  bool
+	// MaxListSize limits the values of an array parameter; 0 uses DefaultMaxListSize
+	MaxListSize int
+	// MaxBatchSize limits the parameter sets of a batch; 0 uses DefaultMaxBatchSize
+	MaxBatchSize int
+	// Cache stores results of queries with a Cache attribute; nil disables caching
+	Cache *QueryCache
+	// QueryTimeout and MaxRows apply when a query or action has no Timeout or MaxRows
+	// attribute; 0 means no limit. MaxRows does not apply to streamed results and
+	// timeouts limit streams only until their first row.
+	QueryTimeout time.Duration
+	MaxRows      int
+	// DataSources resolves the DataSource attribute; nil allows only the default source
+	DataSources DataSources
+}
+
+// ExecutorOptionsFromConfig builds executor options from the application configuration
+func ExecutorOptionsFromConfig(cfg *config.Config) ExecutorOptions {
+	return ExecutorOptions{
+		MockDataSetLocation: cfg.Feature.MockDataSetLocation,
+		CaptureMockDataSet:  cfg.Feature.CaptureMockDataSet,
+		MaxListSize:         cfg.Feature.MaxListSize,
+		MaxBatchSize:        cfg.Feature.MaxBatchSize,
+		QueryTimeout:        cfg.Feature.QueryTimeout,
+		MaxRows:             cfg.Feature.MaxRows,
+	}
+}
+
+// withDefaults fills in unset options
+func (o ExecutorOptions) withDefaults() ExecutorOptions {
+	if o.MockDataSetLocation == "" {
+		o.MockDataSetLocation = DefaultMockDataSetLocation
+	}
+	if o.MaxListSize <= 0 {
+		o.MaxListSize = DefaultMaxListSize
+	}
+	if o.MaxBatchSize <= 0 {
+		o.MaxBatchSize = DefaultMaxBatchSize
+	}
+	return o
+}
@@ -0,0 +1,606 @@
+package xfeature
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidPageRequest is returned when paging, sorting, filtering or search arguments are not allowed
+var ErrInvalidPageRequest = errors.New("invalid page request")
+
+// DefaultPageSize is used when a page is requested without a size and the DataTable declares none
+const DefaultPageSize = 25
+
+// MaxPageSize limits how many rows a single page may return
+const MaxPageSize = 1000
+
+// Filter operators accepted in page requests
+const (
+	FilterEquals      = "eq"
+	FilterNotEquals   = "ne"
+	FilterGreater     = "gt"
+	FilterGreaterOrEq = "gte"
+	FilterLess        = "lt"
+	FilterLessOrEq    = "lte"
+	FilterContains    = "contains"
+	FilterStartsWith  = "startsWith"
+	FilterEndsWith    = "endsWith"
+)
+
+var comparisonOperators = map[string]string{
+	FilterEquals:      "=",
+	FilterNotEquals:   "<>",
+	FilterGreater:     ">",
+	FilterGreaterOrEq: ">=",
+	FilterLess:        "<",
+	FilterLessOrEq:    "<=",
+}
+
+// Query string keys that carry page request arguments rather than SQL parameters
+var pageRequestKeys = []string{"page", "pageSize", "sort", "filter", "search"}
+
+// SortField orders rows by a DataTable column
+type SortField struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc"`
+}
+
+// Filter restricts rows by comparing a DataTable column with a value
+type Filter struct {
+	Column   string `json:"column"`
+	Operator string `json:"operator"`
+	Value    any    `json:"value"`
+}
+
+// PageRequest describes server-side paging, sorting, filtering and search for a DataTable query
+type PageRequest struct {
+	// Page is 1-based; 0 together with a PageSize of 0 returns all rows
+	Page     int
+	PageSize int
+	Sort     []SortField
+	Filters  []Filter
+	Search   string
+}
+
+// Paged reports whether the request limits the number of rows returned
+func (p *PageRequest) Paged() bool {
+	return p.Page > 0 || p.PageSize > 0
+}
+
+// PageResult holds one page of rows and the number of rows across all pages
+type PageResult struct {
+	Rows       []map[string]any
+	TotalCount int64
+	Page       int
+	PageSize   int
+	// Truncated reports that the page was cut off by MaxRows
+	Truncated bool
+}
+
+// PagedSQL holds the statements built for a page request
+type PagedSQL struct {
+	DataSQL  string
+	CountSQL string
+	// Params holds the values of the parameters added for filters, search and paging
+	Params map[string]any
+}
+
+// PageRequestKeys returns the query string keys that carry page request arguments for
+// a query. They are reserved only when the query is bound to a DataTable with server-side
+// paging, sorting, filtering or search, and never when the query has a parameter of the
+// same name, so a :search parameter keeps receiving its value.
+func (xf *XFeature) PageRequestKeys(query *Query) []string {
+	table := xf.GetDataTableForQuery(query.Id)
+	if table == nil || !table.ServerSide() {
+		return nil
+	}
+	var keys []string
+	for _, key := range pageRequestKeys {
+		if !containsString(query.Parameters, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// ServerSide reports whether a DataTable enables paging, sorting, filtering or search
+func (dt *DataTable) ServerSide() bool {
+	enabled := func(flag *bool) bool { return flag != nil && *flag }
+	if enabled(dt.Pagination) || enabled(dt.Sortable) || enabled(dt.Filterable) || enabled(dt.Searchable) {
+		return true
+	}
+	for _, col := range dt.Columns {
+		if enabled(col.Sortable) || enabled(col.Filterable) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePageRequest reads page request arguments from a query string:
+//
+//	page=2&pageSize=50&sort=last_name,-created_at&filter=status:eq:active&search=smith
+//
+// Sort columns prefixed with - are sorted descending. Filters have the form
+// column:operator:value, or column:value for equality, and may be repeated.
+// Only the given keys, as returned by PageRequestKeys, are read; the others are left
+// to the parameters of the query. It returns nil when the query string carries none of
+// these arguments.
+func ParsePageRequest(values url.Values, keys []string) (*PageRequest, error) {
+	found := false
+	for _, key := range keys {
+		if _, ok := values[key]; ok {
+			found = true
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	values = pageValues(values, keys)
+
+	page := &PageRequest{Search: strings.TrimSpace(values.Get("search"))}
+
+	var err error
+	if page.Page, err = parsePageNumber(values.Get("page"), "page"); err != nil {
+		return nil, err
+	}
+	if page.PageSize, err = parsePageNumber(values.Get("pageSize"), "pageSize"); err != nil {
+		return nil, err
+	}
+
+	for _, value := range values["sort"] {
+		for _, column := range strings.Split(value, ",") {
+			column = strings.TrimSpace(column)
+			if column == "" {
+				continue
+			}
+			desc := strings.HasPrefix(column, "-")
+			page.Sort = append(page.Sort, SortField{Column: strings.TrimPrefix(column, "-"), Desc: desc})
+		}
+	}
+
+	for _, value := range values["filter"] {
+		parts := strings.SplitN(value, ":", 3)
+		switch len(parts) {
+		case 2:
+			page.Filters = append(page.Filters, Filter{Column: parts[0], Operator: FilterEquals, Value: parts[1]})
+		case 3:
+			page.Filters = append(page.Filters, Filter{Column: parts[0], Operator: parts[1], Value: parts[2]})
+		default:
+			return nil, fmt.Errorf("%w: filter %q must have the form column:operator:value", ErrInvalidPageRequest, value)
+		}
+	}
+
+	return page, nil
+}
+
+// pageValues keeps the values of the given keys
+func pageValues(values url.Values, keys []string) url.Values {
+	kept := make(url.Values, len(keys))
+	for _, key := range keys {
+		if v, ok := values[key]; ok {
+			kept[key] = v
+		}
+	}
+	return kept
+}
+
+func parsePageNumber(value, name string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%w: %s must be a positive integer", ErrInvalidPageRequest, name)
+	}
+	return n, nil
+}
+
+// GetColumn finds a column by name
+func (dt *DataTable) GetColumn(name string) *Column {
+	for _, col := range dt.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+	return nil
+}
+
+// ColumnSortable reports whether a column may be used for server-side sorting.
+// The Sortable attribute of the column overrides the one of the table.
+func (dt *DataTable) ColumnSortable(col *Column) bool {
+	if col.Sortable != nil {
+		return *col.Sortable
+	}
+	return dt.Sortable != nil && *dt.Sortable
+}
+
+// ColumnFilterable reports whether a column may be used for server-side filtering and search.
+// The Filterable attribute of the column overrides the one of the table.
+func (dt *DataTable) ColumnFilterable(col *Column) bool {
+	if col.Filterable != nil {
+		return *col.Filterable
+	}
+	return dt.Filterable != nil && *dt.Filterable
+}
+
+// CoercePageRequest converts filter values to the Mapping DataType of their column,
+// or to a number for numeric columns without a Mapping
+func (xf *XFeature) CoercePageRequest(table *DataTable, page *PageRequest) error {
+	mappings := make(map[string]*Mapping, len(xf.Mappings))
+	for _, mapping := range xf.Mappings {
+		mappings[mapping.Name] = mapping
+	}
+
+	var fields []FieldError
+	for i := range page.Filters {
+		filter := &page.Filters[i]
+		if !isComparison(filter.Operator) {
+			continue
+		}
+
+		dataType := ""
+		if mapping, ok := mappings[filter.Column]; ok {
+			dataType = mapping.DataType
+		} else if col := table.GetColumn(filter.Column); col != nil {
+			switch col.Type {
+			case "Number", "Currency", "Percentage":
+				dataType = DataTypeDecimal
+			}
+		}
+		if dataType == "" {
+			continue
+		}
+
+		value, errs := coerceField("filter."+filter.Column, filter.Value, strings.TrimSuffix(dataType, "[]"))
+		if len(errs) > 0 {
+			fields = append(fields, errs...)
+			continue
+		}
+		filter.Value = normalizeDecimal(value)
+	}
+
+	if len(fields) > 0 {
+		return &CoercionError{Fields: fields}
+	}
+	return nil
+}
+
+// normalizeDecimal turns integral decimals into int64 so they compare exactly
+func normalizeDecimal(value any) any {
+	if s, ok := value.(string); ok {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	}
+	return value
+}
+
+// BuildPagedSQL wraps a query as a derived table and applies the filters, search,
+// sorting and paging of a page request. Only columns the DataTable declares
+// sortable or filterable may be used; any other column is rejected with
+// ErrInvalidPageRequest, as are tables bound to a ResultSet. The effective page and
+// page size are stored back into page.
+func BuildPagedSQL(sqlStr string, table *DataTable, page *PageRequest, driverName string) (*PagedSQL, error) {
+	if table.ResultSet != "" {
+		return nil, fmt.Errorf("%w: DataTable %s shows result set %s, which cannot be paged on the server", ErrInvalidPageRequest, table.Id, table.ResultSet)
+	}
+	dialect := DialectFor(driverName)
+	inner := dialect.DerivedTable(strings.TrimRight(strings.TrimSpace(sqlStr), "; \t\r\n"))
+	from := " FROM (\n" + inner + "\n) AS xf_page"
+
+	params := make(map[string]any)
+	var conditions []string
+
+	for i, filter := range page.Filters {
+		col := table.GetColumn(filter.Column)
+		if col == nil || !table.ColumnFilterable(col) {
+			return nil, fmt.Errorf("%w: column %q is not filterable", ErrInvalidPageRequest, filter.Column)
+		}
+		name := fmt.Sprintf("xf_filter_%d", i+1)
+		condition, err := filterCondition(dialect.QuoteIdentifier(col.Name), filter, name, params, dialect)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	if page.Search != "" {
+		if table.Searchable == nil || !*table.Searchable {
+			return nil, fmt.Errorf("%w: DataTable %s is not searchable", ErrInvalidPageRequest, table.Id)
+		}
+		var terms []string
+		for _, col := range table.Columns {
+			if table.ColumnFilterable(col) {
+				terms = append(terms, dialect.Like(dialect.QuoteIdentifier(col.Name), "xf_search"))
+			}
+		}
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("%w: DataTable %s has no filterable columns to search", ErrInvalidPageRequest, table.Id)
+		}
+		params["xf_search"] = "%" + dialect.EscapeLike(page.Search) + "%"
+		conditions = append(conditions, "("+strings.Join(terms, " OR ")+")")
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "\nWHERE " + strings.Join(conditions, "\n  AND ")
+	}
+
+	var orderTerms []string
+	for _, sort := range page.Sort {
+		col := table.GetColumn(sort.Column)
+		if col == nil || !table.ColumnSortable(col) {
+			return nil, fmt.Errorf("%w: column %q is not sortable", ErrInvalidPageRequest, sort.Column)
+		}
+		term := dialect.QuoteIdentifier(col.Name)
+		if sort.Desc {
+			term += " DESC"
+		}
+		orderTerms = append(orderTerms, term)
+	}
+
+	// The order of the derived table does not carry over to the outer query, so the
+	// order of the query and the DefaultSort of the table follow the requested sort
+	sorted := make(map[string]bool, len(page.Sort))
+	for _, sort := range page.Sort {
+		sorted[strings.ToLower(sort.Column)] = true
+	}
+	for _, sort := range append(queryOrder(sqlStr, table), table.DefaultSortFields()...) {
+		if sorted[strings.ToLower(sort.Column)] {
+			continue
+		}
+		sorted[strings.ToLower(sort.Column)] = true
+		term := dialect.QuoteIdentifier(sort.Column)
+		if sort.Desc {
+			term += " DESC"
+		}
+		orderTerms = append(orderTerms, term)
+	}
+
+	orderBy := ""
+	if len(orderTerms) > 0 {
+		orderBy = "\nORDER BY " + strings.Join(orderTerms, ", ")
+	}
+
+	if page.Paged() {
+		if orderBy == "" {
+			return nil, fmt.Errorf("%w: DataTable %s needs a sort, a DefaultSort or an ORDER BY in its query to be paged", ErrInvalidPageRequest, table.Id)
+		}
+		pageNumber, pageSize := page.Page, page.PageSize
+		if pageNumber == 0 {
+			pageNumber = 1
+		}
+		if pageSize == 0 {
+			pageSize = DefaultPageSize
+			if table.PageSize != nil {
+				pageSize = *table.PageSize
+			}
+		}
+		if pageSize > MaxPageSize {
+			return nil, fmt.Errorf("%w: pageSize must not exceed %d", ErrInvalidPageRequest, MaxPageSize)
+		}
+		page.Page, page.PageSize = pageNumber, pageSize
+		params["xf_offset"] = int64((pageNumber - 1) * pageSize)
+		params["xf_limit"] = int64(pageSize)
+		orderBy = dialect.Paging(orderBy)
+	}
+
+	return &PagedSQL{
+		DataSQL:  "SELECT *" + from + where + orderBy,
+		CountSQL: "SELECT COUNT(*) AS total_count" + from + where,
+		Params:   params,
+	}, nil
+}
+
+// PagedQuery returns a copy of query whose SQL applies the filters, search, sorting
+// and paging of a page request and the Optional fragments of params, together with
+// params extended by the page parameters.
+// It lets callers such as exports stream a DataTable the way it is displayed.
+func PagedQuery(query *Query, table *DataTable, page *PageRequest, params map[string]any, driverName string) (*Query, map[string]any, error) {
+	query = query.ResolveOptional(params)
+	paged, err := BuildPagedSQL(query.SQL, table, page, driverName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pagedQuery := *query
+	pagedQuery.SQL = paged.DataSQL
+
+	allParams := make(map[string]any, len(params)+len(paged.Params))
+	for name, value := range params {
+		allParams[name] = value
+	}
+	for name, value := range paged.Params {
+		allParams[name] = value
+	}
+	return &pagedQuery, allParams, nil
+}
+
+// filterCondition builds the SQL condition of a single filter
+func filterCondition(column string, filter Filter, name string, params map[string]any, dialect Dialect) (string, error) {
+	if op, ok := comparisonOperators[filter.Operator]; ok {
+		if filter.Value == nil {
+			switch filter.Operator {
+			case FilterEquals:
+				return column + " IS NULL", nil
+			case FilterNotEquals:
+				return column + " IS NOT NULL", nil
+			}
+			return "", fmt.Errorf("%w: operator %s needs a value", ErrInvalidPageRequest, filter.Operator)
+		}
+		params[name] = filter.Value
+		return fmt.Sprintf("%s %s :%s", column, op, name), nil
+	}
+
+	pattern := dialect.EscapeLike(fmt.Sprint(filter.Value))
+	switch filter.Operator {
+	case FilterContains:
+		pattern = "%" + pattern + "%"
+	case FilterStartsWith:
+		pattern = pattern + "%"
+	case FilterEndsWith:
+		pattern = "%" + pattern
+	default:
+		return "", fmt.Errorf("%w: unknown filter operator %q", ErrInvalidPageRequest, filter.Operator)
+	}
+	params[name] = pattern
+	return dialect.Like(column, name), nil
+}
+
+func isComparison(operator string) bool {
+	_, ok := comparisonOperators[operator]
+	return ok
+}
+
+// DefaultSortFields parses the DefaultSort of the table, a comma-separated list of
+// columns that are sorted descending when prefixed with -
+func (dt *DataTable) DefaultSortFields() []SortField {
+	var fields []SortField
+	for _, column := range strings.Split(dt.DefaultSort, ",") {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			continue
+		}
+		fields = append(fields, SortField{Column: strings.TrimPrefix(column, "-"), Desc: strings.HasPrefix(column, "-")})
+	}
+	return fields
+}
+
+// sqlIdentifier matches a plain, bracketed, double-quoted or backquoted identifier
+const sqlIdentifier = `\[[^\]]+\]|"[^"]+"|` + "`[^`]+`" + `|[A-Za-z_][A-Za-z0-9_]*`
+
+// orderColumnPattern matches an optionally qualified column and captures its name
+var orderColumnPattern = regexp.MustCompile(`^(?:(?:` + sqlIdentifier + `)\s*\.\s*)*(` + sqlIdentifier + `)$`)
+
+// queryOrder returns the leading terms of the top-level ORDER BY clause of a statement
+// that name a column of the table, with any table qualifier removed. Terms after the
+// first expression or undeclared column are dropped, as the outer query cannot refer
+// to them.
+func queryOrder(sqlStr string, table *DataTable) []SortField {
+	start := topLevelKeyword(sqlStr, "ORDER")
+	if start < 0 {
+		return nil
+	}
+	clause := strings.TrimSpace(sqlStr[start+len("ORDER"):])
+	if len(clause) < 2 || !strings.EqualFold(clause[:2], "BY") {
+		return nil
+	}
+	clause = clause[2:]
+	if end := topLevelKeyword(clause, "OFFSET", "LIMIT", "FETCH", "FOR", "OPTION"); end >= 0 {
+		clause = clause[:end]
+	}
+
+	var fields []SortField
+	for _, term := range splitTopLevel(strings.TrimRight(strings.TrimSpace(clause), "; \t\r\n")) {
+		words := strings.Fields(term)
+		desc := false
+		if n := len(words); n > 2 && strings.EqualFold(words[n-2], "NULLS") {
+			words = words[:n-2]
+		}
+		if n := len(words); n > 1 && (strings.EqualFold(words[n-1], "ASC") || strings.EqualFold(words[n-1], "DESC")) {
+			desc = strings.EqualFold(words[n-1], "DESC")
+			words = words[:n-1]
+		}
+		match := orderColumnPattern.FindStringSubmatch(strings.Join(words, " "))
+		if match == nil {
+			break
+		}
+		name := match[1]
+		if strings.ContainsAny(name[:1], "[\"`") {
+			name = name[1 : len(name)-1]
+		}
+		col := table.GetColumn(name)
+		if col == nil {
+			break
+		}
+		fields = append(fields, SortField{Column: col.Name, Desc: desc})
+	}
+	return fields
+}
+
+// splitTopLevel splits a list at the commas outside parentheses, string literals and
+// quoted identifiers
+func splitTopLevel(list string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var closing byte
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case closing != 0:
+			if c == closing {
+				closing = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			closing = c
+		case c == '[':
+			closing = ']'
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, list[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, list[start:])
+}
+
+// hasTopLevelKeyword reports whether a keyword occurs in the statement outside
+// parentheses, string literals, quoted identifiers and comments
+func hasTopLevelKeyword(sqlStr, keyword string) bool {
+	return topLevelKeyword(sqlStr, keyword) >= 0
+}
+
+// topLevelKeyword returns the byte offset of the first of the keywords that occurs in
+// the statement outside parentheses, string literals, quoted identifiers and comments,
+// or -1 if none does
+func topLevelKeyword(sqlStr string, keywords ...string) int {
+	runes := []rune(sqlStr)
+	depth := 0
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'' || r == '"' || r == '[' || r == '`':
+			closing := r
+			if r == '[' {
+				closing = ']'
+			}
+			for i++; i < len(runes) && runes[i] != closing; i++ {
+			}
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			for i += 2; i+1 < len(runes) && !(runes[i] == '*' && runes[i+1] == '/'); i++ {
+			}
+			i++
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '_') {
+				j++
+			}
+			if depth == 0 {
+				for _, keyword := range keywords {
+					if strings.EqualFold(string(runes[i:j]), keyword) {
+						return len(string(runes[:i]))
+					}
+				}
+			}
+			i = j - 1
+		}
+	}
+	return -1
+}
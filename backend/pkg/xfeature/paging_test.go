@@ -0,0 +1,289 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func intPtr(n int) *int { return &n }
+
+// pagingTestTable declares username and status sortable, status and email filterable
+func pagingTestTable() *DataTable {
+	return &DataTable{
+		Id:         "UsersTable",
+		QueryRef:   "ListUsers",
+		PageSize:   intPtr(2),
+		Sortable:   boolPtr(true),
+		Searchable: boolPtr(true),
+		Columns: []*Column{
+			{Name: "user_id", Type: "Number", Sortable: boolPtr(false), Filterable: boolPtr(true)},
+			{Name: "username"},
+			{Name: "email", Filterable: boolPtr(true)},
+			{Name: "status", Filterable: boolPtr(true)},
+		},
+	}
+}
+
+// TestBuildPagedSQLServer tests the statements generated for SQL Server
+func TestBuildPagedSQLServer(t *testing.T) {
+	page := &PageRequest{
+		Page:    3,
+		Sort:    []SortField{{Column: "status", Desc: true}, {Column: "username"}},
+		Filters: []Filter{{Column: "status", Operator: FilterEquals, Value: "active"}},
+		Search:  "50%_off",
+	}
+
+	paged, err := BuildPagedSQL("SELECT * FROM users ORDER BY created_at;", pagingTestTable(), page, "sqlserver")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	from := " FROM (\nSELECT * FROM users ORDER BY created_at OFFSET 0 ROWS\n) AS xf_page" +
+		"\nWHERE [status] = :xf_filter_1" +
+		"\n  AND ([user_id] LIKE :xf_search ESCAPE '\\' OR [email] LIKE :xf_search ESCAPE '\\' OR [status] LIKE :xf_search ESCAPE '\\')"
+	expectedData := "SELECT *" + from + "\nORDER BY [status] DESC, [username]\nOFFSET :xf_offset ROWS FETCH NEXT :xf_limit ROWS ONLY"
+	if paged.DataSQL != expectedData {
+		t.Errorf("Expected data SQL:\n%s\nGot:\n%s", expectedData, paged.DataSQL)
+	}
+	if expectedCount := "SELECT COUNT(*) AS total_count" + from; paged.CountSQL != expectedCount {
+		t.Errorf("Expected count SQL:\n%s\nGot:\n%s", expectedCount, paged.CountSQL)
+	}
+
+	expectedParams := map[string]any{
+		"xf_filter_1": "active",
+		"xf_search":   `%50\%\_off%`,
+		"xf_offset":   int64(4),
+		"xf_limit":    int64(2),
+	}
+	if !reflect.DeepEqual(paged.Params, expectedParams) {
+		t.Errorf("Expected params %v, got %v", expectedParams, paged.Params)
+	}
+	if page.Page != 3 || page.PageSize != 2 {
+		t.Errorf("Expected effective page 3 of size 2, got %d of size %d", page.Page, page.PageSize)
+	}
+
+	// The declared columns of the ORDER BY of the query order the outer query
+	paged, err = BuildPagedSQL("SELECT TOP 10 * FROM users u ORDER BY u.[username] DESC, created_at", pagingTestTable(), &PageRequest{Page: 1}, "sqlserver")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedData = "SELECT * FROM (\nSELECT TOP 10 * FROM users u ORDER BY u.[username] DESC, created_at\n) AS xf_page" +
+		"\nORDER BY [username] DESC\nOFFSET :xf_offset ROWS FETCH NEXT :xf_limit ROWS ONLY"
+	if paged.DataSQL != expectedData {
+		t.Errorf("Expected data SQL:\n%s\nGot:\n%s", expectedData, paged.DataSQL)
+	}
+}
+
+// TestBuildPagedSQLOrder tests that pages follow the requested sort with the order of
+// the query and the DefaultSort of the table, and that unordered pages are rejected
+func TestBuildPagedSQLOrder(t *testing.T) {
+	table := pagingTestTable()
+	table.DefaultSort = "-user_id"
+	tests := []struct {
+		name     string
+		sql      string
+		sort     []SortField
+		expected string
+	}{
+		{"DefaultSort only", "SELECT * FROM users", nil, "\nORDER BY [user_id] DESC"},
+		{"Query order", "SELECT * FROM users ORDER BY status, email DESC OFFSET 0 ROWS", nil, "\nORDER BY [status], [email] DESC, [user_id] DESC"},
+		{"Requested sort first", "SELECT * FROM users ORDER BY \"status\" ASC NULLS LAST", []SortField{{Column: "username"}}, "\nORDER BY [username], [status], [user_id] DESC"},
+		{"Requested column not repeated", "SELECT * FROM users ORDER BY username DESC", []SortField{{Column: "username"}}, "\nORDER BY [username], [user_id] DESC"},
+		{"Expression stops the query order", "SELECT * FROM users ORDER BY LOWER(email), status", nil, "\nORDER BY [user_id] DESC"},
+		{"Nested order ignored", "SELECT * FROM (SELECT TOP 5 * FROM users ORDER BY status) u", nil, "\nORDER BY [user_id] DESC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paged, err := BuildPagedSQL(tt.sql, table, &PageRequest{Page: 1, Sort: tt.sort}, "sqlserver")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected := tt.expected + "\nOFFSET :xf_offset ROWS FETCH NEXT :xf_limit ROWS ONLY"
+			if !strings.HasSuffix(paged.DataSQL, ") AS xf_page"+expected) {
+				t.Errorf("Expected data SQL to end with %q, got:\n%s", expected, paged.DataSQL)
+			}
+		})
+	}
+
+	table.DefaultSort = ""
+	if _, err := BuildPagedSQL("SELECT * FROM users ORDER BY created_at", table, &PageRequest{Page: 1}, "sqlserver"); !errors.Is(err, ErrInvalidPageRequest) {
+		t.Errorf("Expected unordered paging to fail with ErrInvalidPageRequest, got %v", err)
+	}
+	if _, err := BuildPagedSQL("SELECT * FROM users", table, &PageRequest{Search: "x"}, "sqlserver"); err != nil {
+		t.Errorf("Expected an unpaged request without order to succeed, got %v", err)
+	}
+}
+
+// TestBuildPagedSQLRejectsUndeclaredColumns tests that only declared columns are accepted
+func TestBuildPagedSQLRejectsUndeclaredColumns(t *testing.T) {
+	table := pagingTestTable()
+	tests := []struct {
+		name string
+		page *PageRequest
+	}{
+		{"Sort on non-sortable column", &PageRequest{Sort: []SortField{{Column: "user_id"}}}},
+		{"Sort on unknown column", &PageRequest{Sort: []SortField{{Column: "password_hash"}}}},
+		{"Sort injection", &PageRequest{Sort: []SortField{{Column: "username; DROP TABLE users"}}}},
+		{"Filter on non-filterable column", &PageRequest{Filters: []Filter{{Column: "username", Operator: FilterEquals, Value: "x"}}}},
+		{"Unknown operator", &PageRequest{Filters: []Filter{{Column: "status", Operator: "like", Value: "x"}}}},
+		{"Page size too large", &PageRequest{Page: 1, PageSize: MaxPageSize + 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := BuildPagedSQL("SELECT * FROM users", table, tt.page, "sqlite3")
+			if !errors.Is(err, ErrInvalidPageRequest) {
+				t.Errorf("Expected ErrInvalidPageRequest, got %v", err)
+			}
+		})
+	}
+
+	table.Searchable = nil
+	if _, err := BuildPagedSQL("SELECT * FROM users", table, &PageRequest{Search: "x"}, "sqlite3"); !errors.Is(err, ErrInvalidPageRequest) {
+		t.Errorf("Expected search on a non-searchable table to fail, got %v", err)
+	}
+}
+
+// TestParsePageRequest tests reading page request arguments from a query string
+func TestParsePageRequest(t *testing.T) {
+	values, _ := url.ParseQuery("page=2&pageSize=10&sort=status,-username&filter=status:active&filter=email:contains:a:b&search=+x+&user_id=5")
+	page, err := ParsePageRequest(values, pageRequestKeys)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := &PageRequest{
+		Page:     2,
+		PageSize: 10,
+		Sort:     []SortField{{Column: "status"}, {Column: "username", Desc: true}},
+		Filters: []Filter{
+			{Column: "status", Operator: FilterEquals, Value: "active"},
+			{Column: "email", Operator: FilterContains, Value: "a:b"},
+		},
+		Search: "x",
+	}
+	if !reflect.DeepEqual(page, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, page)
+	}
+
+	if page, err := ParsePageRequest(url.Values{"user_id": {"5"}}, pageRequestKeys); page != nil || err != nil {
+		t.Errorf("Expected no page request, got %+v, %v", page, err)
+	}
+	if _, err := ParsePageRequest(url.Values{"page": {"0"}}, pageRequestKeys); !errors.Is(err, ErrInvalidPageRequest) {
+		t.Errorf("Expected ErrInvalidPageRequest for page 0, got %v", err)
+	}
+}
+
+// TestPageRequestKeys tests that page request keys are reserved only for queries of
+// server-side DataTables and never hide a parameter of the query
+func TestPageRequestKeys(t *testing.T) {
+	sortable := true
+	xf := NewXFeature(testLogger)
+	xf.Frontend.DataTables = []*DataTable{
+		{Id: "UsersTable", QueryRef: "ListUsers", Sortable: &sortable},
+		{Id: "PlainTable", QueryRef: "ListRoles"},
+	}
+	searchSQL := "SELECT * FROM users WHERE username LIKE :search"
+	search := &Query{Id: "SearchUsers", SQL: searchSQL, Parameters: ExtractParameters(searchSQL)}
+
+	if keys := xf.PageRequestKeys(search); keys != nil {
+		t.Errorf("Expected no page request keys for an unbound query, got %v", keys)
+	}
+	values, _ := url.ParseQuery("search=smith")
+	if page, err := ParsePageRequest(values, xf.PageRequestKeys(search)); page != nil || err != nil {
+		t.Errorf("Expected search to be left to the :search parameter, got %+v, %v", page, err)
+	}
+	if keys := xf.PageRequestKeys(&Query{Id: "ListRoles"}); keys != nil {
+		t.Errorf("Expected no page request keys for a table without server-side paging, got %v", keys)
+	}
+
+	search.Id = "ListUsers"
+	expected := []string{"page", "pageSize", "sort", "filter"}
+	if keys := xf.PageRequestKeys(search); !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected page request keys %v, got %v", expected, keys)
+	}
+}
+
+// TestExecutePage tests paging, sorting, filtering and counting against SQLite
+func TestExecutePage(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	for i := 1; i <= 5; i++ {
+		status := "active"
+		if i%2 == 0 {
+			status = "inactive"
+		}
+		_, err := db.Exec("INSERT INTO users (username, email, status) VALUES (?, ?, ?)",
+			fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i), status)
+		if err != nil {
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	query := &Query{Id: "ListUsers", SQL: "SELECT user_id, username, email, status FROM users WHERE username <> :excluded"}
+	executor := NewQueryExecutor(testLogger)
+	ctx := context.Background()
+	params := map[string]any{"excluded": "user5"}
+
+	result, err := executor.ExecutePage(ctx, db, query, pagingTestTable(), params, &PageRequest{
+		Page:    2,
+		Sort:    []SortField{{Column: "username", Desc: true}},
+		Filters: []Filter{{Column: "status", Operator: FilterEquals, Value: "active"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.TotalCount != 2 || len(result.Rows) != 0 {
+		t.Errorf("Expected an empty second page of 2 rows, got %d rows of %d", len(result.Rows), result.TotalCount)
+	}
+
+	result, err = executor.ExecutePage(ctx, db, query, pagingTestTable(), params, &PageRequest{
+		Page: 1,
+		Sort: []SortField{{Column: "username", Desc: true}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.TotalCount != 4 || result.PageSize != 2 || len(result.Rows) != 2 {
+		t.Fatalf("Expected 2 rows of 4, got %d of %d", len(result.Rows), result.TotalCount)
+	}
+	if result.Rows[0]["username"] != "user4" || result.Rows[1]["username"] != "user3" {
+		t.Errorf("Expected user4 and user3, got %v and %v", result.Rows[0]["username"], result.Rows[1]["username"])
+	}
+
+	// Numeric filter values are coerced by column type
+	xf := NewXFeature(testLogger)
+	page := &PageRequest{
+		Filters: []Filter{{Column: "user_id", Operator: FilterGreater, Value: "2"}},
+		Search:  "user_",
+	}
+	table := pagingTestTable()
+	if err := xf.CoercePageRequest(table, page); err != nil {
+		t.Fatalf("Unexpected coercion error: %v", err)
+	}
+	result, err = executor.ExecutePage(ctx, db, query, table, params, page)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.TotalCount != 0 {
+		t.Errorf("Expected the escaped underscore to match nothing, got %d rows", result.TotalCount)
+	}
+
+	page.Search = "example"
+	result, err = executor.ExecutePage(ctx, db, query, table, params, page)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.TotalCount != 2 || len(result.Rows) != 2 {
+		t.Errorf("Expected users 3 and 4, got %d rows of %d", len(result.Rows), result.TotalCount)
+	}
+}
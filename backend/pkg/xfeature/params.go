@@ -0,0 +1,166 @@
+package xfeature
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ErrListTooLarge is returned when an array parameter has more values than allowed, or
+// the expanded lists exceed the number of arguments the database accepts
+var ErrListTooLarge = errors.New("list parameter has too many values")
+
+// ErrEmptyNotInList is returned when an empty array is used after NOT IN
+var ErrEmptyNotInList = errors.New("empty list parameter after NOT IN")
+
+// notInRegex matches the text in front of a placeholder written as NOT IN (:param)
+var notInRegex = regexp.MustCompile(`(?i)\bNOT\s+IN\s*\(\s*$`)
+
+// ParameterLimitDialect is implemented by dialects whose databases limit the number of
+// arguments a single statement may bind
+type ParameterLimitDialect interface {
+	MaxParameters() int
+}
+
+// MaxParameters is 2098: SQL Server accepts 2100 parameters per request and sp_executesql,
+// which the driver runs parameterized statements with, takes two of them
+func (SQLServerDialect) MaxParameters() int { return 2098 }
+
+// MaxParameters is the default SQLITE_MAX_VARIABLE_NUMBER of SQLite 3.32 and later
+func (SQLiteDialect) MaxParameters() int { return 32766 }
+
+// MaxParameters is the limit of the PostgreSQL wire protocol
+func (PostgresDialect) MaxParameters() int { return 65535 }
+
+// MaxParameters is the limit of prepared statements in MySQL
+func (MySQLDialect) MaxParameters() int { return 65535 }
+
+var parameterRegex = regexp.MustCompile(`:\w+`)
+
+// replaceParameters replaces every :param placeholder of a statement with the result of
+// replace. The :: cast operator of PostgreSQL, as in amount::numeric, is left unchanged.
+func replaceParameters(sqlStr string, replace func(match string) string) string {
+	return replaceParametersAt(sqlStr, func(match string, _ int) string { return replace(match) })
+}
+
+// replaceParametersAt is replaceParameters passing the offset of each placeholder
+func replaceParametersAt(sqlStr string, replace func(match string, start int) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range parameterRegex.FindAllStringIndex(sqlStr, -1) {
+		if loc[0] > 0 && sqlStr[loc[0]-1] == ':' {
+			continue
+		}
+		b.WriteString(sqlStr[last:loc[0]])
+		b.WriteString(replace(sqlStr[loc[0]:loc[1]], loc[0]))
+		last = loc[1]
+	}
+	b.WriteString(sqlStr[last:])
+	return b.String()
+}
+
+// BindParameters converts SQL written with :param placeholders for the driver and
+// returns the statement together with the arguments to execute it with, as
+// defined by the Dialect of the driver.
+// Values are always sent to the driver as parameters and never written into the
+// statement text, so parameter values cannot change the statement.
+//
+// Array-valued parameters are expanded into one placeholder per value, so
+// IN (:ids) becomes IN (:ids__1, :ids__2, ...). An empty array becomes NULL,
+// which matches no rows; as NOT IN (NULL) matches no rows either instead of all,
+// empty arrays after NOT IN are rejected with ErrEmptyNotInList. Arrays longer than
+// maxListSize are rejected; a maxListSize of 0 or less disables the limit. Statements
+// binding more arguments than the database of the dialect accepts are rejected as well.
+func BindParameters(sqlStr string, params map[string]any, driverName string, maxListSize int) (string, []any, error) {
+	sqlStr, params, err := expandListParameters(sqlStr, params, maxListSize)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dialect := DialectFor(driverName)
+	sqlStr, args := dialect.Bind(sqlStr, params)
+	if limited, ok := dialect.(ParameterLimitDialect); ok && len(args) > limited.MaxParameters() {
+		return "", nil, fmt.Errorf("%w: the statement binds %d arguments, %s accepts at most %d",
+			ErrListTooLarge, len(args), dialect.Name(), limited.MaxParameters())
+	}
+	return sqlStr, args, nil
+}
+
+// expandListParameters rewrites every array-valued parameter into a list of scalar
+// parameters and returns the rewritten statement with the matching parameter map
+func expandListParameters(sqlStr string, params map[string]any, maxListSize int) (string, map[string]any, error) {
+	lists := make(map[string][]any)
+	for name, value := range params {
+		values, ok := listValues(value)
+		if !ok {
+			continue
+		}
+		if maxListSize > 0 && len(values) > maxListSize {
+			return "", nil, fmt.Errorf("%w: %s has %d values, the maximum is %d", ErrListTooLarge, name, len(values), maxListSize)
+		}
+		lists[name] = values
+	}
+	if len(lists) == 0 {
+		return sqlStr, params, nil
+	}
+
+	expanded := make(map[string]any, len(params))
+	for name, value := range params {
+		if _, ok := lists[name]; !ok {
+			expanded[name] = value
+		}
+	}
+
+	var emptyNotIn string
+	sqlStr = replaceParametersAt(sqlStr, func(match string, start int) string {
+		name := strings.TrimPrefix(match, ":")
+		values, ok := lists[name]
+		if !ok {
+			return match
+		}
+		if len(values) == 0 {
+			if notInRegex.MatchString(sqlStr[:start]) {
+				emptyNotIn = name
+			}
+			return "NULL"
+		}
+		placeholders := make([]string, len(values))
+		for i, value := range values {
+			itemName := fmt.Sprintf("%s__%d", name, i+1)
+			expanded[itemName] = value
+			placeholders[i] = ":" + itemName
+		}
+		return strings.Join(placeholders, ", ")
+	})
+	if emptyNotIn != "" {
+		return "", nil, fmt.Errorf("%w: %s", ErrEmptyNotInList, emptyNotIn)
+	}
+
+	return sqlStr, expanded, nil
+}
+
+// listValues returns the elements of slice and array values; byte slices are
+// treated as a single binary value
+func listValues(value any) ([]any, bool) {
+	if value == nil {
+		return nil, false
+	}
+	if _, ok := value.([]byte); ok {
+		return nil, false
+	}
+	if values, ok := value.([]any); ok {
+		return values, true
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values, true
+}
@@ -0,0 +1,295 @@
+package xfeature
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// hostileValues are inputs that changed the statement when values were quoted into the SQL text
+var hostileValues = []string{
+	"'; DROP TABLE users; --",
+	"' OR '1'='1",
+	"x' UNION SELECT username, email FROM users --",
+	"@username",
+	":username",
+	"O'Brien",
+	"\\'; DELETE FROM users; --",
+}
+
+// TestBindParametersSQLServer tests that SQL Server statements carry values as named arguments
+func TestBindParametersSQLServer(t *testing.T) {
+	query := "SELECT * FROM users WHERE status = :status AND (role = :role OR :role IS NULL) AND id > :min_id"
+
+	for _, value := range hostileValues {
+		params := map[string]any{
+			"role":   nil,
+			"status": value,
+			"min_id": 42,
+			"unused": "ignored",
+		}
+
+		sqlStr, args, err := BindParameters(query, params, "sqlserver", 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expectedSQL := "SELECT * FROM users WHERE status = @status AND (role = @role OR @role IS NULL) AND id > @min_id"
+		if sqlStr != expectedSQL {
+			t.Fatalf("Statement changed for value %q:\n%s", value, sqlStr)
+		}
+
+		expected := []sql.NamedArg{
+			sql.Named("status", value),
+			sql.Named("role", nil),
+			sql.Named("min_id", 42),
+		}
+		if len(args) != len(expected) {
+			t.Fatalf("Expected %d args, got %d: %v", len(expected), len(args), args)
+		}
+		for i, exp := range expected {
+			arg, ok := args[i].(sql.NamedArg)
+			if !ok {
+				t.Fatalf("Expected sql.NamedArg at %d, got %T", i, args[i])
+			}
+			if arg.Name != exp.Name || arg.Value != exp.Value {
+				t.Errorf("Expected arg %d to be %s=%v, got %s=%v", i, exp.Name, exp.Value, arg.Name, arg.Value)
+			}
+		}
+	}
+}
+
+// TestBindParametersGeneric tests positional arguments for drivers without named binding
+func TestBindParametersGeneric(t *testing.T) {
+	sqlStr, args, err := BindParameters("SELECT :b, :a, :b", map[string]any{"a": 1, "b": "two"}, "odbc", 0)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if sqlStr != "SELECT :b, :a, :b" {
+		t.Errorf("Expected statement to be unchanged, got %s", sqlStr)
+	}
+	if len(args) != 2 || args[0] != "two" || args[1] != 1 {
+		t.Errorf("Expected args [two 1], got %v", args)
+	}
+}
+
+// TestHostileInputsQuery tests that hostile values are matched literally by a query
+func TestHostileInputsQuery(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	if _, err := db.Exec("INSERT INTO users (username, email) VALUES ('john', 'john@example.com')"); err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	query := &Query{
+		Id:  "FindUser",
+		SQL: "SELECT username, email FROM users WHERE username = :username OR email = :username",
+	}
+	executor := NewQueryExecutor(testLogger)
+	ctx := context.Background()
+
+	for _, value := range hostileValues {
+		results, err := executor.Execute(ctx, db, query, map[string]any{"username": value})
+		if err != nil {
+			t.Fatalf("Query failed for value %q: %v", value, err)
+		}
+		if len(results) != 0 {
+			t.Errorf("Expected no rows for value %q, got %d", value, len(results))
+		}
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatalf("Users table is no longer usable: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 user, got %d", count)
+	}
+}
+
+// TestHostileInputsAction tests that hostile values are stored verbatim and nil becomes NULL
+func TestHostileInputsAction(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	action := &ActionQuery{
+		Id:   "CreateUser",
+		Type: "Insert",
+		SQL:  "INSERT INTO users (username, email, first_name) VALUES (:username, :email, :first_name)",
+	}
+	executor := NewActionExecutor(testLogger)
+	ctx := context.Background()
+
+	for i, value := range hostileValues {
+		params := map[string]any{
+			"first_name": nil,
+			"email":      value,
+			"username":   string(rune('a' + i)),
+		}
+		if _, err := executor.Execute(ctx, db, action, params); err != nil {
+			t.Fatalf("Action failed for value %q: %v", value, err)
+		}
+
+		var email string
+		var firstName sql.NullString
+		err := db.QueryRow("SELECT email, first_name FROM users WHERE username = ?", params["username"]).Scan(&email, &firstName)
+		if err != nil {
+			t.Fatalf("Failed to read back value %q: %v", value, err)
+		}
+		if email != value {
+			t.Errorf("Expected email %q, got %q", value, email)
+		}
+		if firstName.Valid {
+			t.Errorf("Expected first_name to be NULL, got %q", firstName.String)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatalf("Users table is no longer usable: %v", err)
+	}
+	if count != len(hostileValues) {
+		t.Errorf("Expected %d users, got %d", len(hostileValues), count)
+	}
+}
+
+// TestBindParametersLists tests expansion of array parameters into placeholder lists
+func TestBindParametersLists(t *testing.T) {
+	query := "DELETE FROM users WHERE user_id IN (:user_ids) AND status <> :status OR user_id IN (:user_ids)"
+
+	sqlStr, args, err := BindParameters(query, map[string]any{
+		"user_ids": []any{int64(3), int64(1), int64(2)},
+		"status":   "locked",
+	}, "sqlserver", 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedSQL := "DELETE FROM users WHERE user_id IN (@user_ids__1, @user_ids__2, @user_ids__3) AND status <> @status" +
+		" OR user_id IN (@user_ids__1, @user_ids__2, @user_ids__3)"
+	if sqlStr != expectedSQL {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expectedSQL, sqlStr)
+	}
+
+	expected := []sql.NamedArg{
+		sql.Named("user_ids__1", int64(3)),
+		sql.Named("user_ids__2", int64(1)),
+		sql.Named("user_ids__3", int64(2)),
+		sql.Named("status", "locked"),
+	}
+	if len(args) != len(expected) {
+		t.Fatalf("Expected %d args, got %d: %v", len(expected), len(args), args)
+	}
+	for i, exp := range expected {
+		if arg := args[i].(sql.NamedArg); arg.Name != exp.Name || arg.Value != exp.Value {
+			t.Errorf("Expected arg %d to be %s=%v, got %s=%v", i, exp.Name, exp.Value, arg.Name, arg.Value)
+		}
+	}
+
+	// Typed slices expand as well, byte slices stay a single value
+	sqlStr, args, err = BindParameters("SELECT :names, :blob", map[string]any{
+		"names": []string{"a", "b"},
+		"blob":  []byte("raw"),
+	}, "sqlite3", 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sqlStr != "SELECT :names__1, :names__2, :blob" || len(args) != 3 {
+		t.Errorf("Unexpected binding: %s %v", sqlStr, args)
+	}
+
+	// Empty arrays match nothing
+	sqlStr, args, err = BindParameters("SELECT * FROM users WHERE user_id IN (:user_ids)", map[string]any{"user_ids": []any{}}, "sqlserver", 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sqlStr != "SELECT * FROM users WHERE user_id IN (NULL)" || len(args) != 0 {
+		t.Errorf("Unexpected binding for empty list: %s %v", sqlStr, args)
+	}
+
+	// Empty arrays after NOT IN would match nothing as well, so they are rejected
+	_, _, err = BindParameters("SELECT * FROM users WHERE user_id NOT IN ( :user_ids)", map[string]any{"user_ids": []any{}}, "sqlserver", 10)
+	if !errors.Is(err, ErrEmptyNotInList) {
+		t.Errorf("Expected ErrEmptyNotInList, got %v", err)
+	}
+
+	// Lists over the limit are rejected
+	_, _, err = BindParameters("SELECT :ids", map[string]any{"ids": []any{1, 2, 3}}, "sqlserver", 2)
+	if !errors.Is(err, ErrListTooLarge) {
+		t.Errorf("Expected ErrListTooLarge, got %v", err)
+	}
+
+	// Lists within the limit are rejected when together they exceed the arguments SQL Server accepts
+	ids := make([]any, 1500)
+	for i := range ids {
+		ids[i] = i
+	}
+	_, _, err = BindParameters("SELECT * FROM users WHERE user_id IN (:a) OR user_id IN (:b)", map[string]any{"a": ids, "b": ids}, "sqlserver", 2000)
+	if !errors.Is(err, ErrListTooLarge) {
+		t.Errorf("Expected ErrListTooLarge for 3000 arguments, got %v", err)
+	}
+	if _, _, err = BindParameters("SELECT * FROM users WHERE user_id IN (:a) OR user_id IN (:b)", map[string]any{"a": ids, "b": ids}, "sqlite3", 2000); err != nil {
+		t.Errorf("Expected SQLite to accept 3000 arguments, got %v", err)
+	}
+}
+
+// TestListParameterAction tests a bulk action with an IN list against SQLite
+func TestListParameterAction(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if _, err := db.Exec("INSERT INTO users (username, email) VALUES (?, ?)", name, name+"@example.com"); err != nil {
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	action := &ActionQuery{
+		Id:   "BulkDeleteUsers",
+		Type: "Delete",
+		SQL:  "DELETE FROM users WHERE username IN (:usernames)",
+	}
+	executor := NewActionExecutorWithOptions(testLogger, ExecutorOptions{MaxListSize: 3})
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		usernames []any
+		affected  int64
+		wantErr   error
+	}{
+		{"Empty list", []any{}, 0, nil},
+		{"Hostile values", []any{"a') OR ('1'='1", "x"}, 0, nil},
+		{"Two users", []any{"a", "c"}, 2, nil},
+		{"Too many", []any{"b", "d", "e", "f"}, 0, ErrListTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := executor.Execute(ctx, db, action, map[string]any{"usernames": tt.usernames})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if affected, _ := result.RowsAffected(); affected != tt.affected {
+				t.Errorf("Expected %d rows affected, got %d", tt.affected, affected)
+			}
+		})
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatalf("Failed to count users: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 remaining users, got %d", count)
+	}
+}
@@ -0,0 +1,146 @@
+package xfeature
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/taheri24/xpanel/backend/pkg/dbutil"
+)
+
+// ErrPlanUnsupported is returned when the driver of a query cannot show query plans
+var ErrPlanUnsupported = errors.New("query plan not supported")
+
+// PlanStatements read the estimated plan of a statement without running it. Setup and
+// Teardown, if set, switch the connection into and out of plan mode around Query.
+type PlanStatements struct {
+	Setup    string
+	Query    string
+	Teardown string
+	// XML reports that the plan is returned as a single XML document
+	XML bool
+}
+
+// PlanDialect is implemented by dialects that can show the estimated plan of a query
+type PlanDialect interface {
+	PlanStatements(sqlStr string) PlanStatements
+}
+
+// PlanStatements runs the query under SET SHOWPLAN_XML ON
+func (SQLServerDialect) PlanStatements(sqlStr string) PlanStatements {
+	return PlanStatements{Setup: "SET SHOWPLAN_XML ON", Query: sqlStr, Teardown: "SET SHOWPLAN_XML OFF", XML: true}
+}
+
+// PlanStatements prefixes the query with EXPLAIN QUERY PLAN
+func (SQLiteDialect) PlanStatements(sqlStr string) PlanStatements {
+	return PlanStatements{Query: "EXPLAIN QUERY PLAN " + sqlStr}
+}
+
+// PlanStatements prefixes the query with EXPLAIN
+func (PostgresDialect) PlanStatements(sqlStr string) PlanStatements {
+	return PlanStatements{Query: "EXPLAIN " + sqlStr}
+}
+
+// PlanStatements prefixes the query with EXPLAIN
+func (MySQLDialect) PlanStatements(sqlStr string) PlanStatements {
+	return PlanStatements{Query: "EXPLAIN " + sqlStr}
+}
+
+// QueryPlan is the estimated plan of a query together with the SQL and arguments it
+// was bound to
+type QueryPlan struct {
+	SQL  string           `json:"sql"`
+	Args []any            `json:"args"`
+	XML  string           `json:"xml,omitempty"`
+	Rows []map[string]any `json:"rows,omitempty"`
+}
+
+// Plan binds the parameters of a query the way Execute does and returns its estimated
+// plan instead of running it. Mock data sets and the cache are not used.
+func (qe *QueryExecutor) Plan(
+	ctx context.Context,
+	db *sqlx.DB,
+	query *Query,
+	params map[string]interface{},
+) (*QueryPlan, error) {
+	startTime := time.Now()
+	if query.IsProcedure() {
+		return nil, fmt.Errorf("%w: query %s has no plan", ErrProcedureUnsupported, query.Id)
+	}
+	query = query.ResolveOptional(params)
+
+	if err := qe.validateParameters(ExtractParameters(query.SQL), params); err != nil {
+		qe.logger.Error("Parameter validation failed", "queryId", query.Id, "error", err)
+		return nil, err
+	}
+
+	db, err := resolveDataSource(qe.dataSources, db, query.DataSource)
+	if err != nil {
+		qe.logger.Error("Data source not available", "queryId", query.Id, "dataSource", query.DataSource, "error", err)
+		return nil, err
+	}
+	dialect, ok := DialectFor(db.DriverName()).(PlanDialect)
+	if !ok {
+		return nil, fmt.Errorf("%w: driver %s", ErrPlanUnsupported, db.DriverName())
+	}
+
+	sql, args, err := BindParameters(query.SQL, params, db.DriverName(), qe.maxListSize)
+	if err != nil {
+		qe.logger.Error("Parameter binding failed", "queryId", query.Id, "error", err)
+		return nil, err
+	}
+	statements := dialect.PlanStatements(sql)
+
+	timeout := parseTimeout(query.Timeout, qe.queryTimeout)
+	execCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	// Plan mode is a setting of the connection, so all statements run on one
+	conn, err := db.Conn(execCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection: %w", err)
+	}
+	defer conn.Close()
+	if statements.Setup != "" {
+		if _, err := conn.ExecContext(execCtx, statements.Setup); err != nil {
+			return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to switch to plan mode: %w", err), query.Id, timeout)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), statements.Teardown); err != nil {
+				// Do not return a connection stuck in plan mode to the pool
+				qe.logger.Warn("Failed to leave plan mode, discarding the connection", "queryId", query.Id, "error", err)
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}()
+	}
+
+	rows, err := conn.QueryContext(execCtx, statements.Query, args...)
+	if err != nil {
+		qe.logger.Error("Query plan failed", "queryId", query.Id, "error", err)
+		return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to read the plan of query %s: %w", query.Id, err), query.Id, timeout)
+	}
+	defer rows.Close()
+	planRows, err := dbutil.RowsToMaps(rows)
+	if err != nil {
+		return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to read the plan of query %s: %w", query.Id, err), query.Id, timeout)
+	}
+
+	plan := &QueryPlan{SQL: sql, Args: args, Rows: planRows}
+	if statements.XML {
+		plan.Rows = nil
+		for _, row := range planRows {
+			for _, value := range row {
+				plan.XML += fmt.Sprint(value)
+			}
+		}
+	}
+
+	qe.logger.Debug("Query plan read",
+		"queryId", query.Id,
+		"duration_ms", time.Since(startTime).Milliseconds(),
+	)
+	return plan, nil
+}
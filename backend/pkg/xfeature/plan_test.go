@@ -0,0 +1,49 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestPlan tests that the plan of a query is read with the parameters bound like Execute binds them
+func TestPlan(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(optionalFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	query, _ := xf.GetQuery("SearchUsers")
+	executor := NewQueryExecutor(testLogger)
+
+	plan, err := executor.Plan(context.Background(), db, query, map[string]any{"status": "active", "keyword": "a%"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(plan.SQL, "username LIKE :keyword") || strings.Contains(plan.SQL, ":min_id") || len(plan.Args) != 2 {
+		t.Errorf("Expected the SQL with the supplied Optional fragment, got %q with %v", plan.SQL, plan.Args)
+	}
+	if len(plan.Rows) == 0 || !strings.Contains(fmt.Sprint(plan.Rows[0]["detail"]), "users") {
+		t.Errorf("Expected the plan to read the users table, got %v", plan.Rows)
+	}
+
+	if _, err := executor.Plan(context.Background(), db, query, nil); err == nil {
+		t.Errorf("Expected missing parameters to be rejected")
+	}
+	procedure := &Query{Id: "Totals", Type: "Procedure", SQL: "dbo.Totals"}
+	if _, err := executor.Plan(context.Background(), db, procedure, nil); !errors.Is(err, ErrProcedureUnsupported) {
+		t.Errorf("Expected ErrProcedureUnsupported, got %v", err)
+	}
+}
+
+// TestPlanStatements tests that SQL Server reads plans in SHOWPLAN_XML mode
+func TestPlanStatements(t *testing.T) {
+	statements := SQLServerDialect{}.PlanStatements("SELECT 1")
+	if statements.Setup != "SET SHOWPLAN_XML ON" || statements.Query != "SELECT 1" || statements.Teardown != "SET SHOWPLAN_XML OFF" || !statements.XML {
+		t.Errorf("Unexpected SQL Server plan statements: %+v", statements)
+	}
+}
@@ -0,0 +1,187 @@
+package xfeature
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ProcedureType is the Type of a Query or ActionQuery that executes a stored procedure.
+// The text content of the element is the procedure name and its Param elements declare
+// the parameters.
+const ProcedureType = "Procedure"
+
+// ReturnValueColumn holds the return code in the last result set of a procedure batch
+const ReturnValueColumn = "xf_return_value"
+
+// ErrProcedureUnsupported is returned for stored procedure calls the driver or the
+// operation cannot run
+var ErrProcedureUnsupported = errors.New("stored procedure not supported")
+
+// ProcParam declares a parameter of a stored procedure. Inputs are passed by name when
+// the request provides them, otherwise the default of the procedure applies. Output
+// parameters are declared with their SqlType, initialized with a provided value and
+// returned after execution.
+type ProcParam struct {
+	Name    string `xml:"Name,attr" json:"name"`
+	Output  bool   `xml:"Output,attr" json:"output"`
+	SqlType string `xml:"SqlType,attr" json:"sqlType,omitempty"`
+}
+
+// ProcedureResult holds the result rows, output parameters and return code of a
+// stored procedure call. It is also the format of procedure mock data sets.
+type ProcedureResult struct {
+	Rows []map[string]any `json:"rows"`
+	// ResultSets holds every result set when the procedure returns several; Rows is the first
+	ResultSets  []*NamedResultSet `json:"resultSets,omitempty"`
+	Outputs     map[string]any    `json:"outputs"`
+	ReturnValue int64             `json:"returnValue"`
+	// Truncated reports that Rows was cut off at the row limit
+	Truncated bool `json:"-"`
+}
+
+// ProcedureDialect is implemented by dialects that can call stored procedures
+type ProcedureDialect interface {
+	// ProcedureSQL returns a batch with :param placeholders that calls the procedure and
+	// returns its result rows followed by one row holding the return code as
+	// ReturnValueColumn and every output parameter by name
+	ProcedureSQL(name string, params []*ProcParam, provided map[string]any) (string, error)
+}
+
+// IsProcedure reports whether the query executes a stored procedure
+func (q *Query) IsProcedure() bool { return q.Type == ProcedureType }
+
+// IsProcedure reports whether the action executes a stored procedure
+func (a *ActionQuery) IsProcedure() bool { return a.Type == ProcedureType }
+
+// procedureParameters returns the names of the declared procedure parameters
+func procedureParameters(params []*ProcParam) []string {
+	names := make([]string, 0, len(params))
+	for _, param := range params {
+		names = append(names, param.Name)
+	}
+	return names
+}
+
+var (
+	procedureNameRegex = regexp.MustCompile(`^(\[[^\]]+\]|\w+)(\.(\[[^\]]+\]|\w+)){0,2}$`)
+	procParamNameRegex = regexp.MustCompile(`^\w+$`)
+	sqlTypeRegex       = regexp.MustCompile(`(?i)^[a-z]\w*(\s*\(\s*(\d+|max)\s*(,\s*\d+\s*)?\))?$`)
+)
+
+// ProcedureSQL declares the output parameters as variables, executes the procedure with
+// the provided inputs and selects the return code and the outputs:
+//
+//	DECLARE @xf_return_value int;
+//	DECLARE @xf_out_total decimal(18,2);
+//	EXEC @xf_return_value = dbo.usp_Totals @customer_id = :customer_id, @total = @xf_out_total OUTPUT;
+//	SELECT @xf_return_value AS [xf_return_value], @xf_out_total AS [total];
+func (d SQLServerDialect) ProcedureSQL(name string, params []*ProcParam, provided map[string]any) (string, error) {
+	if !procedureNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid procedure name %q", name)
+	}
+
+	var b strings.Builder
+	b.WriteString("DECLARE @xf_return_value int;\n")
+	var args []string
+	outputs := []string{"@xf_return_value AS " + d.QuoteIdentifier(ReturnValueColumn)}
+	for _, param := range params {
+		if !procParamNameRegex.MatchString(param.Name) {
+			return "", fmt.Errorf("invalid parameter name %q of procedure %s", param.Name, name)
+		}
+		_, ok := provided[param.Name]
+		if !param.Output {
+			if ok {
+				args = append(args, "@"+param.Name+" = :"+param.Name)
+			}
+			continue
+		}
+
+		if !sqlTypeRegex.MatchString(param.SqlType) {
+			return "", fmt.Errorf("output parameter %s of procedure %s needs a valid SqlType, got %q", param.Name, name, param.SqlType)
+		}
+		variable := "@xf_out_" + param.Name
+		b.WriteString("DECLARE " + variable + " " + param.SqlType)
+		if ok {
+			b.WriteString(" = :" + param.Name)
+		}
+		b.WriteString(";\n")
+		args = append(args, "@"+param.Name+" = "+variable+" OUTPUT")
+		outputs = append(outputs, variable+" AS "+d.QuoteIdentifier(param.Name))
+	}
+
+	b.WriteString("EXEC @xf_return_value = " + name)
+	if len(args) > 0 {
+		b.WriteString(" " + strings.Join(args, ", "))
+	}
+	b.WriteString(";\nSELECT " + strings.Join(outputs, ", ") + ";")
+	return b.String(), nil
+}
+
+// procedureStatement builds the batch of a procedure call for the driver of db and binds its parameters
+func procedureStatement(db sqlx.ExtContext, name string, params []*ProcParam, provided map[string]any, maxListSize int) (string, []any, error) {
+	dialect, ok := DialectFor(db.DriverName()).(ProcedureDialect)
+	if !ok {
+		return "", nil, fmt.Errorf("%w: driver %s cannot call procedure %s", ErrProcedureUnsupported, db.DriverName(), name)
+	}
+	batch, err := dialect.ProcedureSQL(name, params, provided)
+	if err != nil {
+		return "", nil, err
+	}
+	return BindParameters(batch, provided, db.DriverName(), maxListSize)
+}
+
+// readProcedureResult reads the result sets of a procedure batch. The last result set
+// holds the return code and outputs; the result sets before it are named by declared
+// and read up to maxRows, the first of them holds the result rows.
+func readProcedureResult(rows *sql.Rows, declared []*ResultSet, maxRows int) (*ProcedureResult, error) {
+	sets, err := readResultSets(rows, declared, maxRows)
+	if err != nil {
+		return nil, err
+	}
+
+	last := sets[len(sets)-1]
+	if len(last.Rows) != 1 {
+		return nil, fmt.Errorf("procedure batch returned %d output rows, expected 1", len(last.Rows))
+	}
+	result := &ProcedureResult{Outputs: last.Rows[0]}
+	if len(sets) > 1 {
+		result.ResultSets = sets[:len(sets)-1]
+		result.Rows, result.Truncated = sets[0].Rows, sets[0].Truncated
+	}
+	switch value := result.Outputs[ReturnValueColumn].(type) {
+	case int64:
+		result.ReturnValue = value
+	case int32:
+		result.ReturnValue = int64(value)
+	}
+	delete(result.Outputs, ReturnValueColumn)
+	return result, nil
+}
+
+// loadMockProcedureResult loads a procedure result from a JSON mock data set
+func loadMockProcedureResult(location, filePath string) (*ProcedureResult, error) {
+	// If the path doesn't contain path separators, use the configured location
+	if !strings.Contains(filePath, "/") && !strings.Contains(filePath, "\\") {
+		filePath = location + filePath
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mock file %s: %w", filePath, err)
+	}
+
+	var result ProcedureResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse mock file %s as JSON: %w", filePath, err)
+	}
+	if result.Outputs == nil {
+		result.Outputs = map[string]any{}
+	}
+	return &result, nil
+}
@@ -0,0 +1,167 @@
+package xfeature
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// procedureTestDriver is SQLite with a dialect that fakes procedure batches,
+// as SQLite has no stored procedures
+const procedureTestDriver = "sqlite3_procedure"
+
+type procedureTestDialect struct {
+	SQLiteDialect
+}
+
+func (procedureTestDialect) Name() string { return procedureTestDriver }
+
+// ProcedureSQL doubles the input into the output parameter and returns 7
+func (procedureTestDialect) ProcedureSQL(name string, params []*ProcParam, provided map[string]any) (string, error) {
+	return "SELECT 7 AS " + ReturnValueColumn + ", :amount * 2 AS doubled", nil
+}
+
+func init() {
+	sql.Register(procedureTestDriver, &sqlite3.SQLiteDriver{})
+	RegisterDialect(procedureTestDialect{})
+}
+
+const procedureFeature = `<Feature Name="billing" Version="1">
+	<Backend>
+		<Query Id="Totals" Type="Procedure">
+			dbo.usp_Totals
+			<Param Name="amount"/>
+			<Param Name="doubled" Output="true" SqlType="int"/>
+		</Query>
+		<ActionQuery Id="Close" Type="Procedure">dbo.usp_Close<Param Name="amount"/></ActionQuery>
+	</Backend>
+	<Frontend/>
+</Feature>`
+
+// TestProcedureSQL tests the SQL Server batch of a procedure call
+func TestProcedureSQL(t *testing.T) {
+	params := []*ProcParam{
+		{Name: "customer_id"},
+		{Name: "region"},
+		{Name: "total", Output: true, SqlType: "decimal(18, 2)"},
+		{Name: "note", Output: true, SqlType: "nvarchar(max)"},
+	}
+
+	batch, err := SQLServerDialect{}.ProcedureSQL("dbo.usp_Totals", params, map[string]any{"customer_id": 1, "note": "x"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "DECLARE @xf_return_value int;\n" +
+		"DECLARE @xf_out_total decimal(18, 2);\n" +
+		"DECLARE @xf_out_note nvarchar(max) = :note;\n" +
+		"EXEC @xf_return_value = dbo.usp_Totals @customer_id = :customer_id, @total = @xf_out_total OUTPUT, @note = @xf_out_note OUTPUT;\n" +
+		"SELECT @xf_return_value AS [xf_return_value], @xf_out_total AS [total], @xf_out_note AS [note];"
+	if batch != expected {
+		t.Errorf("Expected batch:\n%s\nGot:\n%s", expected, batch)
+	}
+
+	invalid := []struct {
+		name   string
+		params []*ProcParam
+	}{
+		{name: "dbo.usp_Totals; DROP TABLE users"},
+		{name: "dbo.usp_Totals", params: []*ProcParam{{Name: "total", Output: true}}},
+		{name: "dbo.usp_Totals", params: []*ProcParam{{Name: "total", Output: true, SqlType: "int; DROP TABLE users"}}},
+		{name: "dbo.usp_Totals", params: []*ProcParam{{Name: "a b"}}},
+	}
+	for _, tt := range invalid {
+		if _, err := (SQLServerDialect{}).ProcedureSQL(tt.name, tt.params, nil); err == nil {
+			t.Errorf("Expected an error for procedure %q with %v", tt.name, tt.params)
+		}
+	}
+}
+
+// TestProcedureQuery tests running Procedure queries and actions through a procedure dialect
+func TestProcedureQuery(t *testing.T) {
+	db, err := sqlx.Open(procedureTestDriver, ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(procedureFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	query, _ := xf.GetQuery("Totals")
+	if query.SQL != "dbo.usp_Totals" || len(query.Params) != 2 || strings.Join(query.Parameters, ",") != "amount,doubled" {
+		t.Fatalf("Unexpected procedure query: %q %v %v", query.SQL, query.Params, query.Parameters)
+	}
+
+	executor := NewQueryExecutor(testLogger)
+	rows, err := executor.Execute(context.Background(), db, query, map[string]any{"amount": 21})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result := executor.LastProcedure
+	if len(rows) != 0 || result == nil || result.ReturnValue != 7 || result.Outputs["doubled"] != int64(42) {
+		t.Errorf("Unexpected procedure result: rows %v, result %+v", rows, result)
+	}
+	if _, ok := result.Outputs[ReturnValueColumn]; ok {
+		t.Errorf("Expected the return value to be removed from the outputs")
+	}
+
+	action, _ := xf.GetActionQuery("Close")
+	actionExecutor := NewActionExecutor(testLogger)
+	if _, err := actionExecutor.Execute(context.Background(), db, action, map[string]any{"amount": 1}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if actionExecutor.LastProcedure == nil || actionExecutor.LastProcedure.ReturnValue != 7 {
+		t.Errorf("Expected the action to report the return value, got %+v", actionExecutor.LastProcedure)
+	}
+
+	// Procedures are neither paged nor streamed
+	_, err = executor.Stream(context.Background(), db, query, nil, func(map[string]any) error { return nil })
+	if !errors.Is(err, ErrProcedureUnsupported) {
+		t.Errorf("Expected ErrProcedureUnsupported for streaming, got %v", err)
+	}
+}
+
+// TestProcedureUnsupportedDriver tests that drivers without procedure support are rejected
+func TestProcedureUnsupportedDriver(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	query := &Query{Id: "Totals", Type: ProcedureType, SQL: "dbo.usp_Totals"}
+	_, err := NewQueryExecutor(testLogger).Execute(context.Background(), db, query, nil)
+	if !errors.Is(err, ErrProcedureUnsupported) {
+		t.Errorf("Expected ErrProcedureUnsupported, got %v", err)
+	}
+}
+
+// TestProcedureMockDataSet tests that procedure results can be faked with mock data sets
+func TestProcedureMockDataSet(t *testing.T) {
+	dir := t.TempDir() + string(filepath.Separator)
+	mock := `{"rows": [{"id": 1}, {"id": 2}], "outputs": {"total": 12.5}, "returnValue": 3}`
+	if err := os.WriteFile(dir+"totals.json", []byte(mock), 0644); err != nil {
+		t.Fatalf("Failed to write mock data set: %v", err)
+	}
+
+	query := &Query{Id: "Totals", Type: ProcedureType, SQL: "dbo.usp_Totals", MockDataSet: "totals.json"}
+	executor := NewQueryExecutorWithLocation(testLogger, dir)
+	rows, err := executor.Execute(context.Background(), nil, query, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rows) != 2 || executor.LastProcedure.ReturnValue != 3 || executor.LastProcedure.Outputs["total"] != 12.5 {
+		t.Errorf("Unexpected mock procedure result: rows %v, result %+v", rows, executor.LastProcedure)
+	}
+
+	action := &ActionQuery{Id: "Close", Type: ProcedureType, SQL: "dbo.usp_Close", MockDataSet: "totals.json"}
+	result, err := NewActionExecutorWithLocation(testLogger, dir).ExecuteProcedure(context.Background(), nil, action, nil)
+	if err != nil || result.ReturnValue != 3 || len(result.Rows) != 2 {
+		t.Errorf("Unexpected mock procedure action result: %+v (error %v)", result, err)
+	}
+}
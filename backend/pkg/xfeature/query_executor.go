@@ -3,10 +3,10 @@ package xfeature
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
-	"regexp"
 	"strings"
 	"time"
 
@@ -20,44 +20,70 @@ type QueryExecutor struct {
 	logger              *slog.Logger
 	mockDataSetLocation string
 	captureEnabled      bool
+	maxListSize         int
+	cache               *QueryCache
+	queryTimeout        time.Duration
+	maxRows             int
+	dataSources         DataSources
 	LastMockDataSet     string
+	// LastCacheStatus is CacheHit or CacheMiss after Execute or ExecutePage ran a cached query
+	LastCacheStatus string
+	// LastTruncated reports that the last result was cut off at LastMaxRows rows
+	LastTruncated bool
+	LastMaxRows   int
+	// LastColumns holds the result columns of the last Stream call
+	LastColumns []string
+	// LastProcedure holds the outputs and return code after Execute ran a Procedure query
+	LastProcedure *ProcedureResult
+	// LastResultSets holds every result set read by Execute; Execute returns the first
+	LastResultSets []*NamedResultSet
 }
 
 // NewQueryExecutor creates a new query executor
 func NewQueryExecutor(logger *slog.Logger) *QueryExecutor {
-	if logger == nil {
-		logger = slog.Default()
-	}
-	return &QueryExecutor{logger: logger, mockDataSetLocation: "specs/mock/"}
+	return NewQueryExecutorWithOptions(logger, ExecutorOptions{})
 }
 
 // NewQueryExecutorWithLocation creates a new query executor with a custom mock data set location
 func NewQueryExecutorWithLocation(logger *slog.Logger, mockDataSetLocation string) *QueryExecutor {
-	if logger == nil {
-		logger = slog.Default()
-	}
-	if mockDataSetLocation == "" {
-		mockDataSetLocation = "specs/mock/"
-	}
-	return &QueryExecutor{logger: logger, mockDataSetLocation: mockDataSetLocation, captureEnabled: false}
+	return NewQueryExecutorWithOptions(logger, ExecutorOptions{MockDataSetLocation: mockDataSetLocation})
 }
 
 // NewQueryExecutorWithConfig creates a new query executor with config options
 func NewQueryExecutorWithConfig(logger *slog.Logger, mockDataSetLocation string, captureEnabled bool) *QueryExecutor {
+	return NewQueryExecutorWithOptions(logger, ExecutorOptions{
+		MockDataSetLocation: mockDataSetLocation,
+		CaptureMockDataSet:  captureEnabled,
+	})
+}
+
+// NewQueryExecutorWithOptions creates a new query executor from executor options
+func NewQueryExecutorWithOptions(logger *slog.Logger, opts ExecutorOptions) *QueryExecutor {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	if mockDataSetLocation == "" {
-		mockDataSetLocation = "specs/mock/"
-	}
+	opts = opts.withDefaults()
 	return &QueryExecutor{
 		logger:              logger,
-		mockDataSetLocation: mockDataSetLocation,
-		captureEnabled:      captureEnabled,
+		mockDataSetLocation: opts.MockDataSetLocation,
+		captureEnabled:      opts.CaptureMockDataSet,
+		maxListSize:         opts.MaxListSize,
+		cache:               opts.Cache,
+		queryTimeout:        opts.QueryTimeout,
+		maxRows:             opts.MaxRows,
+		dataSources:         opts.DataSources,
 	}
 }
 
-// Execute runs a SELECT query and returns results as slice of maps
+// cachedRows is the cache entry of an Execute result
+type cachedRows struct {
+	rows       []map[string]interface{}
+	truncated  bool
+	resultSets []*NamedResultSet
+}
+
+// Execute runs a SELECT query and returns results as slice of maps. Queries returning
+// several result sets return the first; LastResultSets holds all of them.
 func (qe *QueryExecutor) Execute(
 	ctx context.Context,
 	db *sqlx.DB,
@@ -66,6 +92,30 @@ func (qe *QueryExecutor) Execute(
 ) ([]map[string]interface{}, error) {
 	startTime := time.Now()
 	qe.LastMockDataSet = ""
+	qe.LastCacheStatus = ""
+	qe.LastTruncated = false
+	qe.LastProcedure = nil
+	qe.LastResultSets = nil
+	qe.LastMaxRows = parseMaxRows(query.MaxRows, qe.maxRows)
+	timeout := parseTimeout(query.Timeout, qe.queryTimeout)
+
+	// Stored procedures return their result rows, the outputs are kept in LastProcedure
+	if query.IsProcedure() {
+		result, err := qe.ExecuteProcedure(ctx, db, query, params)
+		if err != nil {
+			return nil, err
+		}
+		qe.LastProcedure = result
+		qe.LastResultSets = result.ResultSets
+		if len(qe.LastResultSets) == 0 {
+			qe.LastResultSets = []*NamedResultSet{{Name: resultSetName(query.ResultSets, 0), Rows: result.Rows, Truncated: result.Truncated}}
+		}
+		return result.Rows, nil
+	}
+
+	// Leave out the Optional fragments whose parameters are not supplied
+	query = query.ResolveOptional(params)
+
 	// Check if MockDataSet is specified and exists
 	if query.MockDataSet != "" {
 		if mockData, err := qe.loadMockDataSet(query.MockDataSet); err == nil {
@@ -75,6 +125,10 @@ func (qe *QueryExecutor) Execute(
 				"rowCount", len(mockData),
 				"duration_ms", time.Since(startTime).Milliseconds(),
 			)
+			if qe.LastMaxRows > 0 && len(mockData) > qe.LastMaxRows {
+				mockData, qe.LastTruncated = mockData[:qe.LastMaxRows], true
+			}
+			qe.LastResultSets = []*NamedResultSet{{Name: resultSetName(query.ResultSets, 0), Rows: mockData, Truncated: qe.LastTruncated}}
 			return mockData, nil
 		} else if os.IsExist(os.ErrNotExist) || !os.IsNotExist(err) {
 			qe.logger.Warn("Mock data set error, falling back to database query",
@@ -85,6 +139,20 @@ func (qe *QueryExecutor) Execute(
 		}
 	}
 
+	// Serve cached results of queries with a Cache attribute
+	key, cached := qe.cacheKey(query, params, nil)
+	if cached {
+		if value, ok := qe.cache.Get(key); ok {
+			qe.LastCacheStatus = CacheHit
+			qe.logger.Debug("Query served from cache", "queryId", query.Id)
+			entry := value.(cachedRows)
+			qe.LastTruncated = entry.truncated
+			qe.LastResultSets = entry.resultSets
+			return entry.rows, nil
+		}
+		qe.LastCacheStatus = CacheMiss
+	}
+
 	// Extract expected parameters from SQL
 	expectedParams := ExtractParameters(query.SQL)
 
@@ -94,43 +162,58 @@ func (qe *QueryExecutor) Execute(
 		return nil, err
 	}
 
-	// Convert parameters for the database driver
-	sql := query.SQL
-	driverName := db.DriverName()
-	sql = ConvertParametersForDriver(sql, driverName)
+	// Run on the data source named by the query
+	db, err := resolveDataSource(qe.dataSources, db, query.DataSource)
+	if err != nil {
+		qe.logger.Error("Data source not available", "queryId", query.Id, "dataSource", query.DataSource, "error", err)
+		return nil, err
+	}
 
-	// Build args slice in the order of parameters used in SQL
-	sql, args := qe.buildArgs(sql, params, driverName)
+	// Bind parameters for the database driver
+	sql, args, err := BindParameters(query.SQL, params, db.DriverName(), qe.maxListSize)
+	if err != nil {
+		qe.logger.Error("Parameter binding failed", "queryId", query.Id, "error", err)
+		return nil, err
+	}
 
 	// Log colored SQL for debugging
 	qe.logColoredSQL(fmt.Sprintf("%s/%s", query.Parent, query.Id), sql)
 
 	// Execute query
-	sqlRows, err := db.QueryContext(ctx, sql, args...)
+	queryCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	sqlRows, err := db.QueryContext(queryCtx, sql, args...)
 	if err != nil {
 		qe.logger.Error("Query execution failed",
 			"queryId", query.Id,
 			"error", err,
 			"duration_ms", time.Since(startTime).Milliseconds(),
 		)
-		return nil, fmt.Errorf("failed to execute query %s: %w", query.Id, err)
+		return nil, timeoutError(queryCtx, ctx, fmt.Errorf("failed to execute query %s: %w", query.Id, err), query.Id, timeout)
 	}
 	defer sqlRows.Close()
 
-	// Convert rows to maps using the utility function
-	results, err := dbutil.RowsToMaps(sqlRows)
+	// Convert the rows of every result set to maps, stopping at the row limit
+	resultSets, err := readResultSets(sqlRows, query.ResultSets, qe.LastMaxRows)
 	if err != nil {
 		qe.logger.Error("Failed to convert rows",
 			"queryId", query.Id,
 			"error", err,
 			"duration_ms", time.Since(startTime).Milliseconds(),
 		)
-		return nil, fmt.Errorf("failed to convert rows: %w", err)
+		return nil, timeoutError(queryCtx, ctx, fmt.Errorf("failed to convert rows: %w", err), query.Id, timeout)
+	}
+	results, truncated := resultSets[0].Rows, resultSets[0].Truncated
+	qe.LastTruncated = truncated
+	qe.LastResultSets = resultSets
+	if truncated {
+		qe.logger.Warn("Query result truncated", "queryId", query.Id, "maxRows", qe.LastMaxRows)
 	}
 
 	qe.logger.Debug("Query executed successfully",
 		"queryId", query.Id,
 		"rowCount", len(results),
+		"resultSets", len(resultSets),
 		"duration_ms", time.Since(startTime).Milliseconds(),
 		"params", params,
 		"args", args,
@@ -147,72 +230,423 @@ func (qe *QueryExecutor) Execute(
 		}
 	}
 
+	if cached {
+		qe.cache.Set(key, query, cachedRows{rows: results, truncated: truncated, resultSets: resultSets}, query.CacheTTL())
+	}
+
 	return results, nil
 }
 
-// validateParameters checks that all required parameters are provided
-func (qe *QueryExecutor) validateParameters(required []string, provided map[string]interface{}) error {
-	for _, param := range required {
-		if _, ok := provided[param]; !ok {
-			return fmt.Errorf("missing required parameter: %s", param)
+// Stream runs a SELECT query and passes the rows to fn one at a time as they are
+// scanned, without holding the result set in memory. It stops when fn returns an
+// error or ctx is cancelled and returns the number of rows passed to fn.
+// Only a MaxRows attribute limits the rows; the default row limit does not apply.
+// The Timeout attribute, or the default QueryTimeout, only limits the time until the
+// first row: once rows flow, long exports run until they end or ctx is cancelled.
+func (qe *QueryExecutor) Stream(
+	ctx context.Context,
+	db *sqlx.DB,
+	query *Query,
+	params map[string]interface{},
+	fn func(row map[string]interface{}) error,
+) (int, error) {
+	return qe.StreamResultSet(ctx, db, query, "", params, fn)
+}
+
+// StreamResultSet is Stream for the named result set of a query returning several;
+// an empty name streams the first. Mock data sets only hold the first result set.
+func (qe *QueryExecutor) StreamResultSet(
+	ctx context.Context,
+	db *sqlx.DB,
+	query *Query,
+	resultSet string,
+	params map[string]interface{},
+	fn func(row map[string]interface{}) error,
+) (int, error) {
+	startTime := time.Now()
+	qe.LastMockDataSet = ""
+	qe.LastColumns = nil
+	qe.LastTruncated = false
+	qe.LastMaxRows = query.MaxRows
+	if query.IsProcedure() {
+		return 0, fmt.Errorf("%w: query %s cannot be streamed", ErrProcedureUnsupported, query.Id)
+	}
+	query = query.ResolveOptional(params)
+	index, ok := query.ResultSetIndex(resultSet)
+	if !ok {
+		return 0, fmt.Errorf("%w: query %s declares no result set %s", ErrUnknownResultSet, query.Id, resultSet)
+	}
+	timeout := parseTimeout(query.Timeout, qe.queryTimeout)
+	queryCtx, started, cancel := withStartTimeout(ctx, timeout)
+	defer cancel()
+	count := 0
+
+	emit := func(row map[string]interface{}) error {
+		if count == 0 {
+			started()
+		}
+		if err := queryCtx.Err(); err != nil {
+			return err
 		}
+		if qe.LastMaxRows > 0 && count >= qe.LastMaxRows {
+			qe.LastTruncated = true
+			return errRowLimit
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+		count++
+		return nil
 	}
-	return nil
-}
 
-// buildArgs constructs the arguments slice for the query based on parameter order
-func (qe *QueryExecutor) buildArgs(sql string, params map[string]interface{}, driverName string) (string, []interface{}) {
-	var args []any
-	switch driverName {
-	case "sqlserver":
-		// For SQL Server, extract @param names in order
-		paramRegex := regexp.MustCompile(`@(\w+)`)
-		matches := paramRegex.FindAllStringSubmatch(sql, -1)
-
-		//seen := make(map[string]bool)
-		for _, match := range matches {
-			paramName := match[1]
-			sql = strings.Replace(sql, match[0], fmt.Sprintf("'%s'", params[match[1]]), 1)
-			if val, ok := params[paramName]; ok {
-				args = append(args, val)
-				//					seen[paramName] = true
+	// Check if MockDataSet is specified and exists
+	if query.MockDataSet != "" && index == 0 {
+		if mockData, err := qe.loadMockDataSet(query.MockDataSet); err == nil {
+			if len(mockData) > 0 {
+				qe.LastColumns = sortedRowKeys(mockData[0])
+			}
+			for _, row := range mockData {
+				if err := emit(row); errors.Is(err, errRowLimit) {
+					break
+				} else if err != nil {
+					return count, err
+				}
 			}
+			return count, nil
+		} else if !os.IsNotExist(err) {
+			qe.logger.Warn("Mock data set error, falling back to database query",
+				"queryId", query.Id,
+				"mockDataSet", query.MockDataSet,
+				"error", err,
+			)
 		}
+	}
 
-	case "sqlite3", "sqlite":
-		// For SQLite, extract :param names in order
-		paramRegex := regexp.MustCompile(`:(\w+)`)
-		matches := paramRegex.FindAllStringSubmatch(sql, -1)
-
-		seen := make(map[string]bool)
-		for _, match := range matches {
-			paramName := match[1]
-			if !seen[paramName] {
-				if val, ok := params[paramName]; ok {
-					args = append(args, val)
-					seen[paramName] = true
-				}
+	// Validate that all required parameters are provided
+	if err := qe.validateParameters(ExtractParameters(query.SQL), params); err != nil {
+		qe.logger.Error("Parameter validation failed", "queryId", query.Id, "error", err)
+		return 0, err
+	}
+
+	// Run on the data source named by the query
+	db, err := resolveDataSource(qe.dataSources, db, query.DataSource)
+	if err != nil {
+		qe.logger.Error("Data source not available", "queryId", query.Id, "dataSource", query.DataSource, "error", err)
+		return 0, err
+	}
+
+	// Bind parameters for the database driver
+	sql, args, err := BindParameters(query.SQL, params, db.DriverName(), qe.maxListSize)
+	if err != nil {
+		qe.logger.Error("Parameter binding failed", "queryId", query.Id, "error", err)
+		return 0, err
+	}
+
+	qe.logColoredSQL(fmt.Sprintf("STREAM %s/%s", query.Parent, query.Id), sql)
+
+	sqlRows, err := db.QueryContext(queryCtx, sql, args...)
+	if err != nil {
+		qe.logger.Error("Query execution failed",
+			"queryId", query.Id,
+			"error", err,
+			"duration_ms", time.Since(startTime).Milliseconds(),
+		)
+		return 0, timeoutError(queryCtx, ctx, fmt.Errorf("failed to execute query %s: %w", query.Id, err), query.Id, timeout)
+	}
+	defer sqlRows.Close()
+
+	// Skip the result sets before the requested one
+	for range index {
+		if !sqlRows.NextResultSet() {
+			if err := sqlRows.Err(); err != nil {
+				return 0, timeoutError(queryCtx, ctx, err, query.Id, timeout)
 			}
+			return 0, fmt.Errorf("%w: query %s returned no result set %s", ErrUnknownResultSet, query.Id, resultSet)
 		}
+	}
 
-	default:
-		// Generic approach: extract all named parameters
-		paramRegex := regexp.MustCompile(`:\w+`)
-		matches := paramRegex.FindAllString(sql, -1)
-
-		seen := make(map[string]bool)
-		for _, match := range matches {
-			paramName := strings.TrimPrefix(match, ":")
-			if !seen[paramName] {
-				if val, ok := params[paramName]; ok {
-					args = append(args, val)
-					seen[paramName] = true
-				}
+	if qe.LastColumns, err = sqlRows.Columns(); err != nil {
+		return 0, fmt.Errorf("failed to read columns of query %s: %w", query.Id, err)
+	}
+
+	if err := dbutil.EachRow(sqlRows, emit); err != nil && !errors.Is(err, errRowLimit) {
+		qe.logger.Warn("Query stream stopped",
+			"queryId", query.Id,
+			"rowCount", count,
+			"error", err,
+			"duration_ms", time.Since(startTime).Milliseconds(),
+		)
+		return count, timeoutError(queryCtx, ctx, err, query.Id, timeout)
+	}
+	if qe.LastTruncated {
+		qe.logger.Warn("Query stream truncated", "queryId", query.Id, "maxRows", qe.LastMaxRows)
+	}
+
+	qe.logger.Debug("Query streamed successfully",
+		"queryId", query.Id,
+		"rowCount", count,
+		"duration_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	return count, nil
+}
+
+// ExecutePage runs a SELECT query for a DataTable with server-side paging, sorting,
+// filtering and search, and counts the rows matching the filters across all pages.
+// Mock data sets are paged in memory; sorting, filters and search do not apply to them.
+func (qe *QueryExecutor) ExecutePage(
+	ctx context.Context,
+	db *sqlx.DB,
+	query *Query,
+	table *DataTable,
+	params map[string]interface{},
+	page *PageRequest,
+) (*PageResult, error) {
+	startTime := time.Now()
+	qe.LastMockDataSet = ""
+	qe.LastCacheStatus = ""
+	qe.LastTruncated = false
+	qe.LastMaxRows = parseMaxRows(query.MaxRows, qe.maxRows)
+	timeout := parseTimeout(query.Timeout, qe.queryTimeout)
+	if query.IsProcedure() {
+		return nil, fmt.Errorf("%w: query %s cannot be paged", ErrProcedureUnsupported, query.Id)
+	}
+	query = query.ResolveOptional(params)
+
+	db, err := resolveDataSource(qe.dataSources, db, query.DataSource)
+	if err != nil {
+		qe.logger.Error("Data source not available", "queryId", query.Id, "dataSource", query.DataSource, "error", err)
+		return nil, err
+	}
+
+	driverName := db.DriverName()
+	paged, err := BuildPagedSQL(query.SQL, table, page, driverName)
+	if err != nil {
+		qe.logger.Warn("Invalid page request", "queryId", query.Id, "error", err)
+		return nil, err
+	}
+
+	if query.MockDataSet != "" {
+		if mockData, err := qe.loadMockDataSet(query.MockDataSet); err == nil {
+			result := pageRows(mockData, page)
+			if qe.LastMaxRows > 0 && len(result.Rows) > qe.LastMaxRows {
+				result.Rows, result.Truncated = result.Rows[:qe.LastMaxRows], true
+			}
+			qe.LastTruncated = result.Truncated
+			return result, nil
+		} else if !os.IsNotExist(err) {
+			qe.logger.Warn("Mock data set error, falling back to database query",
+				"queryId", query.Id,
+				"mockDataSet", query.MockDataSet,
+				"error", err,
+			)
+		}
+	}
+
+	// Pages are cached separately by their effective page request
+	key, cached := qe.cacheKey(query, params, page)
+	if cached {
+		if value, ok := qe.cache.Get(key); ok {
+			qe.LastCacheStatus = CacheHit
+			qe.logger.Debug("Page served from cache", "queryId", query.Id)
+			result := value.(*PageResult)
+			qe.LastTruncated = result.Truncated
+			return result, nil
+		}
+		qe.LastCacheStatus = CacheMiss
+	}
+
+	// Validate that all required parameters are provided
+	if err := qe.validateParameters(ExtractParameters(query.SQL), params); err != nil {
+		qe.logger.Error("Parameter validation failed", "queryId", query.Id, "error", err)
+		return nil, err
+	}
+
+	allParams := make(map[string]interface{}, len(params)+len(paged.Params))
+	for name, value := range params {
+		allParams[name] = value
+	}
+	for name, value := range paged.Params {
+		allParams[name] = value
+	}
+
+	// Count the rows matching the filters
+	countSQL, countArgs, err := BindParameters(paged.CountSQL, allParams, driverName, qe.maxListSize)
+	if err != nil {
+		qe.logger.Error("Parameter binding failed", "queryId", query.Id, "error", err)
+		return nil, err
+	}
+	// The timeout covers both the count and the page query
+	queryCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	var totalCount int64
+	if err := db.QueryRowContext(queryCtx, countSQL, countArgs...).Scan(&totalCount); err != nil {
+		qe.logger.Error("Count query failed",
+			"queryId", query.Id,
+			"error", err,
+			"duration_ms", time.Since(startTime).Milliseconds(),
+		)
+		return nil, timeoutError(queryCtx, ctx, fmt.Errorf("failed to count rows of query %s: %w", query.Id, err), query.Id, timeout)
+	}
+
+	// Fetch the requested page
+	sql, args, err := BindParameters(paged.DataSQL, allParams, driverName, qe.maxListSize)
+	if err != nil {
+		qe.logger.Error("Parameter binding failed", "queryId", query.Id, "error", err)
+		return nil, err
+	}
+	qe.logColoredSQL(fmt.Sprintf("PAGE %s/%s", query.Parent, query.Id), sql)
+
+	sqlRows, err := db.QueryContext(queryCtx, sql, args...)
+	if err != nil {
+		qe.logger.Error("Query execution failed",
+			"queryId", query.Id,
+			"error", err,
+			"duration_ms", time.Since(startTime).Milliseconds(),
+		)
+		return nil, timeoutError(queryCtx, ctx, fmt.Errorf("failed to execute query %s: %w", query.Id, err), query.Id, timeout)
+	}
+	defer sqlRows.Close()
+
+	results, truncated, err := dbutil.RowsToMapsLimit(sqlRows, qe.LastMaxRows)
+	if err != nil {
+		qe.logger.Error("Failed to convert rows",
+			"queryId", query.Id,
+			"error", err,
+			"duration_ms", time.Since(startTime).Milliseconds(),
+		)
+		return nil, timeoutError(queryCtx, ctx, fmt.Errorf("failed to convert rows: %w", err), query.Id, timeout)
+	}
+	qe.LastTruncated = truncated
+	if truncated {
+		qe.logger.Warn("Page truncated", "queryId", query.Id, "maxRows", qe.LastMaxRows)
+	}
+
+	qe.logger.Debug("Page query executed successfully",
+		"queryId", query.Id,
+		"page", page.Page,
+		"pageSize", page.PageSize,
+		"rowCount", len(results),
+		"totalCount", totalCount,
+		"duration_ms", time.Since(startTime).Milliseconds(),
+	)
+
+	result := &PageResult{
+		Rows:       results,
+		TotalCount: totalCount,
+		Page:       page.Page,
+		PageSize:   page.PageSize,
+		Truncated:  truncated,
+	}
+	if cached {
+		qe.cache.Set(key, query, result, query.CacheTTL())
+	}
+	return result, nil
+}
+
+// ExecuteProcedure runs a Procedure query and returns its result rows, output parameters
+// and return code. Procedure results are not cached.
+func (qe *QueryExecutor) ExecuteProcedure(
+	ctx context.Context,
+	db *sqlx.DB,
+	query *Query,
+	params map[string]interface{},
+) (*ProcedureResult, error) {
+	startTime := time.Now()
+	qe.LastMockDataSet = ""
+	qe.LastTruncated = false
+	qe.LastMaxRows = parseMaxRows(query.MaxRows, qe.maxRows)
+	timeout := parseTimeout(query.Timeout, qe.queryTimeout)
+
+	if query.MockDataSet != "" {
+		qe.LastMockDataSet = query.MockDataSet
+		if result, err := loadMockProcedureResult(qe.mockDataSetLocation, query.MockDataSet); err == nil {
+			qe.logger.Debug("Mock procedure result loaded successfully",
+				"queryId", query.Id,
+				"mockDataSet", query.MockDataSet,
+				"rowCount", len(result.Rows),
+			)
+			if qe.LastMaxRows > 0 && len(result.Rows) > qe.LastMaxRows {
+				result.Rows, result.Truncated = result.Rows[:qe.LastMaxRows], true
 			}
+			qe.LastTruncated = result.Truncated
+			return result, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			qe.logger.Warn("Mock data set error, falling back to database procedure",
+				"queryId", query.Id,
+				"mockDataSet", query.MockDataSet,
+				"error", err,
+			)
 		}
 	}
 
-	return sql, args
+	db, err := resolveDataSource(qe.dataSources, db, query.DataSource)
+	if err != nil {
+		qe.logger.Error("Data source not available", "queryId", query.Id, "dataSource", query.DataSource, "error", err)
+		return nil, err
+	}
+
+	sql, args, err := procedureStatement(db, query.SQL, query.Params, params, qe.maxListSize)
+	if err != nil {
+		qe.logger.Error("Procedure binding failed", "queryId", query.Id, "error", err)
+		return nil, err
+	}
+	qe.logColoredSQL(fmt.Sprintf("%s/%s", query.Parent, query.Id), sql)
+
+	queryCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	sqlRows, err := db.QueryContext(queryCtx, sql, args...)
+	if err != nil {
+		qe.logger.Error("Procedure execution failed", "queryId", query.Id, "error", err)
+		return nil, timeoutError(queryCtx, ctx, fmt.Errorf("failed to execute procedure %s: %w", query.Id, err), query.Id, timeout)
+	}
+	defer sqlRows.Close()
+
+	result, err := readProcedureResult(sqlRows, query.ResultSets, qe.LastMaxRows)
+	if err != nil {
+		qe.logger.Error("Failed to read procedure result", "queryId", query.Id, "error", err)
+		return nil, timeoutError(queryCtx, ctx, fmt.Errorf("failed to read procedure result %s: %w", query.Id, err), query.Id, timeout)
+	}
+	qe.LastTruncated = result.Truncated
+
+	qe.logger.Debug("Procedure executed successfully",
+		"queryId", query.Id,
+		"rowCount", len(result.Rows),
+		"returnValue", result.ReturnValue,
+		"duration_ms", time.Since(startTime).Milliseconds(),
+	)
+	return result, nil
+}
+
+// cacheKey returns the cache key of a query execution; ok is false if the
+// executor has no cache or the query has no Cache attribute
+func (qe *QueryExecutor) cacheKey(query *Query, params map[string]interface{}, extra any) (string, bool) {
+	if qe.cache == nil || query.CacheTTL() <= 0 {
+		return "", false
+	}
+	return cacheKey(query, params, extra)
+}
+
+// pageRows slices a page out of rows that are already in memory
+func pageRows(rows []map[string]interface{}, page *PageRequest) *PageResult {
+	result := &PageResult{Rows: rows, TotalCount: int64(len(rows)), Page: page.Page, PageSize: page.PageSize}
+	if !page.Paged() {
+		return result
+	}
+	start := min((page.Page-1)*page.PageSize, len(rows))
+	end := min(start+page.PageSize, len(rows))
+	result.Rows = rows[start:end]
+	return result
+}
+
+// validateParameters checks that all required parameters are provided
+func (qe *QueryExecutor) validateParameters(required []string, provided map[string]interface{}) error {
+	for _, param := range required {
+		if _, ok := provided[param]; !ok {
+			return fmt.Errorf("missing required parameter: %s", param)
+		}
+	}
+	return nil
 }
 
 // loadMockDataSet loads mock data from a JSON file
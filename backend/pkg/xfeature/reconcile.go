@@ -0,0 +1,276 @@
+package xfeature
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Statuses of reconciled rows
+const (
+	ReconcileMatched    = "matched"
+	ReconcileMismatched = "mismatched"
+	ReconcileOnlyLeft   = "onlyLeft"
+	ReconcileOnlyRight  = "onlyRight"
+)
+
+// Reconcile compares the rows of two queries, which may run on different data sources.
+// Rows are paired by their Key columns; paired rows whose Compare columns differ are
+// mismatched, rows without a partner are only on the left or the right.
+type Reconcile struct {
+	Parent      string             `xml:"-" json:"-"`
+	Id          string             `xml:"Id,attr" json:"id"`
+	Description string             `xml:"Description,attr" json:"description"`
+	LeftRef     string             `xml:"LeftRef,attr" json:"leftRef"`
+	RightRef    string             `xml:"RightRef,attr" json:"rightRef"`
+	Keys        []*ReconcileColumn `xml:"Key" json:"keys"`
+	Compares    []*ReconcileColumn `xml:"Compare" json:"compares"`
+}
+
+// ReconcileColumn names a column of the left query and its counterpart in the right
+// query, which defaults to the same name
+type ReconcileColumn struct {
+	Left  string `xml:"Left,attr" json:"left"`
+	Right string `xml:"Right,attr" json:"right"`
+}
+
+// LeftColumn returns the column of the left query
+func (c *ReconcileColumn) LeftColumn() string { return c.Left }
+
+// RightColumn returns the column of the right query
+func (c *ReconcileColumn) RightColumn() string {
+	if c.Right == "" {
+		return c.Left
+	}
+	return c.Right
+}
+
+// ReconcileRow is a key with its left and right rows; Differences lists the left names
+// of the Compare columns of mismatched rows that differ
+type ReconcileRow struct {
+	Status      string         `json:"status"`
+	Key         map[string]any `json:"key"`
+	Left        map[string]any `json:"left,omitempty"`
+	Right       map[string]any `json:"right,omitempty"`
+	Differences []string       `json:"differences,omitempty"`
+}
+
+// ReconcileSummary counts the rows read from each query and the reconciled rows by status
+type ReconcileSummary struct {
+	Left       int `json:"left"`
+	Right      int `json:"right"`
+	Matched    int `json:"matched"`
+	Mismatched int `json:"mismatched"`
+	OnlyLeft   int `json:"onlyLeft"`
+	OnlyRight  int `json:"onlyRight"`
+}
+
+// ReconcileResult holds the reconciled rows, left rows in their order followed by the
+// rows only on the right, and their summary. A truncated side was cut off at MaxRows,
+// so rows reported as only on the other side may have a partner.
+type ReconcileResult struct {
+	Summary        ReconcileSummary `json:"summary"`
+	Rows           []*ReconcileRow  `json:"rows"`
+	LeftTruncated  bool             `json:"leftTruncated"`
+	RightTruncated bool             `json:"rightTruncated"`
+}
+
+// GetReconcile finds a reconcile by ID
+func (xf *XFeature) GetReconcile(id string) (*Reconcile, error) {
+	for _, reconcile := range xf.Backend.Reconciles {
+		if reconcile.Id == id {
+			if reconcile.Parent == "" {
+				reconcile.Parent = xf.Name
+			}
+			return reconcile, nil
+		}
+	}
+	return nil, fmt.Errorf("reconcile not found: %s", id)
+}
+
+// ExecuteReconcile runs the left and right queries of a reconcile with the same
+// parameters, each on its own data source, and reconciles their rows
+func (qe *QueryExecutor) ExecuteReconcile(
+	ctx context.Context,
+	db *sqlx.DB,
+	xf *XFeature,
+	reconcile *Reconcile,
+	params map[string]any,
+) (*ReconcileResult, error) {
+	left, leftTruncated, err := qe.executeSide(ctx, db, xf, reconcile.LeftRef, params)
+	if err != nil {
+		return nil, fmt.Errorf("left query of reconcile %s: %w", reconcile.Id, err)
+	}
+	right, rightTruncated, err := qe.executeSide(ctx, db, xf, reconcile.RightRef, params)
+	if err != nil {
+		return nil, fmt.Errorf("right query of reconcile %s: %w", reconcile.Id, err)
+	}
+
+	result := reconcile.Reconcile(left, right)
+	result.LeftTruncated, result.RightTruncated = leftTruncated, rightTruncated
+	qe.logger.Debug("Reconcile executed",
+		"reconcileId", reconcile.Id,
+		"matched", result.Summary.Matched,
+		"mismatched", result.Summary.Mismatched,
+		"onlyLeft", result.Summary.OnlyLeft,
+		"onlyRight", result.Summary.OnlyRight,
+	)
+	return result, nil
+}
+
+// executeSide runs one query of a reconcile
+func (qe *QueryExecutor) executeSide(
+	ctx context.Context,
+	db *sqlx.DB,
+	xf *XFeature,
+	queryID string,
+	params map[string]any,
+) ([]map[string]any, bool, error) {
+	query, err := xf.GetQuery(queryID)
+	if err != nil {
+		return nil, false, err
+	}
+	rows, err := qe.Execute(ctx, db, query, params)
+	if err != nil {
+		return nil, false, err
+	}
+	return rows, qe.LastTruncated, nil
+}
+
+// Reconcile pairs the left and right rows by their keys. Rows sharing a key on the
+// same side are paired in order with the rows of that key on the other side.
+func (r *Reconcile) Reconcile(left, right []map[string]any) *ReconcileResult {
+	result := &ReconcileResult{
+		Summary: ReconcileSummary{Left: len(left), Right: len(right)},
+		Rows:    make([]*ReconcileRow, 0, max(len(left), len(right))),
+	}
+
+	// Queue the indexes of the right rows of every key in their order
+	pending := make(map[string][]int)
+	for i, row := range right {
+		key := r.keyOf(row, (*ReconcileColumn).RightColumn)
+		pending[key] = append(pending[key], i)
+	}
+
+	paired := make([]bool, len(right))
+	for _, row := range left {
+		key := r.keyOf(row, (*ReconcileColumn).LeftColumn)
+		reconciled := &ReconcileRow{Key: r.keyColumns(row, (*ReconcileColumn).LeftColumn), Left: row}
+		if partners := pending[key]; len(partners) > 0 {
+			pending[key], paired[partners[0]] = partners[1:], true
+			reconciled.Right = right[partners[0]]
+			reconciled.Differences = r.differences(row, reconciled.Right)
+			if len(reconciled.Differences) > 0 {
+				reconciled.Status = ReconcileMismatched
+				result.Summary.Mismatched++
+			} else {
+				reconciled.Status = ReconcileMatched
+				result.Summary.Matched++
+			}
+		} else {
+			reconciled.Status = ReconcileOnlyLeft
+			result.Summary.OnlyLeft++
+		}
+		result.Rows = append(result.Rows, reconciled)
+	}
+
+	for i, row := range right {
+		if paired[i] {
+			continue
+		}
+		result.Rows = append(result.Rows, &ReconcileRow{Status: ReconcileOnlyRight, Key: r.keyColumns(row, (*ReconcileColumn).RightColumn), Right: row})
+		result.Summary.OnlyRight++
+	}
+	return result
+}
+
+// keyOf encodes the normalized key values of a row, read through column
+func (r *Reconcile) keyOf(row map[string]any, column func(*ReconcileColumn) string) string {
+	values := make([]any, len(r.Keys))
+	for i, key := range r.Keys {
+		values[i] = reconcileValue(row[column(key)])
+	}
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
+
+// keyColumns returns the key values of a row, read through column, under the left names
+func (r *Reconcile) keyColumns(row map[string]any, column func(*ReconcileColumn) string) map[string]any {
+	key := make(map[string]any, len(r.Keys))
+	for _, c := range r.Keys {
+		key[c.Left] = row[column(c)]
+	}
+	return key
+}
+
+// differences returns the left names of the Compare columns whose values differ
+func (r *Reconcile) differences(left, right map[string]any) []string {
+	var differences []string
+	for _, column := range r.Compares {
+		if !reconcileEqual(left[column.Left], right[column.RightColumn()]) {
+			differences = append(differences, column.Left)
+		}
+	}
+	return differences
+}
+
+// reconcileEqual compares two column values; decimals read as text, like 10.00, equal
+// numbers of the same value
+func reconcileEqual(a, b any) bool {
+	return reconcileValue(a) == reconcileValue(b)
+}
+
+// reconcileValue normalizes a column value for comparison across drivers: numbers of
+// any type and numeric text become their exact decimal value as a json.Number, so 10,
+// 10.0 and "10.00" are equal and bigints keep every digit, and text ignores surrounding
+// blanks like CHAR padding
+func reconcileValue(value any) any {
+	var text string
+	switch v := value.(type) {
+	case nil, bool:
+		return v
+	case int:
+		text = strconv.Itoa(v)
+	case int32:
+		text = strconv.FormatInt(int64(v), 10)
+	case int64:
+		text = strconv.FormatInt(v, 10)
+	case uint64:
+		text = strconv.FormatUint(v, 10)
+	case float32:
+		text = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		text = v.String()
+	case string:
+		text = strings.TrimSpace(v)
+	case []byte:
+		text = strings.TrimSpace(string(v))
+	default:
+		return fmt.Sprint(v)
+	}
+	if number, ok := exactDecimal(text); ok {
+		return number
+	}
+	return text
+}
+
+// exactDecimal returns numeric text as its shortest exact decimal, e.g. 010.50 becomes 10.5
+func exactDecimal(s string) (json.Number, bool) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return json.Number(strconv.FormatInt(n, 10)), true
+	}
+	d, err := parseDecimal(s)
+	if err != nil {
+		return "", false
+	}
+	if strings.Contains(d, ".") {
+		d = strings.TrimRight(strings.TrimRight(d, "0"), ".")
+	}
+	return json.Number(d), true
+}
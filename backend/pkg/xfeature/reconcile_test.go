@@ -0,0 +1,104 @@
+package xfeature
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+const reconcileFeature = `<Feature Name="invoices" Version="1">
+	<Backend>
+		<Query Id="PortalLines" Type="Select">SELECT code, qty FROM portal_lines WHERE invoice_no = :invoiceNo ORDER BY code</Query>
+		<Query Id="SageLines" Type="Select">SELECT ITMREF_0, QTYSTU_0 FROM sage_lines WHERE invoice_no = :invoiceNo ORDER BY ITMREF_0</Query>
+		<Reconcile Id="PortalVsSage" LeftRef="PortalLines" RightRef="SageLines">
+			<Key Left="code" Right="ITMREF_0"/>
+			<Compare Left="qty" Right="QTYSTU_0"/>
+		</Reconcile>
+	</Backend>
+	<Frontend/>
+</Feature>`
+
+// TestExecuteReconcile tests that the rows of two queries are paired by key and compared
+func TestExecuteReconcile(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`
+		CREATE TABLE portal_lines (invoice_no TEXT, code TEXT, qty INTEGER);
+		CREATE TABLE sage_lines (invoice_no TEXT, ITMREF_0 TEXT, QTYSTU_0 TEXT);
+		INSERT INTO portal_lines VALUES ('INV1', 'bolt', 10), ('INV1', 'gear', 3), ('INV1', 'nut', 20);
+		INSERT INTO sage_lines VALUES ('INV1', 'bolt  ', '10.000'), ('INV1', 'nut', '18'), ('INV1', 'washer', '5')`); err != nil {
+		t.Fatalf("Failed to prepare test data: %v", err)
+	}
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(reconcileFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	reconcile, err := xf.GetReconcile("PortalVsSage")
+	if err != nil || reconcile.Parent != "invoices" {
+		t.Fatalf("Unexpected reconcile %+v (error %v)", reconcile, err)
+	}
+
+	result, err := NewQueryExecutor(testLogger).ExecuteReconcile(context.Background(), db, xf, reconcile, map[string]any{"invoiceNo": "INV1"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := ReconcileSummary{Left: 3, Right: 3, Matched: 1, Mismatched: 1, OnlyLeft: 1, OnlyRight: 1}
+	if result.Summary != expected {
+		t.Errorf("Expected summary %+v, got %+v", expected, result.Summary)
+	}
+
+	var statuses []string
+	for _, row := range result.Rows {
+		statuses = append(statuses, row.Status+":"+row.Key["code"].(string))
+	}
+	want := []string{"matched:bolt", "onlyLeft:gear", "mismatched:nut", "onlyRight:washer"}
+	if !slices.Equal(statuses, want) {
+		t.Errorf("Expected rows %v, got %v", want, statuses)
+	}
+	if !slices.Equal(result.Rows[2].Differences, []string{"qty"}) {
+		t.Errorf("Expected qty to differ, got %v", result.Rows[2].Differences)
+	}
+}
+
+// TestReconcileDuplicateKeys tests that rows sharing a key are paired in order
+func TestReconcileDuplicateKeys(t *testing.T) {
+	reconcile := &Reconcile{Keys: []*ReconcileColumn{{Left: "id"}}, Compares: []*ReconcileColumn{{Left: "amount"}}}
+	left := []map[string]any{{"id": int64(1), "amount": 5.0}, {"id": int64(1), "amount": 7.0}}
+	right := []map[string]any{{"id": 1.0, "amount": int64(5)}}
+
+	result := reconcile.Reconcile(left, right)
+	if result.Summary.Matched != 1 || result.Summary.OnlyLeft != 1 || result.Rows[1].Left["amount"] != 7.0 {
+		t.Errorf("Expected the first left row to match and the second to be left over, got %+v", result.Summary)
+	}
+}
+
+// TestReconcileNumericKeys tests that numeric keys match across numbers and numeric
+// text exactly, without the rounding of float64
+func TestReconcileNumericKeys(t *testing.T) {
+	reconcile := &Reconcile{Keys: []*ReconcileColumn{{Left: "id"}}}
+	left := []map[string]any{
+		{"id": "10.00"},
+		{"id": []byte("-0.50")},
+		{"id": int64(9007199254740993)},
+		{"id": "12345678901234567890.10"},
+	}
+	right := []map[string]any{
+		{"id": int64(10)},
+		{"id": -0.5},
+		{"id": int64(9007199254740992)},
+		{"id": "9007199254740993"},
+		{"id": json.Number("12345678901234567890.1")},
+	}
+
+	result := reconcile.Reconcile(left, right)
+	expected := ReconcileSummary{Left: 4, Right: 5, Matched: 4, OnlyRight: 1}
+	if result.Summary != expected {
+		t.Fatalf("Expected summary %+v, got %+v", expected, result.Summary)
+	}
+	if only := result.Rows[len(result.Rows)-1]; only.Status != ReconcileOnlyRight || only.Right["id"] != int64(9007199254740992) {
+		t.Errorf("Expected 9007199254740992 to be left over, got %+v", only)
+	}
+}
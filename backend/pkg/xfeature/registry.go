@@ -0,0 +1,241 @@
+package xfeature
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrFeatureNotFound is returned when no definition is registered under a name
+var ErrFeatureNotFound = errors.New("feature not found")
+
+// RegistryEntry holds the state of a single feature definition file
+type RegistryEntry struct {
+	Key      string
+	Path     string
+	Checksum string
+	ModTime  time.Time
+	LoadedAt time.Time
+	// Feature is the last definition that parsed successfully, nil if none did
+	Feature *XFeature
+	// Err is the error of the most recent load attempt, nil if it succeeded
+	Err error
+}
+
+// Registry keeps every feature definition under a directory parsed in memory
+type Registry struct {
+	logger  *slog.Logger
+	dir     string
+	mu      sync.RWMutex
+	entries map[string]*RegistryEntry
+	hooks   []func(*RegistryEntry)
+}
+
+// NewRegistry creates a registry for the definitions under dir
+func NewRegistry(logger *slog.Logger, dir string) *Registry {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Registry{
+		logger:  logger,
+		dir:     dir,
+		entries: make(map[string]*RegistryEntry),
+	}
+}
+
+// Dir returns the directory the registry loads definitions from
+func (r *Registry) Dir() string {
+	return r.dir
+}
+
+// Get returns the current definition registered under name.
+// The name is the file path relative to the registry directory without the .xml extension;
+// the Name attribute of the feature is accepted as a fallback.
+func (r *Registry) Get(name string) (*XFeature, error) {
+	entry, ok := r.Entry(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrFeatureNotFound, name)
+	}
+	if entry.Feature == nil {
+		return nil, entry.Err
+	}
+	return entry.Feature, nil
+}
+
+// Entry returns the registry entry for name
+func (r *Registry) Entry(name string) (*RegistryEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if entry, ok := r.entries[name]; ok {
+		return entry, true
+	}
+	for _, entry := range r.entries {
+		if entry.Feature != nil && entry.Feature.Name == name {
+			return entry, true
+		}
+	}
+	return nil, false
+}
+
+// List returns all registry entries sorted by key
+func (r *Registry) List() []*RegistryEntry {
+	r.mu.RLock()
+	entries := make([]*RegistryEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// OnLoad registers a hook that runs every time a definition is loaded successfully.
+// The hook also runs immediately for definitions that are already loaded.
+func (r *Registry) OnLoad(hook func(*RegistryEntry)) {
+	r.mu.Lock()
+	r.hooks = append(r.hooks, hook)
+	r.mu.Unlock()
+
+	for _, entry := range r.List() {
+		if entry.Err == nil && entry.Feature != nil {
+			hook(entry)
+		}
+	}
+}
+
+// Reload scans the directory and reparses every file whose content changed.
+// A file that fails to parse keeps its last good definition.
+func (r *Registry) Reload() error {
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(d.Name()), ".xml") {
+			return nil
+		}
+
+		key := r.keyFor(path)
+		seen[key] = true
+		r.loadFile(key, path)
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("Failed to scan feature directory", "dir", r.dir, "error", err)
+		return fmt.Errorf("failed to scan %s: %w", r.dir, err)
+	}
+
+	r.mu.Lock()
+	for key := range r.entries {
+		if !seen[key] {
+			delete(r.entries, key)
+			r.logger.Info("Feature definition removed", "feature", key)
+		}
+	}
+	r.mu.Unlock()
+
+	return nil
+}
+
+// Watch reloads the directory every interval until ctx is cancelled
+func (r *Registry) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = r.Reload()
+		}
+	}
+}
+
+// loadFile parses a single file and swaps it into the registry if it changed
+func (r *Registry) loadFile(key, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		r.logger.Error("Failed to read feature definition", "feature", key, "path", path, "error", err)
+		return
+	}
+
+	sum := md5.Sum(data)
+	checksum := hex.EncodeToString(sum[:])
+
+	r.mu.RLock()
+	prev := r.entries[key]
+	r.mu.RUnlock()
+
+	if prev != nil && prev.Checksum == checksum {
+		return
+	}
+
+	entry := &RegistryEntry{
+		Key:      key,
+		Path:     path,
+		Checksum: checksum,
+		LoadedAt: time.Now(),
+	}
+	if info, err := os.Stat(path); err == nil {
+		entry.ModTime = info.ModTime()
+	}
+
+	xf := NewXFeature(r.logger)
+	if err := xf.Parse(data); err != nil {
+		entry.Err = fmt.Errorf("%s: %w", path, err)
+		if prev != nil {
+			entry.Feature = prev.Feature
+		}
+		r.logger.Error("Failed to load feature definition, keeping last good version",
+			"feature", key,
+			"path", path,
+			"hasPrevious", entry.Feature != nil,
+			"error", err,
+		)
+	} else {
+		entry.Feature = xf
+		r.logger.Info("Feature definition loaded",
+			"feature", key,
+			"name", xf.Name,
+			"version", xf.Version,
+			"path", path,
+		)
+	}
+
+	r.mu.Lock()
+	r.entries[key] = entry
+	hooks := append([]func(*RegistryEntry){}, r.hooks...)
+	r.mu.Unlock()
+
+	if entry.Err == nil {
+		for _, hook := range hooks {
+			hook(entry)
+		}
+	}
+}
+
+// keyFor derives the registry key of a file from its path relative to the directory
+func (r *Registry) keyFor(path string) string {
+	rel, err := filepath.Rel(r.dir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	rel = filepath.ToSlash(rel)
+	return strings.TrimSuffix(rel, filepath.Ext(rel))
+}
@@ -0,0 +1,193 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const registryTestXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Feature Name="%s" Version="%s">
+  <Backend>
+    <Query Id="GetUser" Type="Select">
+      <![CDATA[SELECT user_id, username FROM users WHERE user_id = :user_id]]>
+    </Query>
+  </Backend>
+  <Frontend/>
+</Feature>`
+
+// writeFeatureFile writes a feature definition into dir and returns its path
+func writeFeatureFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write feature file: %v", err)
+	}
+	return path
+}
+
+func featureXML(name, version string) string {
+	return fmt.Sprintf(registryTestXML, name, version)
+}
+
+// TestRegistryReload tests loading all definitions including subdirectories
+func TestRegistryReload(t *testing.T) {
+	dir := t.TempDir()
+	writeFeatureFile(t, dir, "users.xml", featureXML("Users", "1.0"))
+	writeFeatureFile(t, dir, "sales/invoices.xml", featureXML("Invoices", "2.0"))
+	writeFeatureFile(t, dir, "notes.txt", "not a feature")
+
+	registry := NewRegistry(testLogger, dir)
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if len(registry.List()) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(registry.List()))
+	}
+
+	xf, err := registry.Get("users")
+	if err != nil {
+		t.Fatalf("Expected feature 'users', got error: %v", err)
+	}
+	if xf.Name != "Users" {
+		t.Errorf("Expected Name 'Users', got '%s'", xf.Name)
+	}
+
+	query, err := xf.GetQuery("GetUser")
+	if err != nil {
+		t.Fatalf("Expected query GetUser, got error: %v", err)
+	}
+	if query.Parent != "Users" {
+		t.Errorf("Expected query parent 'Users', got '%s'", query.Parent)
+	}
+
+	if _, err := registry.Get("sales/invoices"); err != nil {
+		t.Errorf("Expected feature 'sales/invoices', got error: %v", err)
+	}
+
+	// The Name attribute is accepted as a fallback
+	if _, err := registry.Get("Invoices"); err != nil {
+		t.Errorf("Expected lookup by feature name to succeed, got error: %v", err)
+	}
+
+	if _, err := registry.Get("missing"); !errors.Is(err, ErrFeatureNotFound) {
+		t.Errorf("Expected ErrFeatureNotFound, got %v", err)
+	}
+}
+
+// TestRegistryKeepsLastGoodVersion tests that a broken file does not replace a parsed definition
+func TestRegistryKeepsLastGoodVersion(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFeatureFile(t, dir, "users.xml", featureXML("Users", "1.0"))
+
+	registry := NewRegistry(testLogger, dir)
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	writeFeatureFile(t, dir, "users.xml", `<Feature Name="Users" Version="1.1"><Backend>`)
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	xf, err := registry.Get("users")
+	if err != nil {
+		t.Fatalf("Expected last good version to be served, got error: %v", err)
+	}
+	if xf.Version != "1.0" {
+		t.Errorf("Expected Version '1.0', got '%s'", xf.Version)
+	}
+
+	entry, _ := registry.Entry("users")
+	if entry.Err == nil {
+		t.Error("Expected entry to record the parse error")
+	}
+	if entry.Path != path {
+		t.Errorf("Expected path '%s', got '%s'", path, entry.Path)
+	}
+
+	// Fixing the file clears the error and swaps in the new version
+	writeFeatureFile(t, dir, "users.xml", featureXML("Users", "1.2"))
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	entry, _ = registry.Entry("users")
+	if entry.Err != nil {
+		t.Errorf("Expected no error after fix, got %v", entry.Err)
+	}
+	if entry.Feature.Version != "1.2" {
+		t.Errorf("Expected Version '1.2', got '%s'", entry.Feature.Version)
+	}
+}
+
+// TestRegistryBrokenFileWithoutPreviousVersion tests that a never-parsed file reports its error
+func TestRegistryBrokenFileWithoutPreviousVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeFeatureFile(t, dir, "broken.xml", `<Feature Name="Broken"`)
+
+	registry := NewRegistry(testLogger, dir)
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if _, err := registry.Get("broken"); err == nil || errors.Is(err, ErrFeatureNotFound) {
+		t.Errorf("Expected parse error, got %v", err)
+	}
+}
+
+// TestRegistryRemovesDeletedFiles tests that deleted definitions disappear from the registry
+func TestRegistryRemovesDeletedFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFeatureFile(t, dir, "users.xml", featureXML("Users", "1.0"))
+
+	registry := NewRegistry(testLogger, dir)
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if _, err := registry.Get("users"); !errors.Is(err, ErrFeatureNotFound) {
+		t.Errorf("Expected ErrFeatureNotFound after removal, got %v", err)
+	}
+}
+
+// TestRegistryWatch tests that the watcher picks up changed files
+func TestRegistryWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFeatureFile(t, dir, "users.xml", featureXML("Users", "1.0"))
+
+	registry := NewRegistry(testLogger, dir)
+	if err := registry.Reload(); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.Watch(ctx, 10*time.Millisecond)
+
+	writeFeatureFile(t, dir, "users.xml", featureXML("Users", "2.0"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if xf, err := registry.Get("users"); err == nil && xf.Version == "2.0" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("Expected watcher to reload the changed definition")
+}
@@ -0,0 +1,101 @@
+package xfeature
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/taheri24/xpanel/backend/pkg/dbutil"
+)
+
+// ErrUnknownResultSet is returned for a result set name the query does not return
+var ErrUnknownResultSet = errors.New("unknown result set")
+
+// ResultSet names a result set of a query returning several, in the order they are returned
+type ResultSet struct {
+	Name string `xml:"Name,attr" json:"name"`
+}
+
+// NamedResultSet holds the rows of one result set of a query
+type NamedResultSet struct {
+	Name      string           `json:"name"`
+	Rows      []map[string]any `json:"rows"`
+	Truncated bool             `json:"truncated"`
+}
+
+// resultSetName returns the declared name of the result set at index, or resultSet<n>
+// counting from 1 for result sets without a ResultSet declaration
+func resultSetName(declared []*ResultSet, index int) string {
+	if index < len(declared) && declared[index].Name != "" {
+		return declared[index].Name
+	}
+	return fmt.Sprintf("resultSet%d", index+1)
+}
+
+// ResultSetIndex returns the position of a named result set of the query; an empty
+// name selects the first result set
+func (q *Query) ResultSetIndex(name string) (int, bool) {
+	if name == "" {
+		return 0, true
+	}
+	for i := range max(len(q.ResultSets), 1) {
+		if resultSetName(q.ResultSets, i) == name {
+			return i, true
+		}
+	}
+	var index int
+	if _, err := fmt.Sscanf(name, "resultSet%d", &index); err == nil && index > 0 {
+		return index - 1, true
+	}
+	return 0, false
+}
+
+// FindResultSet returns the result set with the given name; an empty name selects the first
+func FindResultSet(sets []*NamedResultSet, name string) *NamedResultSet {
+	for _, set := range sets {
+		if name == "" || set.Name == name {
+			return set
+		}
+	}
+	return nil
+}
+
+// readResultSets reads every result set of rows, each up to maxRows rows, and names
+// them by the declared result sets
+func readResultSets(rows *sql.Rows, declared []*ResultSet, maxRows int) ([]*NamedResultSet, error) {
+	var sets []*NamedResultSet
+	for {
+		set, truncated, err := dbutil.RowsToMapsLimit(rows, maxRows)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, &NamedResultSet{Name: resultSetName(declared, len(sets)), Rows: set, Truncated: truncated})
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+// BoundResultSet returns the result set of the query shown by its DataTable, or an
+// empty name for the first result set
+func (xf *XFeature) BoundResultSet(queryId string) string {
+	if table := xf.GetDataTableForQuery(queryId); table != nil {
+		return table.ResultSet
+	}
+	return ""
+}
+
+// ResultSet returns the rows of the named result set read by the last Execute, an empty
+// name returns the first, and stores in LastTruncated whether it was cut off
+func (qe *QueryExecutor) ResultSet(name string) ([]map[string]any, error) {
+	set := FindResultSet(qe.LastResultSets, name)
+	if set == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownResultSet, name)
+	}
+	qe.LastTruncated = set.Truncated
+	return set.Rows, nil
+}
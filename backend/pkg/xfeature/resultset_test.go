@@ -0,0 +1,160 @@
+package xfeature
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// resultSetTestDriver returns the same two result sets for every query, as SQLite
+// cannot return several
+const resultSetTestDriver = "xfeature_resultsets"
+
+type resultSetDriver struct{}
+
+func (resultSetDriver) Open(string) (driver.Conn, error) { return resultSetConn{}, nil }
+
+type resultSetConn struct{}
+
+func (resultSetConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (resultSetConn) Close() error                        { return nil }
+func (resultSetConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (resultSetConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &resultSetRows{
+		columns: [][]string{{"id"}, {"total"}},
+		sets:    [][][]driver.Value{{{int64(1)}, {int64(2)}, {int64(3)}}, {{int64(6)}}},
+	}, nil
+}
+
+type resultSetRows struct {
+	columns  [][]string
+	sets     [][][]driver.Value
+	set, row int
+}
+
+func (r *resultSetRows) Columns() []string { return r.columns[r.set] }
+func (r *resultSetRows) Close() error      { return nil }
+
+func (r *resultSetRows) Next(dest []driver.Value) error {
+	if r.row >= len(r.sets[r.set]) {
+		return io.EOF
+	}
+	copy(dest, r.sets[r.set][r.row])
+	r.row++
+	return nil
+}
+
+func (r *resultSetRows) HasNextResultSet() bool { return r.set+1 < len(r.sets) }
+
+func (r *resultSetRows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	r.set, r.row = r.set+1, 0
+	return nil
+}
+
+func init() {
+	sql.Register(resultSetTestDriver, resultSetDriver{})
+}
+
+const resultSetFeature = `<Feature Name="orders" Version="1">
+	<Backend>
+		<Query Id="Summary" Type="Select">
+			SELECT id FROM orders; SELECT SUM(id) AS total FROM orders;
+			<ResultSet Name="orders"/>
+			<ResultSet Name="totals"/>
+		</Query>
+	</Backend>
+	<Frontend>
+		<DataTable Id="TotalsTable" QueryRef="Summary" Title="Totals" ResultSet="totals"/>
+	</Frontend>
+</Feature>`
+
+// TestResultSets tests reading, naming and selecting the result sets of a query
+func TestResultSets(t *testing.T) {
+	db, err := sqlx.Open(resultSetTestDriver, "")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	xf := NewXFeature(testLogger)
+	if err := xf.Parse([]byte(resultSetFeature)); err != nil {
+		t.Fatalf("Failed to parse feature: %v", err)
+	}
+	query, _ := xf.GetQuery("Summary")
+	if len(query.ResultSets) != 2 || xf.BoundResultSet("Summary") != "totals" {
+		t.Fatalf("Unexpected result set declarations: %v, bound %q", query.ResultSets, xf.BoundResultSet("Summary"))
+	}
+
+	executor := NewQueryExecutor(testLogger)
+	rows, err := executor.Execute(context.Background(), db, query, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(rows) != 3 || len(executor.LastResultSets) != 2 || executor.LastResultSets[0].Name != "orders" {
+		t.Fatalf("Unexpected result sets: rows %v, sets %v", rows, executor.LastResultSets)
+	}
+	totals, err := executor.ResultSet("totals")
+	if err != nil || len(totals) != 1 || totals[0]["total"] != int64(6) {
+		t.Errorf("Unexpected totals result set: %v (error %v)", totals, err)
+	}
+	if _, err := executor.ResultSet("missing"); !errors.Is(err, ErrUnknownResultSet) {
+		t.Errorf("Expected ErrUnknownResultSet, got %v", err)
+	}
+
+	// Undeclared result sets are numbered, each is cut off at MaxRows
+	undeclared := &Query{Id: "Summary", Type: "Select", SQL: query.SQL, MaxRows: 2}
+	if _, err := executor.Execute(context.Background(), db, undeclared, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sets := executor.LastResultSets
+	if sets[0].Name != "resultSet1" || !sets[0].Truncated || sets[1].Name != "resultSet2" || sets[1].Truncated {
+		t.Errorf("Unexpected undeclared result sets: %+v %+v", sets[0], sets[1])
+	}
+}
+
+// TestStreamResultSet tests streaming a result set other than the first
+func TestStreamResultSet(t *testing.T) {
+	db, err := sqlx.Open(resultSetTestDriver, "")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	query := &Query{Id: "Summary", Type: "Select", SQL: "SELECT 1", ResultSets: []*ResultSet{{Name: "orders"}, {Name: "totals"}}}
+	executor := NewQueryExecutor(testLogger)
+	var streamed []map[string]any
+	count, err := executor.StreamResultSet(context.Background(), db, query, "totals", nil, func(row map[string]any) error {
+		streamed = append(streamed, row)
+		return nil
+	})
+	if err != nil || count != 1 || streamed[0]["total"] != int64(6) || executor.LastColumns[0] != "total" {
+		t.Errorf("Unexpected stream of totals: %v (count %d, error %v)", streamed, count, err)
+	}
+
+	_, err = executor.StreamResultSet(context.Background(), db, query, "resultSet3", nil, func(map[string]any) error { return nil })
+	if !errors.Is(err, ErrUnknownResultSet) {
+		t.Errorf("Expected ErrUnknownResultSet for a missing result set, got %v", err)
+	}
+	_, err = executor.StreamResultSet(context.Background(), db, query, "missing", nil, func(map[string]any) error { return nil })
+	if !errors.Is(err, ErrUnknownResultSet) {
+		t.Errorf("Expected ErrUnknownResultSet for an undeclared name, got %v", err)
+	}
+}
+
+// TestResultSetNotPaged tests that tables bound to a result set are not paged on the server
+func TestResultSetNotPaged(t *testing.T) {
+	table := &DataTable{Id: "TotalsTable", QueryRef: "Summary", ResultSet: "totals"}
+	_, err := BuildPagedSQL("SELECT 1; SELECT 2", table, &PageRequest{Page: 1}, "sqlserver")
+	if !errors.Is(err, ErrInvalidPageRequest) {
+		t.Errorf("Expected ErrInvalidPageRequest, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/taheri24/xpanel/backend/pkg/dbutil"
+)
+
+// ErrReturningUnsupported is returned for Returning actions on drivers that cannot
+// return the affected rows
+var ErrReturningUnsupported = errors.New("returning rows not supported")
+
+// ReturningDialect is implemented by dialects that can return the rows affected by an
+// INSERT, UPDATE or DELETE statement
+type ReturningDialect interface {
+	// ReturningSQL adds the clause returning every column of the affected rows to a
+	// statement of the given action Type. Statements that already have such a clause
+	// are returned unchanged.
+	ReturningSQL(sqlStr, actionType string) (string, error)
+}
+
+// ReturningSQL adds OUTPUT INSERTED.* to inserts and updates and OUTPUT DELETED.* to
+// deletes, in front of the VALUES, SELECT, FROM or WHERE clause the OUTPUT clause
+// precedes. Statements the clause cannot be placed in, like DELETE with a join,
+// declare it themselves.
+func (SQLServerDialect) ReturningSQL(sqlStr, actionType string) (string, error) {
+	sqlStr = strings.TrimRight(strings.TrimSpace(sqlStr), "; \t\r\n")
+	if hasTopLevelKeyword(sqlStr, "OUTPUT") {
+		return sqlStr, nil
+	}
+
+	clause, keywords := "OUTPUT INSERTED.*", []string{"FROM", "WHERE"}
+	switch actionType {
+	case "Insert":
+		keywords = []string{"VALUES", "SELECT", "DEFAULT", "EXEC", "EXECUTE"}
+	case "Delete":
+		clause, keywords = "OUTPUT DELETED.*", []string{"WHERE"}
+	}
+	offset := topLevelKeyword(sqlStr, keywords...)
+	if offset < 0 {
+		if actionType == "Insert" {
+			return "", fmt.Errorf("cannot place the OUTPUT clause in insert %q", sqlStr)
+		}
+		return sqlStr + "\n" + clause, nil
+	}
+	return sqlStr[:offset] + clause + "\n" + sqlStr[offset:], nil
+}
+
+// ReturningSQL appends RETURNING *
+func (SQLiteDialect) ReturningSQL(sqlStr, actionType string) (string, error) {
+	return appendReturning(sqlStr), nil
+}
+
+// ReturningSQL appends RETURNING *
+func (PostgresDialect) ReturningSQL(sqlStr, actionType string) (string, error) {
+	return appendReturning(sqlStr), nil
+}
+
+func appendReturning(sqlStr string) string {
+	sqlStr = strings.TrimRight(strings.TrimSpace(sqlStr), "; \t\r\n")
+	if hasTopLevelKeyword(sqlStr, "RETURNING") {
+		return sqlStr
+	}
+	return sqlStr + "\nRETURNING *"
+}
+
+// queryReturning runs the statement of an action with the returning clause of the
+// dialect of db, which may be a transaction, and reads the returned rows up to
+// LastMaxRows
+func (ae *ActionExecutor) queryReturning(
+	ctx context.Context,
+	db sqlx.ExtContext,
+	action *ActionQuery,
+	params map[string]any,
+) ([]map[string]any, error) {
+	startTime := time.Now()
+	ae.LastTruncated = false
+	ae.LastMaxRows = parseMaxRows(action.MaxRows, ae.maxRows)
+
+	dialect, ok := DialectFor(db.DriverName()).(ReturningDialect)
+	if !ok {
+		return nil, fmt.Errorf("%w: driver %s cannot return the rows of action %s", ErrReturningUnsupported, db.DriverName(), action.Id)
+	}
+	statement, err := dialect.ReturningSQL(action.SQL, action.Type)
+	if err != nil {
+		ae.logger.Error("Returning clause failed", "actionId", action.Id, "error", err)
+		return nil, err
+	}
+
+	// Bind parameters for the database driver
+	sql, args, err := BindParameters(statement, params, db.DriverName(), ae.maxListSize)
+	if err != nil {
+		ae.logger.Error("Parameter binding failed", "actionId", action.Id, "error", err)
+		return nil, err
+	}
+	ae.logColoredSQL(fmt.Sprintf("RETURNING %s/%s", action.Parent, action.Id), sql, action.Type)
+
+	timeout := parseTimeout(action.Timeout, ae.queryTimeout)
+	execCtx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+	sqlRows, err := db.QueryContext(execCtx, sql, args...)
+	if err != nil {
+		ae.logger.Error("Action execution with RETURNING failed",
+			"actionId", action.Id,
+			"actionType", action.Type,
+			"error", err,
+			"duration_ms", time.Since(startTime).Milliseconds(),
+		)
+		return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to execute action %s: %w", action.Id, err), action.Id, timeout)
+	}
+	defer sqlRows.Close()
+
+	rows, truncated, err := dbutil.RowsToMapsLimit(sqlRows, ae.LastMaxRows)
+	if err != nil {
+		ae.logger.Error("Failed to convert returned rows", "actionId", action.Id, "error", err)
+		return nil, timeoutError(execCtx, ctx, fmt.Errorf("failed to convert returned rows: %w", err), action.Id, timeout)
+	}
+	ae.LastTruncated = truncated
+	if err := concurrencyConflict(action, int64(len(rows))); err != nil {
+		ae.logger.Warn("Concurrency conflict", "actionId", action.Id, "column", action.Concurrency)
+		return nil, err
+	}
+
+	ae.logger.Debug("Action with RETURNING executed successfully",
+		"actionId", action.Id,
+		"actionType", action.Type,
+		"rowCount", len(rows),
+		"duration_ms", time.Since(startTime).Milliseconds(),
+		"params", ae.sanitizeParams(params),
+	)
+	return rows, nil
+}
@@ -0,0 +1,125 @@
+package xfeature
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestReturningSQL tests where the dialects place the returning clause
+func TestReturningSQL(t *testing.T) {
+	tests := []struct {
+		name       string
+		dialect    ReturningDialect
+		actionType string
+		sql        string
+		expected   string
+	}{
+		{
+			name: "sqlserver insert", dialect: SQLServerDialect{}, actionType: "Insert",
+			sql:      "INSERT INTO users (username, email) VALUES (:username, :email);",
+			expected: "INSERT INTO users (username, email) OUTPUT INSERTED.*\nVALUES (:username, :email)",
+		},
+		{
+			name: "sqlserver update", dialect: SQLServerDialect{}, actionType: "Update",
+			sql:      "UPDATE users SET email = (SELECT :email) WHERE user_id = :user_id",
+			expected: "UPDATE users SET email = (SELECT :email) OUTPUT INSERTED.*\nWHERE user_id = :user_id",
+		},
+		{
+			name: "sqlserver update without where", dialect: SQLServerDialect{}, actionType: "Update",
+			sql:      "UPDATE users SET status = 'active'",
+			expected: "UPDATE users SET status = 'active'\nOUTPUT INSERTED.*",
+		},
+		{
+			name: "sqlserver delete", dialect: SQLServerDialect{}, actionType: "Delete",
+			sql:      "DELETE FROM users WHERE user_id = :user_id",
+			expected: "DELETE FROM users OUTPUT DELETED.*\nWHERE user_id = :user_id",
+		},
+		{
+			name: "sqlserver declared output", dialect: SQLServerDialect{}, actionType: "Insert",
+			sql:      "INSERT INTO users (username) OUTPUT INSERTED.user_id VALUES (:username)",
+			expected: "INSERT INTO users (username) OUTPUT INSERTED.user_id VALUES (:username)",
+		},
+		{
+			name: "sqlite", dialect: SQLiteDialect{}, actionType: "Update",
+			sql:      "UPDATE users SET email = :email WHERE user_id = :user_id;",
+			expected: "UPDATE users SET email = :email WHERE user_id = :user_id\nRETURNING *",
+		},
+		{
+			name: "postgres declared returning", dialect: PostgresDialect{}, actionType: "Insert",
+			sql:      "INSERT INTO users (username) VALUES (:username) RETURNING user_id",
+			expected: "INSERT INTO users (username) VALUES (:username) RETURNING user_id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dialect.ReturningSQL(tt.sql, tt.actionType)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+
+	if _, ok := DialectFor("mysql").(ReturningDialect); ok {
+		t.Errorf("Expected MySQL to have no returning clause")
+	}
+}
+
+// TestExecuteWithReturning tests that actions return the rows they inserted, updated and deleted
+func TestExecuteWithReturning(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	executor := NewActionExecutor(testLogger)
+	ctx := context.Background()
+
+	insert := &ActionQuery{Id: "CreateUser", Type: "Insert", Returning: true,
+		SQL: "INSERT INTO users (username, email) VALUES (:username, :email)"}
+	result, rows, err := executor.ExecuteWithReturning(ctx, db, insert, map[string]any{"username": "ada", "email": "ada@example.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected != 1 || len(rows) != 1 || rows[0]["user_id"] != int64(1) || rows[0]["status"] != "active" {
+		t.Errorf("Expected the inserted row with its defaults, got %v (rows affected %d)", rows, rowsAffected)
+	}
+
+	update := &ActionQuery{Id: "SetRole", Type: "Update", Returning: true, MaxRows: 1,
+		SQL: "UPDATE users SET role = :role"}
+	if _, _, err := executor.ExecuteWithReturning(ctx, db, insert, map[string]any{"username": "bob", "email": "bob@example.com"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result, rows, err = executor.ExecuteWithReturning(ctx, db, update, map[string]any{"role": "admin"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	rowsAffected, _ = result.RowsAffected()
+	if len(rows) != 1 || rows[0]["role"] != "admin" || !executor.LastTruncated || rowsAffected != -1 {
+		t.Errorf("Expected one updated row cut off at MaxRows, got %v (truncated %v, rows affected %d)", rows, executor.LastTruncated, rowsAffected)
+	}
+}
+
+// TestExecuteWithReturningMockDataSet tests that mock data sets provide the returned rows
+func TestExecuteWithReturningMockDataSet(t *testing.T) {
+	dir := t.TempDir() + string(filepath.Separator)
+	mock := `{"rowsAffected": 1, "lastInsertId": 7, "rows": [{"user_id": 7, "username": "ada"}]}`
+	if err := os.WriteFile(dir+"create.json", []byte(mock), 0644); err != nil {
+		t.Fatalf("Failed to write mock data set: %v", err)
+	}
+
+	action := &ActionQuery{Id: "CreateUser", Type: "Insert", Returning: true, MockDataSet: "create.json",
+		SQL: "INSERT INTO users (username) VALUES (:username)"}
+	result, rows, err := NewActionExecutorWithLocation(testLogger, dir).ExecuteWithReturning(context.Background(), nil, action, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	id, _ := result.LastInsertId()
+	if id != 7 || len(rows) != 1 || rows[0]["username"] != "ada" {
+		t.Errorf("Unexpected mock result: id %d, rows %v", id, rows)
+	}
+}
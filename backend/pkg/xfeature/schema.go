@@ -0,0 +1,589 @@
+package xfeature
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Diagnostic describes a single schema violation in a feature definition
+type Diagnostic struct {
+	Line      int    `json:"line"`
+	Element   string `json:"element,omitempty"`
+	Attribute string `json:"attribute,omitempty"`
+	Message   string `json:"message"`
+}
+
+// String formats the diagnostic for logs and error messages
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+}
+
+// ValidationError is returned when a feature definition violates the schema
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Diagnostics) == 0 {
+		return "invalid feature definition"
+	}
+	msg := "invalid feature definition: " + e.Diagnostics[0].String()
+	if len(e.Diagnostics) > 1 {
+		msg += fmt.Sprintf(" (and %d more)", len(e.Diagnostics)-1)
+	}
+	return msg
+}
+
+// AsValidationError extracts a ValidationError from an error chain
+func AsValidationError(err error) (*ValidationError, bool) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return verr, true
+	}
+	return nil, false
+}
+
+type attrType int
+
+const (
+	attrString attrType = iota
+	attrBoolean
+	attrPositiveInteger
+	attrDuration
+)
+
+// attrRule describes an attribute as declared in feature-schema.xsd
+type attrRule struct {
+	Type     attrType
+	Required bool
+	Enum     []string
+}
+
+// childRule describes how often a child element may occur; Max 0 means unbounded
+type childRule struct {
+	Min int
+	Max int
+}
+
+// elementRule describes an element as declared in feature-schema.xsd
+type elementRule struct {
+	Attrs    map[string]attrRule
+	Children map[string]childRule
+	Text     bool
+}
+
+func enum(required bool, values ...string) attrRule {
+	return attrRule{Type: attrString, Required: required, Enum: values}
+}
+
+// mappingDataTypes lists every allowed Mapping DataType, scalars first, then arrays
+func mappingDataTypes() []string {
+	values := append([]string{}, DataTypes...)
+	for _, dataType := range DataTypes {
+		values = append(values, dataType+"[]")
+	}
+	return values
+}
+
+var (
+	optionalString   = attrRule{Type: attrString}
+	requiredString   = attrRule{Type: attrString, Required: true}
+	optionalBoolean  = attrRule{Type: attrBoolean}
+	requiredBoolean  = attrRule{Type: attrBoolean, Required: true}
+	optionalPositive = attrRule{Type: attrPositiveInteger}
+	optionalDuration = attrRule{Type: attrDuration}
+	unbounded        = childRule{Min: 0, Max: 0}
+	optionalOnce     = childRule{Min: 0, Max: 1}
+	exactlyOnce      = childRule{Min: 1, Max: 1}
+)
+
+// schemaRules is the Go rule set derived from specs/xfeature/feature-schema.xsd.
+// Keep both in sync when the schema changes.
+var schemaRules = map[string]*elementRule{
+	"Feature": {
+		Attrs: map[string]attrRule{
+			"Name":       requiredString,
+			"Version":    requiredString,
+			"DataSource": optionalString,
+		},
+		Children: map[string]childRule{
+			"Backend":  exactlyOnce,
+			"Frontend": exactlyOnce,
+			"Mapping":  unbounded,
+		},
+	},
+	"Backend": {
+		Children: map[string]childRule{
+			"Query":       unbounded,
+			"ActionQuery": unbounded,
+			"ActionGroup": unbounded,
+			"Reconcile":   unbounded,
+		},
+	},
+	"Query": {
+		Attrs: map[string]attrRule{
+			"Id":          requiredString,
+			"MockDataSet": optionalString,
+			"Type":        enum(true, "Select", "Procedure"),
+			"Description": optionalString,
+			"Cache":       optionalDuration,
+			"Timeout":     optionalDuration,
+			"MaxRows":     optionalPositive,
+			"DataSource":  optionalString,
+		},
+		Children: map[string]childRule{
+			"Optional":  unbounded,
+			"Param":     unbounded,
+			"ResultSet": unbounded,
+		},
+		Text: true,
+	},
+	"Optional": {
+		Attrs: map[string]attrRule{
+			"Param": requiredString,
+		},
+		Text: true,
+	},
+	"ActionQuery": {
+		Attrs: map[string]attrRule{
+			"Id":               requiredString,
+			"MockDataSet":      optionalString,
+			"Type":             enum(true, "Insert", "Update", "Delete", "Procedure"),
+			"Description":      optionalString,
+			"Invalidates":      optionalString,
+			"Returning":        optionalBoolean,
+			"Concurrency":      optionalString,
+			"ConflictQueryRef": optionalString,
+			"Timeout":          optionalDuration,
+			"MaxRows":          optionalPositive,
+			"DataSource":       optionalString,
+		},
+		Children: map[string]childRule{
+			"Param": unbounded,
+		},
+		Text: true,
+	},
+	"Param": {
+		Attrs: map[string]attrRule{
+			"Name":    requiredString,
+			"Output":  optionalBoolean,
+			"SqlType": optionalString,
+		},
+	},
+	"ResultSet": {
+		Attrs: map[string]attrRule{
+			"Name": requiredString,
+		},
+	},
+	"Reconcile": {
+		Attrs: map[string]attrRule{
+			"Id":          requiredString,
+			"Description": optionalString,
+			"LeftRef":     requiredString,
+			"RightRef":    requiredString,
+		},
+		Children: map[string]childRule{
+			"Key":     {Min: 1},
+			"Compare": unbounded,
+		},
+	},
+	"Key": {
+		Attrs: map[string]attrRule{
+			"Left":  requiredString,
+			"Right": optionalString,
+		},
+	},
+	"Compare": {
+		Attrs: map[string]attrRule{
+			"Left":  requiredString,
+			"Right": optionalString,
+		},
+	},
+	"ActionGroup": {
+		Attrs: map[string]attrRule{
+			"Id":          requiredString,
+			"Description": optionalString,
+			"Timeout":     optionalDuration,
+			"DataSource":  optionalString,
+		},
+		Children: map[string]childRule{
+			"Step": unbounded,
+		},
+	},
+	"Step": {
+		Attrs: map[string]attrRule{
+			"ActionRef": requiredString,
+			"InsertId":  optionalString,
+		},
+	},
+	"Frontend": {
+		Children: map[string]childRule{
+			"Form":      unbounded,
+			"DataTable": unbounded,
+		},
+	},
+	"Form": {
+		Attrs: map[string]attrRule{
+			"Id":        requiredString,
+			"Mode":      enum(true, "Create", "Edit", "View", "Delete", "Search"),
+			"Dialog":    requiredBoolean,
+			"Title":     requiredString,
+			"ActionRef": optionalString,
+			"QueryRef":  optionalString,
+		},
+		Children: map[string]childRule{
+			"Field":   unbounded,
+			"Button":  unbounded,
+			"Message": unbounded,
+		},
+	},
+	"Field": {
+		Attrs: map[string]attrRule{
+			"Name":  requiredString,
+			"Label": optionalString,
+			"Type": enum(true, "Text", "Email", "Password", "Tel", "Number", "Date", "DateTime", "Time",
+				"Textarea", "Select", "Checkbox", "Radio", "Hidden", "File"),
+			"Required":     optionalBoolean,
+			"Readonly":     optionalBoolean,
+			"Placeholder":  optionalString,
+			"Validation":   optionalString,
+			"Format":       optionalString,
+			"DefaultValue": optionalString,
+		},
+		Children: map[string]childRule{
+			"Option": unbounded,
+		},
+	},
+	"Option": {
+		Attrs: map[string]attrRule{
+			"Value": requiredString,
+			"Label": requiredString,
+		},
+	},
+	"Button": {
+		Attrs: map[string]attrRule{
+			"Type":      enum(true, "Submit", "Cancel", "Reset", "Close", "Confirm"),
+			"Label":     requiredString,
+			"Style":     enum(true, "Primary", "Secondary", "Success", "Danger", "Warning", "Info"),
+			"ActionRef": optionalString,
+		},
+	},
+	"Message": {
+		Attrs: map[string]attrRule{
+			"Type": enum(true, "Info", "Success", "Warning", "Error"),
+		},
+		Text: true,
+	},
+	"DataTable": {
+		Attrs: map[string]attrRule{
+			"Id":          requiredString,
+			"QueryRef":    requiredString,
+			"Title":       requiredString,
+			"Pagination":  optionalBoolean,
+			"PageSize":    optionalPositive,
+			"Sortable":    optionalBoolean,
+			"Filterable":  optionalBoolean,
+			"Searchable":  optionalBoolean,
+			"FormActions": optionalString,
+			"ResultSet":   optionalString,
+			"DetailOf":    optionalString,
+			"DefaultSort": optionalString,
+		},
+		Children: map[string]childRule{
+			"Column": unbounded,
+			"Bind":   unbounded,
+		},
+	},
+	"Bind": {
+		Attrs: map[string]attrRule{
+			"Column": requiredString,
+			"Param":  requiredString,
+		},
+	},
+	"Column": {
+		Attrs: map[string]attrRule{
+			"Name":  requiredString,
+			"Label": requiredString,
+			"Type": enum(false, "Text", "Number", "Date", "DateTime", "Boolean", "Currency", "Percentage",
+				"Link", "Badge", "Image"),
+			"Sortable":   optionalBoolean,
+			"Filterable": optionalBoolean,
+			"Width":      optionalString,
+			"Format":     optionalString,
+			"Align":      enum(false, "Left", "Center", "Right"),
+		},
+	},
+	"Mapping": {
+		Attrs: map[string]attrRule{
+			"Name":     requiredString,
+			"DataType": enum(true, mappingDataTypes()...),
+			"Label":    requiredString,
+		},
+		Children: map[string]childRule{
+			"ListQuery": optionalOnce,
+			"Options":   optionalOnce,
+		},
+	},
+	"ListQuery": {
+		Attrs: map[string]attrRule{
+			"Id":          requiredString,
+			"Type":        enum(true, "Select"),
+			"Description": optionalString,
+			"DataSource":  optionalString,
+		},
+		Text: true,
+	},
+	"Options": {
+		Children: map[string]childRule{
+			"Option": unbounded,
+		},
+	},
+}
+
+// openElement tracks an element while its content is being validated
+type openElement struct {
+	name     string
+	line     int
+	rule     *elementRule
+	children map[string]int
+}
+
+// ValidateSchema checks a feature definition against the rules of feature-schema.xsd
+// and returns a *ValidationError with line-numbered diagnostics when it is invalid
+func ValidateSchema(data []byte) error {
+	v := &schemaValidator{data: data}
+	v.run()
+	if len(v.diagnostics) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.diagnostics, func(i, j int) bool {
+		return v.diagnostics[i].Line < v.diagnostics[j].Line
+	})
+	return &ValidationError{Diagnostics: v.diagnostics}
+}
+
+type schemaValidator struct {
+	data        []byte
+	diagnostics []Diagnostic
+}
+
+func (v *schemaValidator) report(line int, element, attribute, format string, args ...any) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Line:      line,
+		Element:   element,
+		Attribute: attribute,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *schemaValidator) run() {
+	dec := xml.NewDecoder(bytes.NewReader(v.data))
+	var stack []*openElement
+	rootSeen := false
+
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				v.report(syntaxErr.Line, "", "", "malformed XML: %s", syntaxErr.Msg)
+			} else {
+				v.report(v.lineAt(offset), "", "", "malformed XML: %v", err)
+			}
+			return
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			tagStart := v.skipSpace(offset)
+			tagEnd := dec.InputOffset()
+			line := v.lineAt(tagStart)
+			name := t.Name.Local
+
+			rule, known := schemaRules[name]
+			switch {
+			case len(stack) == 0:
+				if rootSeen {
+					v.report(line, name, "", "unexpected second root element <%s>", name)
+				} else if name != "Feature" {
+					v.report(line, name, "", "root element must be <Feature>, found <%s>", name)
+				}
+				rootSeen = true
+			default:
+				parent := stack[len(stack)-1]
+				if parent.rule != nil {
+					if _, allowed := parent.rule.Children[name]; !allowed {
+						v.report(line, name, "", "element <%s> is not allowed inside <%s>", name, parent.name)
+					}
+					parent.children[name]++
+				}
+			}
+			// Unknown elements are reported by their parent; their content is not checked
+			if known {
+				v.checkAttributes(name, rule, t.Attr, tagStart, tagEnd)
+			}
+			stack = append(stack, &openElement{name: name, line: line, rule: rule, children: make(map[string]int)})
+
+		case xml.EndElement:
+			if len(stack) == 0 {
+				continue
+			}
+			el := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			v.checkChildren(el)
+
+		case xml.CharData:
+			if len(stack) == 0 {
+				continue
+			}
+			el := stack[len(stack)-1]
+			if el.rule != nil && !el.rule.Text && len(bytes.TrimSpace(t)) > 0 {
+				v.report(v.lineAt(v.skipSpace(offset)), el.name, "", "text content is not allowed inside <%s>", el.name)
+			}
+		}
+	}
+
+	if !rootSeen {
+		v.report(1, "", "", "document has no <Feature> root element")
+	}
+}
+
+// checkAttributes validates the attributes of a start tag against its rule
+func (v *schemaValidator) checkAttributes(element string, rule *elementRule, attrs []xml.Attr, tagStart, tagEnd int64) {
+	present := make(map[string]bool)
+	for _, attr := range attrs {
+		// Namespace declarations and namespaced attributes such as xsi:noNamespaceSchemaLocation
+		if attr.Name.Space != "" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		name := attr.Name.Local
+		present[name] = true
+		line := v.attrLine(name, tagStart, tagEnd)
+
+		ar, ok := rule.Attrs[name]
+		if !ok {
+			v.report(line, element, name, "unknown attribute %s on <%s>", name, element)
+			continue
+		}
+
+		switch ar.Type {
+		case attrBoolean:
+			switch attr.Value {
+			case "true", "false", "1", "0":
+			default:
+				v.report(line, element, name, "%s=%q on <%s> is not a boolean (expected true or false)", name, attr.Value, element)
+			}
+		case attrPositiveInteger:
+			if n, err := strconv.Atoi(strings.TrimSpace(attr.Value)); err != nil || n < 1 {
+				v.report(line, element, name, "%s=%q on <%s> is not a positive integer", name, attr.Value, element)
+			}
+		case attrDuration:
+			if d, err := time.ParseDuration(strings.TrimSpace(attr.Value)); err != nil || d <= 0 {
+				v.report(line, element, name, "%s=%q on <%s> is not a positive duration (e.g. 30s or 5m)", name, attr.Value, element)
+			}
+		}
+
+		if len(ar.Enum) > 0 && !containsString(ar.Enum, attr.Value) {
+			v.report(line, element, name, "unknown %s=%q on <%s> (allowed: %s)", name, attr.Value, element, strings.Join(ar.Enum, ", "))
+		}
+	}
+
+	line := v.lineAt(tagStart)
+	for _, name := range sortedKeys(rule.Attrs) {
+		if rule.Attrs[name].Required && !present[name] {
+			v.report(line, element, name, "missing required attribute %s on <%s>", name, element)
+		}
+	}
+}
+
+// checkChildren validates child element occurrences once an element is closed
+func (v *schemaValidator) checkChildren(el *openElement) {
+	if el.rule == nil {
+		return
+	}
+	for _, name := range sortedKeys(el.rule.Children) {
+		cr := el.rule.Children[name]
+		count := el.children[name]
+		if count < cr.Min {
+			v.report(el.line, el.name, "", "<%s> requires a <%s> element", el.name, name)
+		}
+		if cr.Max > 0 && count > cr.Max {
+			v.report(el.line, el.name, "", "<%s> allows at most %d <%s> element(s), found %d", el.name, cr.Max, name, count)
+		}
+	}
+}
+
+// lineAt converts a byte offset into a 1-based line number
+func (v *schemaValidator) lineAt(offset int64) int {
+	if offset > int64(len(v.data)) {
+		offset = int64(len(v.data))
+	}
+	return bytes.Count(v.data[:offset], []byte("\n")) + 1
+}
+
+// skipSpace advances an offset past whitespace to the start of the next token
+func (v *schemaValidator) skipSpace(offset int64) int64 {
+	for offset < int64(len(v.data)) {
+		switch v.data[offset] {
+		case ' ', '\t', '\r', '\n':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// attrLine finds the line of an attribute inside a start tag spanning several lines
+func (v *schemaValidator) attrLine(name string, tagStart, tagEnd int64) int {
+	if tagEnd > int64(len(v.data)) {
+		tagEnd = int64(len(v.data))
+	}
+	tag := v.data[tagStart:tagEnd]
+	for i := 0; i+len(name) <= len(tag); i++ {
+		idx := bytes.Index(tag[i:], []byte(name))
+		if idx < 0 {
+			break
+		}
+		pos := i + idx
+		before := byte(' ')
+		if pos > 0 {
+			before = tag[pos-1]
+		}
+		rest := bytes.TrimLeft(tag[pos+len(name):], " \t\r\n")
+		if (before == ' ' || before == '\t' || before == '\n' || before == '\r') && len(rest) > 0 && rest[0] == '=' {
+			return v.lineAt(tagStart + int64(pos))
+		}
+		i = pos
+	}
+	return v.lineAt(tagStart)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,126 @@
+package xfeature
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidateSchemaSample tests that every feature definition shipped in the repository
+// satisfies the schema
+func TestValidateSchemaSample(t *testing.T) {
+	var files []string
+	for _, pattern := range []string{"../../../specs/xfeature/*.xml", "../../../sage-app/x/*.xml"} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			t.Fatalf("Invalid pattern %s: %v", pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) < 4 {
+		t.Fatalf("Expected the sample and the sage-app features, found %v", files)
+	}
+
+	for _, file := range files {
+		xf := NewXFeature(testLogger)
+		if err := xf.LoadFromFile(file); err != nil {
+			t.Errorf("Expected %s to be valid, got: %v", file, err)
+		}
+	}
+}
+
+// TestValidateSchemaDiagnostics tests line-numbered diagnostics for schema violations
+func TestValidateSchemaDiagnostics(t *testing.T) {
+	xmlContent := `<?xml version="1.0" encoding="UTF-8"?>
+<Feature Name="Broken" Version="1.0">
+  <Backend>
+    <Query Id="ListUsers" Cache="5x"
+           Type="Selct">
+      SELECT * FROM users
+    </Query>
+    <Procedure Id="Unknown"/>
+  </Backend>
+  <Frontend>
+    <DataTable Id="UsersTable" QueryRef="ListUsers" Title="Users" PageSize="0" Colour="red">
+      <Column Name="id" Label="ID" Align="Middle"/>
+    </DataTable>
+    <Form Id="UserForm" Mode="Create" Dialog="yes">
+      <Button Type="Submit" Label="Save" Style="Primary"/>
+    </Form>
+  </Frontend>
+</Feature>`
+
+	err := ValidateSchema([]byte(xmlContent))
+	verr, ok := AsValidationError(err)
+	if !ok {
+		t.Fatalf("Expected ValidationError, got %v", err)
+	}
+
+	expected := []struct {
+		line    int
+		message string
+	}{
+		{4, `Cache="5x" on <Query> is not a positive duration (e.g. 30s or 5m)`},
+		{5, `unknown Type="Selct" on <Query> (allowed: Select, Procedure)`},
+		{8, "element <Procedure> is not allowed inside <Backend>"},
+		{11, `PageSize="0" on <DataTable> is not a positive integer`},
+		{11, "unknown attribute Colour on <DataTable>"},
+		{12, `unknown Align="Middle" on <Column> (allowed: Left, Center, Right)`},
+		{14, `Dialog="yes" on <Form> is not a boolean (expected true or false)`},
+		{14, "missing required attribute Title on <Form>"},
+	}
+
+	for _, exp := range expected {
+		found := false
+		for _, d := range verr.Diagnostics {
+			if d.Line == exp.line && d.Message == exp.message {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Expected diagnostic on line %d: %s\nGot: %v", exp.line, exp.message, verr.Diagnostics)
+		}
+	}
+
+	if len(verr.Diagnostics) != len(expected) {
+		t.Errorf("Expected %d diagnostics, got %d: %v", len(expected), len(verr.Diagnostics), verr.Diagnostics)
+	}
+}
+
+// TestValidateSchemaStructure tests root element, required children and malformed XML
+func TestValidateSchemaStructure(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		line     int
+		contains string
+	}{
+		{"Wrong root", `<Features Name="x" Version="1"/>`, 1, "root element must be <Feature>"},
+		{"Missing Frontend", "<Feature Name=\"x\" Version=\"1\">\n<Backend/>\n</Feature>", 1, "requires a <Frontend> element"},
+		{"Text in Backend", "<Feature Name=\"x\" Version=\"1\">\n<Backend>SELECT 1</Backend><Frontend/>\n</Feature>", 2, "text content is not allowed inside <Backend>"},
+		{"Malformed", "<Feature Name=\"x\" Version=\"1\">\n<Backend>\n</Feature>", 3, "malformed XML"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verr, ok := AsValidationError(ValidateSchema([]byte(tt.xml)))
+			if !ok {
+				t.Fatal("Expected ValidationError")
+			}
+			d := verr.Diagnostics[0]
+			if d.Line != tt.line || !strings.Contains(d.Message, tt.contains) {
+				t.Errorf("Expected line %d containing %q, got %v", tt.line, tt.contains, d)
+			}
+		})
+	}
+}
+
+// TestLoadFromFileRejectsInvalidSchema tests that the loader runs schema validation
+func TestLoadFromFileRejectsInvalidSchema(t *testing.T) {
+	xf := NewXFeature(testLogger)
+	err := xf.Parse([]byte(`<Feature Name="x" Version="1"><Backend><Query Id="q" Type="Selct">SELECT 1</Query></Backend><Frontend/></Feature>`))
+	if _, ok := AsValidationError(err); !ok {
+		t.Errorf("Expected ValidationError from Parse, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package xfeature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestStream tests that rows are passed one at a time and cancellation stops the query
+func TestStream(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	for i := 1; i <= 10; i++ {
+		_, err := db.Exec("INSERT INTO users (username, email) VALUES (?, ?)", fmt.Sprintf("user%d", i), fmt.Sprintf("user%d@example.com", i))
+		if err != nil {
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	query := &Query{Id: "ListUsers", SQL: "SELECT user_id, username FROM users WHERE user_id > :min_id ORDER BY user_id"}
+	executor := NewQueryExecutor(testLogger)
+
+	var usernames []string
+	count, err := executor.Stream(context.Background(), db, query, map[string]any{"min_id": 7}, func(row map[string]any) error {
+		usernames = append(usernames, row["username"].(string))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if count != 3 || len(usernames) != 3 || usernames[0] != "user8" {
+		t.Errorf("Expected user8 to user10, got %d rows: %v", count, usernames)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count, err = executor.Stream(ctx, db, query, map[string]any{"min_id": 0}, func(row map[string]any) error {
+		if row["user_id"] == int64(2) {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected the stream to stop after 2 rows, got %d", count)
+	}
+
+	if _, err := executor.Stream(context.Background(), db, query, map[string]any{}, func(map[string]any) error { return nil }); err == nil {
+		t.Error("Expected missing parameter error")
+	}
+}
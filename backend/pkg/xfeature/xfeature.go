@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"regexp"
 	"strings"
 
 	"github.com/jmoiron/sqlx"
@@ -15,18 +14,21 @@ import (
 
 // XFeature represents the entire XML feature definition
 type XFeature struct {
-	Name     string       `xml:"Name,attr" json:"name"`
-	Version  string       `xml:"Version,attr" json:"version"`
-	Backend  Backend      `xml:"Backend" json:"backend"`
-	Frontend Frontend     `xml:"Frontend" json:"frontend"`
-	Mappings []*Mapping   `xml:"Mapping" json:"mappings"`
-	Logger   *slog.Logger `json:"-"`
+	Name       string       `xml:"Name,attr" json:"name"`
+	Version    string       `xml:"Version,attr" json:"version"`
+	DataSource string       `xml:"DataSource,attr" json:"dataSource"`
+	Backend    Backend      `xml:"Backend" json:"backend"`
+	Frontend   Frontend     `xml:"Frontend" json:"frontend"`
+	Mappings   []*Mapping   `xml:"Mapping" json:"mappings"`
+	Logger     *slog.Logger `json:"-"`
 }
 
 // Backend contains all backend queries and actions
 type Backend struct {
 	Queries       []*Query       `xml:"Query" json:"queries"`
 	ActionQueries []*ActionQuery `xml:"ActionQuery" json:"actionQueries"`
+	ActionGroups  []*ActionGroup `xml:"ActionGroup" json:"actionGroups,omitempty"`
+	Reconciles    []*Reconcile   `xml:"Reconcile" json:"reconciles,omitempty"`
 }
 
 // Frontend contains all frontend forms and tables
@@ -37,24 +39,48 @@ type Frontend struct {
 
 // Query represents a SELECT operation
 type Query struct {
-	Parent      string   `xml:"-" json:"-"`
-	Id          string   `xml:"Id,attr" json:"id"`
-	Type        string   `xml:"Type,attr" json:"type"`
-	Description string   `xml:"Description,attr" json:"description"`
-	MockDataSet string   `xml:"MockDataSet,attr" json:"mockDataSet"`
-	SQL         string   `xml:",chardata" json:"sql"`
-	Parameters  []string `json:"parameters"`
+	Parent      string       `xml:"-" json:"-"`
+	Id          string       `xml:"Id,attr" json:"id"`
+	Type        string       `xml:"Type,attr" json:"type"`
+	Description string       `xml:"Description,attr" json:"description"`
+	MockDataSet string       `xml:"MockDataSet,attr" json:"mockDataSet"`
+	Cache       string       `xml:"Cache,attr" json:"cache"`
+	Timeout     string       `xml:"Timeout,attr" json:"timeout"`
+	MaxRows     int          `xml:"MaxRows,attr" json:"maxRows"`
+	DataSource  string       `xml:"DataSource,attr" json:"dataSource"`
+	SQL         string       `xml:",chardata" json:"sql"`
+	Params      []*ProcParam `xml:"Param" json:"params,omitempty"`
+	ResultSets  []*ResultSet `xml:"ResultSet" json:"resultSets,omitempty"`
+	Parameters  []string     `json:"parameters"`
+
+	// Optionals are the fragments of SQL run only with their parameter. Parse compiles
+	// them from the Body, the inner XML of the query, keeping their place in the SQL.
+	Optionals []*Optional `xml:"Optional" json:"optionals,omitempty"`
+	Body      string      `xml:",innerxml" json:"-"`
+	fragments []sqlFragment
 }
 
 // ActionQuery represents an INSERT/UPDATE/DELETE operation
 type ActionQuery struct {
-	Parent      string   `xml:"-" json:"-"`
-	Id          string   `xml:"Id,attr" json:"id"`
-	Type        string   `xml:"Type,attr" json:"type"`
-	Description string   `xml:"Description,attr" json:"description"`
-	MockDataSet string   `xml:"MockDataSet,attr" json:"mockDataSet"`
-	SQL         string   `xml:",chardata" json:"sql"`
-	Parameters  []string `json:"parameters"`
+	Parent      string       `xml:"-" json:"-"`
+	Id          string       `xml:"Id,attr" json:"id"`
+	Type        string       `xml:"Type,attr" json:"type"`
+	Description string       `xml:"Description,attr" json:"description"`
+	MockDataSet string       `xml:"MockDataSet,attr" json:"mockDataSet"`
+	Invalidates string       `xml:"Invalidates,attr" json:"invalidates"`
+	Returning   bool         `xml:"Returning,attr" json:"returning"`
+	Timeout     string       `xml:"Timeout,attr" json:"timeout"`
+	MaxRows     int          `xml:"MaxRows,attr" json:"maxRows"`
+	DataSource  string       `xml:"DataSource,attr" json:"dataSource"`
+	SQL         string       `xml:",chardata" json:"sql"`
+	Params      []*ProcParam `xml:"Param" json:"params,omitempty"`
+	Parameters  []string     `json:"parameters"`
+
+	// Concurrency names the column, like rowversion or updated_at, whose original value
+	// the action takes as the parameter of the same name to detect concurrent changes.
+	// ConflictQueryRef reads the current row when a change is rejected.
+	Concurrency      string `xml:"Concurrency,attr" json:"concurrency,omitempty"`
+	ConflictQueryRef string `xml:"ConflictQueryRef,attr" json:"conflictQueryRef,omitempty"`
 }
 
 // DataTable represents a frontend data table
@@ -68,7 +94,17 @@ type DataTable struct {
 	Filterable  *bool     `xml:"Filterable,attr" json:"filterable"`
 	Searchable  *bool     `xml:"Searchable,attr" json:"searchable"`
 	FormActions string    `xml:"FormActions,attr" json:"formActions"`
+	ResultSet   string    `xml:"ResultSet,attr" json:"resultSet,omitempty"`
 	Columns     []*Column `xml:"Column" json:"columns"`
+
+	// DetailOf names the master DataTable of a detail table. The Binds map columns of
+	// the selected master row to parameters of the query of the detail table.
+	DetailOf string  `xml:"DetailOf,attr" json:"detailOf,omitempty"`
+	Binds    []*Bind `xml:"Bind" json:"binds,omitempty"`
+
+	// DefaultSort lists the columns that give paged rows a stable order, such as a key,
+	// in the form of the sort argument of a page request
+	DefaultSort string `xml:"DefaultSort,attr" json:"defaultSort,omitempty"`
 }
 
 // Column represents a table column definition
@@ -144,6 +180,7 @@ type ListQuery struct {
 	Id          string `xml:"Id,attr" json:"id"`
 	Type        string `xml:"Type,attr" json:"type"`
 	Description string `xml:"Description,attr" json:"description"`
+	DataSource  string `xml:"DataSource,attr" json:"dataSource"`
 	SQL         string `xml:",chardata" json:"sql"`
 }
 
@@ -175,21 +212,73 @@ func (xf *XFeature) LoadFromFile(path string) error {
 		return fmt.Errorf("failed to read file: %w", err)
 	}
 
+	if err := xf.Parse(data); err != nil {
+		return err
+	}
+
+	xf.Logger.Debug("Loaded XFeature from file", "path", path, "name", xf.Name, "version", xf.Version)
+	return nil
+}
+
+// Parse parses an XML feature definition from memory
+func (xf *XFeature) Parse(data []byte) error {
+	if err := ValidateSchema(data); err != nil {
+		return err
+	}
+
 	if err := xml.Unmarshal(data, xf); err != nil {
 		return fmt.Errorf("failed to unmarshal XML: %w", err)
 	}
 
-	// Normalize SQL content by trimming whitespace and extract parameters
+	// Normalize SQL content by trimming whitespace and extract parameters; procedures
+	// take their parameters from the Param declarations. The SQL of queries with
+	// Optional fragments includes all of them.
+	// Queries, actions and groups without a DataSource use the DataSource of the feature.
 	for _, query := range xf.Backend.Queries {
+		query.Parent = xf.Name
+		if len(query.Optionals) > 0 {
+			fragments, err := compileOptional(query.Body)
+			if err != nil {
+				return fmt.Errorf("query %s: %w", query.Id, err)
+			}
+			query.fragments = fragments
+			query.SQL = renderFragments(fragments, func(string) bool { return true })
+		}
 		query.SQL = strings.TrimSpace(query.SQL)
 		query.Parameters = ExtractParameters(query.SQL)
+		if query.IsProcedure() {
+			query.Parameters = procedureParameters(query.Params)
+		}
+		if query.DataSource == "" {
+			query.DataSource = xf.DataSource
+		}
 	}
 	for _, action := range xf.Backend.ActionQueries {
+		action.Parent = xf.Name
 		action.SQL = strings.TrimSpace(action.SQL)
 		action.Parameters = ExtractParameters(action.SQL)
+		if action.IsProcedure() {
+			action.Parameters = procedureParameters(action.Params)
+		}
+		if action.DataSource == "" {
+			action.DataSource = xf.DataSource
+		}
+	}
+	for _, group := range xf.Backend.ActionGroups {
+		group.Parent = xf.Name
+		if group.DataSource == "" {
+			group.DataSource = xf.DataSource
+		}
+	}
+	for _, reconcile := range xf.Backend.Reconciles {
+		reconcile.Parent = xf.Name
+	}
+	for _, mapping := range xf.Mappings {
+		if mapping.ListQuery != nil && mapping.ListQuery.DataSource == "" {
+			mapping.ListQuery.DataSource = xf.DataSource
+		}
 	}
 
-	xf.Logger.Debug("Loaded XFeature from file", "path", path, "name", xf.Name, "version", xf.Version)
 	return nil
 }
 
@@ -197,7 +286,9 @@ func (xf *XFeature) LoadFromFile(path string) error {
 func (xf *XFeature) GetQuery(id string) (*Query, error) {
 	for _, query := range xf.Backend.Queries {
 		if query.Id == id {
-			query.Parent = xf.Name
+			if query.Parent == "" {
+				query.Parent = xf.Name
+			}
 			return query, nil
 		}
 	}
@@ -208,7 +299,9 @@ func (xf *XFeature) GetQuery(id string) (*Query, error) {
 func (xf *XFeature) GetActionQuery(id string) (*ActionQuery, error) {
 	for _, action := range xf.Backend.ActionQueries {
 		if action.Id == id {
-			action.Parent = xf.Name
+			if action.Parent == "" {
+				action.Parent = xf.Name
+			}
 			return action, nil
 		}
 	}
@@ -225,6 +318,16 @@ func (xf *XFeature) GetDataTable(id string) (*DataTable, error) {
 	return nil, fmt.Errorf("data table not found: %s", id)
 }
 
+// GetDataTableForQuery finds the first data table that displays the given query
+func (xf *XFeature) GetDataTableForQuery(queryId string) *DataTable {
+	for _, table := range xf.Frontend.DataTables {
+		if table.QueryRef == queryId {
+			return table
+		}
+	}
+	return nil
+}
+
 // GetForm finds a form by ID
 func (xf *XFeature) GetForm(id string) (*Form, error) {
 	for _, form := range xf.Frontend.Forms {
@@ -267,6 +370,11 @@ func (xf *XFeature) ExecuteQuery(
 		return nil, err
 	}
 
+	params, err = xf.CoerceParameters(params)
+	if err != nil {
+		return nil, err
+	}
+
 	executor := NewQueryExecutor(xf.Logger)
 	return executor.Execute(ctx, db, query, params)
 }
@@ -283,14 +391,23 @@ func (xf *XFeature) ExecuteAction(
 		return nil, err
 	}
 
+	params, err = xf.CoerceParameters(params)
+	if err != nil {
+		return nil, err
+	}
+
 	executor := NewActionExecutor(xf.Logger)
 	return executor.Execute(ctx, db, action, params)
 }
 
-// ExtractParameters extracts parameter names from SQL (e.g., :param_name)
+// ExtractParameters extracts parameter names from SQL (e.g., :param_name); casts like
+// amount::numeric are not parameters
 func ExtractParameters(sqlStr string) []string {
-	paramRegex := regexp.MustCompile(`:\w+`)
-	matches := paramRegex.FindAllString(sqlStr, -1)
+	var matches []string
+	replaceParameters(sqlStr, func(match string) string {
+		matches = append(matches, match)
+		return match
+	})
 
 	// Remove duplicates
 	paramMap := make(map[string]bool)
@@ -307,22 +424,10 @@ func ExtractParameters(sqlStr string) []string {
 }
 
 // ConvertParametersForDriver converts parameter placeholders for different SQL drivers
-// SQLite uses ? or $1, SQL Server uses @param
+// using the Dialect of the driver, e.g. SQLite keeps :param and SQL Server uses @param
 func ConvertParametersForDriver(sqlStr string, driverName string) string {
-	switch driverName {
-	case "sqlserver":
-		// Convert :param to @param
-		paramRegex := regexp.MustCompile(`:\w+`)
-		return paramRegex.ReplaceAllStringFunc(sqlStr, func(match string) string {
-			return "@" + strings.TrimPrefix(match, ":")
-		})
-	case "sqlite3", "sqlite":
-		// SQLite uses named parameters, so keep :param
-		return sqlStr
-	default:
-		// Default: keep as is
-		return sqlStr
-	}
+	sqlStr, _ = DialectFor(driverName).Bind(sqlStr, nil)
+	return sqlStr
 }
 
 // ExtractMappingsFromSQL extracts SQL parameters and returns them as Mapping objects
@@ -362,16 +467,20 @@ func (xf *XFeature) GetMappingsForSQL(sqlStr string) []*Mapping {
 // ExecuteListQueryToOptions executes a ListQuery and converts the results to MappingOptions
 // It takes the first column from the query result and uses it as both Label and Value
 func (xf *XFeature) ExecuteListQueryToOptions(ctx context.Context, db *sqlx.DB, listQuery *ListQuery) ([]*MappingOption, error) {
+	return xf.executeListQueryToOptions(ctx, NewQueryExecutor(xf.Logger), db, listQuery)
+}
+
+func (xf *XFeature) executeListQueryToOptions(ctx context.Context, executor *QueryExecutor, db *sqlx.DB, listQuery *ListQuery) ([]*MappingOption, error) {
 	if listQuery == nil || db == nil {
 		return nil, fmt.Errorf("listQuery and db cannot be nil")
 	}
 
-	executor := NewQueryExecutor(xf.Logger)
 	query := &Query{
 		Parent:      xf.Name,
 		Id:          listQuery.Id,
 		Type:        listQuery.Type,
 		Description: listQuery.Description,
+		DataSource:  listQuery.DataSource,
 		SQL:         listQuery.SQL,
 	}
 
@@ -404,7 +513,14 @@ func (xf *XFeature) ExecuteListQueryToOptions(ctx context.Context, db *sqlx.DB,
 // ResolveMappings resolves all Mappings by executing ListQuery and converting to Options
 // It iterates through all Mappings in the XFeature and executes any ListQuery to populate Options
 func (xf *XFeature) ResolveMappings(ctx context.Context, db *sqlx.DB) []*Mapping {
+	return xf.ResolveMappingsWithOptions(ctx, db, ExecutorOptions{})
+}
+
+// ResolveMappingsWithOptions resolves all Mappings like ResolveMappings, running each
+// ListQuery with the given executor options, e.g. on its DataSource
+func (xf *XFeature) ResolveMappingsWithOptions(ctx context.Context, db *sqlx.DB, opts ExecutorOptions) []*Mapping {
 	var mappings []*Mapping
+	executor := NewQueryExecutorWithOptions(xf.Logger, opts)
 
 	for _, pm := range xf.Mappings {
 		// Create a copy to avoid modifying the original
@@ -418,7 +534,7 @@ func (xf *XFeature) ResolveMappings(ctx context.Context, db *sqlx.DB) []*Mapping
 
 		// If there's a ListQuery, execute it and convert to Options
 		if pmCopy.ListQuery != nil && db != nil {
-			options, err := xf.ExecuteListQueryToOptions(ctx, db, pmCopy.ListQuery)
+			options, err := xf.executeListQueryToOptions(ctx, executor, db, pmCopy.ListQuery)
 			if err == nil && len(options) > 0 {
 				pmCopy.Options = &Options{Items: options}
 				pmCopy.ListQuery = nil // Clear ListQuery since we've resolved it to Options